@@ -2,11 +2,18 @@ package enthistory
 
 import (
 	"encoding/json"
+	"maps"
+	"time"
+
+	"entgo.io/ent/schema"
 )
 
 const (
 	ValueTypeInt ValueType = iota
 	ValueTypeString
+	ValueTypeInt64
+	// ValueTypeUUID is a github.com/google/uuid UUID
+	ValueTypeUUID
 )
 
 type ValueType uint
@@ -19,10 +26,64 @@ const (
 	annotationName = "History"
 )
 
+// skipOps are the operations that can be set using the SkipOps of the history annotation
+var skipOps = []string{"CREATE", "UPDATE", "DELETE"}
+
 // Annotations of the history extension
 type Annotations struct {
 	Exclude   bool `json:"exclude,omitempty"`   // Will exclude history tracking for this schema
 	IsHistory bool `json:"isHistory,omitempty"` // DO NOT APPLY TO ANYTHING EXCEPT HISTORY SCHEMAS
+	// Indexes are the fields of indexes on the original schema that should also be added to the history schema,
+	// e.g. [][]string{{"tenant_id"}, {"owner_id", "name"}}; unique indexes are added as non-unique indexes
+	Indexes [][]string `json:"indexes,omitempty"`
+	// AllowedRelation overrides the relation used to restrict the history queries of this schema when using
+	// the authz policy, e.g. "audit_log_viewer", instead of the relation set by WithAllowedRelation
+	AllowedRelation string `json:"allowedRelation,omitempty"`
+	// TableName overrides the name of the history table of this schema, e.g. "audit_users", instead of the table of
+	// the schema with the _history suffix
+	TableName string `json:"tableName,omitempty"`
+	// SchemaName overrides the database schema of the history table of this schema, e.g. "audit", instead of the
+	// schema name set by WithSchemaName; this is also used by the edge history tables of the edges of the schema
+	SchemaName string `json:"schemaName,omitempty"`
+	// RefFields are the fields recorded as the ref of the history rows instead of the id, e.g. []string{"email"}, for
+	// schemas where the records are correlated using a natural key; multiple fields are recorded as a composite key,
+	// and the ref of the history schema is a string, see NaturalRef
+	RefFields []string `json:"refFields,omitempty"`
+	// Track marks the schema for history tracking when using WithOptIn, this is set by the history Mixin
+	Track bool `json:"track,omitempty"`
+	// SkipOps are the mutations of the schema that do not create history, e.g. []string{"CREATE"} for ephemeral
+	// schemas that only track deletes; the operations are CREATE, UPDATE (including soft deletes and restores), and
+	// DELETE
+	SkipOps []string `json:"skipOps,omitempty"`
+	// IgnoredUpdateFields are the fields that do not create update history when they are the only
+	// fields changed by the update, e.g. []string{"last_seen_at"}
+	IgnoredUpdateFields []string `json:"ignoredUpdateFields,omitempty"`
+	// SampleInterval records at most one history row for each record every interval, e.g. time.Minute, for noisy
+	// schemas (heartbeats, counters), updates within the interval of the latest history row of the record are not
+	// recorded; creates, deletes, and soft deletes are always recorded
+	SampleInterval time.Duration `json:"sampleInterval,omitempty"`
+	// FieldLimits are the size limits of the string and bytes fields copied to the history rows, e.g.
+	// []enthistory.FieldLimit{{Field: "body", MaxSize: 4096, Strategy: enthistory.LimitHash}}
+	FieldLimits []FieldLimit `json:"fieldLimits,omitempty"`
+	// CompressedFields are the string and bytes fields stored compressed on the history rows by the name of the
+	// compression, e.g. map[string]enthistory.Compression{"body": enthistory.CompressionGzip}; the values are
+	// decompressed when read, so the history rows hold the original values
+	CompressedFields map[string]Compression `json:"compressedFields,omitempty"`
+	// SchemaVersion is the version of the fields of the original schema recorded on the history rows when using
+	// WithSchemaVersion, DO NOT APPLY, this is set on the history schemas by the generator
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+	// SchemaHash is the hash of the fields of the original schema at the SchemaVersion, DO NOT APPLY, this is set
+	// on the history schemas by the generator
+	SchemaHash string `json:"schemaHash,omitempty"`
+	// SchemaGeneratedAt is the unix time the SchemaVersion was generated, DO NOT APPLY, this is set on the
+	// history schemas by the generator
+	SchemaGeneratedAt int64 `json:"schemaGeneratedAt,omitempty"`
+	// SchemaFields are the ent field types (e.g. TypeString) of the fields of the original schema at the
+	// SchemaVersion, DO NOT APPLY, this is set on the history schemas by the generator
+	SchemaFields map[string]string `json:"schemaFields,omitempty"`
+	// LegacyFields are the ent field types of the fields removed from the original schema which are kept on the
+	// history schema when using WithLegacyFields, DO NOT APPLY, this is set on the history schemas by the generator
+	LegacyFields map[string]string `json:"legacyFields,omitempty"`
 }
 
 // Name of the annotation
@@ -30,6 +91,62 @@ func (Annotations) Name() string {
 	return annotationName
 }
 
+// Merge implements the schema.Merger interface, so the annotations of the history Mixin
+// are combined with the annotations of the schema
+func (a Annotations) Merge(other schema.Annotation) schema.Annotation {
+	var ant Annotations
+
+	switch other := other.(type) {
+	case Annotations:
+		ant = other
+	case *Annotations:
+		if other != nil {
+			ant = *other
+		}
+	default:
+		return a
+	}
+
+	a.Exclude = a.Exclude || ant.Exclude
+	a.IsHistory = a.IsHistory || ant.IsHistory
+	a.Track = a.Track || ant.Track
+	a.Indexes = append(a.Indexes, ant.Indexes...)
+	a.IgnoredUpdateFields = append(a.IgnoredUpdateFields, ant.IgnoredUpdateFields...)
+	a.SkipOps = append(a.SkipOps, ant.SkipOps...)
+	a.FieldLimits = append(a.FieldLimits, ant.FieldLimits...)
+
+	if ant.AllowedRelation != "" {
+		a.AllowedRelation = ant.AllowedRelation
+	}
+
+	if ant.TableName != "" {
+		a.TableName = ant.TableName
+	}
+
+	if ant.SchemaName != "" {
+		a.SchemaName = ant.SchemaName
+	}
+
+	if len(ant.RefFields) > 0 {
+		a.RefFields = ant.RefFields
+	}
+
+	if len(ant.CompressedFields) > 0 {
+		a.CompressedFields = maps.Clone(a.CompressedFields)
+		if a.CompressedFields == nil {
+			a.CompressedFields = map[string]Compression{}
+		}
+
+		maps.Copy(a.CompressedFields, ant.CompressedFields)
+	}
+
+	if ant.SampleInterval != 0 {
+		a.SampleInterval = ant.SampleInterval
+	}
+
+	return a
+}
+
 // jsonUnmarshalAnnotations unmarshals the annotations from the schema
 // this is useful when you have a map[string]any and want to get the fields
 // from the annotation
@@ -3,6 +3,7 @@ package enthistory
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -57,3 +58,42 @@ func TestJsonUnmarshalAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeAnnotations(t *testing.T) {
+	a := Annotations{Track: true, Indexes: [][]string{{"name"}}}
+
+	got := a.Merge(Annotations{Exclude: true, AllowedRelation: "admin", Indexes: [][]string{{"owner_id"}}})
+	assert.Equal(t, Annotations{
+		Exclude:         true,
+		Track:           true,
+		AllowedRelation: "admin",
+		Indexes:         [][]string{{"name"}, {"owner_id"}},
+	}, got)
+
+	got = a.Merge(&Annotations{IsHistory: true, SampleInterval: time.Minute})
+	assert.Equal(t, Annotations{IsHistory: true, Track: true, Indexes: [][]string{{"name"}}, SampleInterval: time.Minute}, got)
+
+	got = a.Merge(Annotations{TableName: "audit_users", SchemaName: "audit"})
+	assert.Equal(t, Annotations{Track: true, Indexes: [][]string{{"name"}}, TableName: "audit_users", SchemaName: "audit"}, got)
+
+	got = a.Merge(Annotations{SkipOps: []string{"CREATE"}})
+	assert.Equal(t, Annotations{Track: true, Indexes: [][]string{{"name"}}, SkipOps: []string{"CREATE"}}, got)
+
+	got = a.Merge(Annotations{RefFields: []string{"tenant_id", "email"}})
+	assert.Equal(t, Annotations{Track: true, Indexes: [][]string{{"name"}}, RefFields: []string{"tenant_id", "email"}}, got)
+
+	got = a.Merge(Annotations{FieldLimits: []FieldLimit{{Field: "body", MaxSize: 1024}}})
+	assert.Equal(t, Annotations{
+		Track:       true,
+		Indexes:     [][]string{{"name"}},
+		FieldLimits: []FieldLimit{{Field: "body", MaxSize: 1024}},
+	}, got)
+
+	c := Annotations{CompressedFields: map[string]Compression{"body": CompressionGzip}}
+
+	got = c.Merge(Annotations{CompressedFields: map[string]Compression{"attachment": CompressionZlib}})
+	assert.Equal(t, Annotations{
+		CompressedFields: map[string]Compression{"body": CompressionGzip, "attachment": CompressionZlib},
+	}, got)
+	assert.Len(t, c.CompressedFields, 1)
+}
@@ -0,0 +1,38 @@
+package enthistory
+
+import (
+	"time"
+
+	"entgo.io/ent/dialect/sql"
+)
+
+// LatestAsOf returns a predicate matching the latest history row of each ref recorded at or before the time, by
+// history time and then id, which holds the state of the record at the time (or its final state when the operation
+// is a delete); the later rows are read from the history table in the database schema, if any, which must be the
+// schema of the query (e.g. from the schema config of the generated code). This is used by the generated AsOf clients
+// to read the tracked schemas as they were at the time
+func LatestAsOf(t time.Time, schema string) func(*sql.Selector) {
+	return func(s *sql.Selector) {
+		later := sql.Table(s.TableName()).Schema(schema).As("later")
+
+		s.Where(sql.And(
+			sql.LTE(s.C("history_time"), t),
+			sql.NotExists(
+				sql.Dialect(s.Dialect()).
+					Select(later.C("id")).
+					From(later).
+					Where(sql.And(
+						sql.ColumnsEQ(later.C("ref"), s.C("ref")),
+						sql.LTE(later.C("history_time"), t),
+						sql.Or(
+							sql.ColumnsGT(later.C("history_time"), s.C("history_time")),
+							sql.And(
+								sql.ColumnsEQ(later.C("history_time"), s.C("history_time")),
+								sql.ColumnsGT(later.C("id"), s.C("id")),
+							),
+						),
+					)),
+			),
+		))
+	}
+}
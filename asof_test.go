@@ -0,0 +1,32 @@
+package enthistory
+
+import (
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestAsOf(t *testing.T) {
+	at := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	s := sql.Dialect(dialect.Postgres).Select().From(sql.Table("todo_history"))
+	LatestAsOf(at, "")(s)
+
+	query, args := s.Query()
+	assert.Equal(t, `SELECT * FROM "todo_history" WHERE "todo_history"."history_time" <= $1 AND NOT EXISTS `+
+		`(SELECT "later"."id" FROM "todo_history" AS "later" WHERE "later"."ref" = "todo_history"."ref" `+
+		`AND "later"."history_time" <= $2 AND ("later"."history_time" > "todo_history"."history_time" `+
+		`OR ("later"."history_time" = "todo_history"."history_time" AND "later"."id" > "todo_history"."id")))`, query)
+	assert.Equal(t, []any{at, at}, args)
+
+	// the later rows are read from the history table of the schema
+	s = sql.Dialect(dialect.Postgres).Select().From(sql.Table("todo_history").Schema("audit"))
+	LatestAsOf(at, "audit")(s)
+
+	query, _ = s.Query()
+	assert.Contains(t, query, `SELECT * FROM "audit"."todo_history" WHERE "audit"."todo_history"."history_time" <= $1`)
+	assert.Contains(t, query, `FROM "audit"."todo_history" AS "later" WHERE "later"."ref" = "audit"."todo_history"."ref"`)
+}
@@ -0,0 +1,105 @@
+package enthistory
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"entgo.io/ent"
+)
+
+// AttemptReason is the reason a mutation recorded as an attempted change failed
+type AttemptReason string
+
+const (
+	// AttemptDenied is a mutation denied by a privacy policy
+	AttemptDenied AttemptReason = "DENIED"
+	// AttemptInvalid is a mutation rejected by the validators of the fields
+	AttemptInvalid AttemptReason = "INVALID"
+	// AttemptConstraint is a mutation violating a database constraint (e.g. a unique index or foreign key)
+	AttemptConstraint AttemptReason = "CONSTRAINT"
+	// AttemptFailed is a mutation that failed for any other reason (e.g. an error returned by a hook)
+	AttemptFailed AttemptReason = "FAILED"
+)
+
+// Values provides list valid values for Enum.
+func (AttemptReason) Values() []string {
+	return []string{
+		AttemptDenied.String(),
+		AttemptInvalid.String(),
+		AttemptConstraint.String(),
+		AttemptFailed.String(),
+	}
+}
+
+// Value of the attempt reason
+func (r AttemptReason) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// String value of the attempt reason
+func (r AttemptReason) String() string {
+	return string(r)
+}
+
+// Scan implements the `database/sql.Scanner` interface for the `AttemptReason` type
+func (r *AttemptReason) Scan(v any) error {
+	if v == nil {
+		*r = AttemptReason("")
+		return nil
+	}
+
+	switch src := v.(type) {
+	case string:
+		*r = AttemptReason(src)
+	case []byte:
+		*r = AttemptReason(string(src))
+	default:
+		return ErrUnsupportedType
+	}
+
+	return nil
+}
+
+// MarshalGQL implement the Marshaler interface for gqlgen
+func (r AttemptReason) MarshalGQL(w io.Writer) {
+	io.WriteString(w, strconv.Quote(string(r))) //nolint:errcheck
+}
+
+// UnmarshalGQL implement the Unmarshaler interface for gqlgen
+func (r *AttemptReason) UnmarshalGQL(v interface{}) error {
+	return r.Scan(v)
+}
+
+// AttemptRecorder records a failed mutation and its error, e.g. in the history_attempt table
+type AttemptRecorder func(ctx context.Context, m ent.Mutation, err error) error
+
+// WithAttempts records the mutations that fail (e.g. denied by a privacy policy, rejected by a validator, or violating
+// a constraint) using the recorder, the generated hooks use the recordAttempt writing to the history_attempt table
+func WithAttempts(record AttemptRecorder) HookOption {
+	return func(c *hookConfig) {
+		c.recordAttempt = record
+	}
+}
+
+// historyHookAttempt is a hook that records the mutation when it fails, this wraps the other history hooks so the
+// failed mutations are recorded whichever hook or policy returned the error
+func historyHookAttempt(record AttemptRecorder) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			value, err := next.Mutate(ctx, m)
+			if err == nil || shouldSkip(ctx, m) {
+				return value, err
+			}
+
+			if rerr := record(newHistoryContext(ctx), m, err); rerr != nil {
+				return value, errors.Join(err, fmt.Errorf("recording attempted change: %w", rerr))
+			}
+
+			return value, err
+		})
+	}
+}
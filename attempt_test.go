@@ -0,0 +1,102 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestMutation = errors.New("mutation failed")
+
+func TestHistoryHooksAttempts(t *testing.T) {
+	assert.Len(t, HistoryHooks[*testOrderMutation](), 3)
+	assert.Len(t, HistoryHooks[*testOrderMutation](WithAttempts(func(context.Context, ent.Mutation, error) error { return nil })), 4)
+}
+
+func TestHistoryHookAttempt(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		recordErr error
+		recorded  bool
+	}{
+		{
+			name: "succeeded",
+			ctx:  context.Background(),
+		},
+		{
+			name:     "failed",
+			ctx:      context.Background(),
+			err:      errTestMutation,
+			recorded: true,
+		},
+		{
+			name: "skipped",
+			ctx:  NewSkipContext(context.Background()),
+			err:  errTestMutation,
+		},
+		{
+			name:      "recording failed",
+			ctx:       context.Background(),
+			err:       errTestMutation,
+			recordErr: ErrUnsupportedType,
+			recorded:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorded := false
+			m := &testOrderMutation{op: ent.OpCreate, calls: &[]string{}}
+
+			record := func(_ context.Context, got ent.Mutation, err error) error {
+				recorded = true
+
+				assert.Equal(t, m, got)
+				assert.ErrorIs(t, err, tt.err)
+
+				return tt.recordErr
+			}
+
+			mutator := historyHookAttempt(record)(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+				return nil, tt.err
+			}))
+
+			_, err := mutator.Mutate(tt.ctx, m)
+			assert.Equal(t, tt.recorded, recorded)
+
+			if tt.err == nil {
+				require.NoError(t, err)
+
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.err)
+
+			if tt.recordErr != nil {
+				assert.ErrorIs(t, err, tt.recordErr)
+			}
+		})
+	}
+}
+
+func TestAttemptReasonScan(t *testing.T) {
+	var r AttemptReason
+
+	require.NoError(t, r.Scan("DENIED"))
+	assert.Equal(t, AttemptDenied, r)
+
+	require.NoError(t, r.Scan([]byte("INVALID")))
+	assert.Equal(t, AttemptInvalid, r)
+
+	require.NoError(t, r.Scan(nil))
+	assert.Equal(t, AttemptReason(""), r)
+
+	assert.ErrorIs(t, r.Scan(1), ErrUnsupportedType)
+	assert.Equal(t, []string{"DENIED", "INVALID", "CONSTRAINT", "FAILED"}, AttemptReason("").Values())
+}
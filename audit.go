@@ -0,0 +1,173 @@
+package enthistory
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// AuditFormat is the format the audit log is written in by the generated WriteAudit and ExportAudit methods
+type AuditFormat string
+
+const (
+	// AuditFormatCSV writes the audit log as CSV, the first record holds the column names
+	AuditFormatCSV AuditFormat = "csv"
+	// AuditFormatJSON writes the audit log as a JSON array of objects, keyed by the column names
+	AuditFormatJSON AuditFormat = "json"
+)
+
+// auditFormats are the supported audit formats
+var auditFormats = []AuditFormat{AuditFormatCSV, AuditFormatJSON}
+
+// AuditConfig is the configuration of the generated audit log, set using the options of WithAuditing
+type AuditConfig struct {
+	// Format is the format the audit log is written in, defaults to csv
+	Format AuditFormat
+	// Tables are the tracked schemas (e.g. Todo) included in the audit log, when empty all schemas are included
+	Tables []string
+	// DiffOnly leaves the values of deleted records, and the updates without changes, out of the audit log
+	DiffOnly bool
+}
+
+// AuditOption is a functional option for WithAuditing
+type AuditOption func(*AuditConfig)
+
+// WithAuditFormat sets the format the audit log is written in by the generated WriteAudit and ExportAudit methods
+func WithAuditFormat(format AuditFormat) AuditOption {
+	return func(c *AuditConfig) {
+		c.Format = format
+	}
+}
+
+// WithAuditTables limits the audit log to the history of the tracked schemas, by name (e.g. Todo), the other
+// history tables are left out of the generated Audit and AuditWithFilter methods
+func WithAuditTables(tables ...string) AuditOption {
+	return func(c *AuditConfig) {
+		c.Tables = append(c.Tables, tables...)
+	}
+}
+
+// WithAuditDiffOnly only records the fields changed by each history row, compared to the previous history row of the
+// record, in the audit log; deletes are recorded without the values of the deleted record, and the updates that did
+// not change any field are left out
+func WithAuditDiffOnly() AuditOption {
+	return func(c *AuditConfig) {
+		c.DiffOnly = true
+	}
+}
+
+// AuditOutput opens the writer the audit log is written to by the generated ExportAudit method, e.g. a file or an
+// object storage upload named after the time of the export; the writer is closed once the audit log is written
+type AuditOutput func(ctx context.Context, format AuditFormat) (io.WriteCloser, error)
+
+var (
+	// auditOutput is the audit output set using SetAuditOutput
+	auditOutput AuditOutput
+	// auditOutputMu guards the audit output
+	auditOutputMu sync.RWMutex
+)
+
+// SetAuditOutput sets the audit output the generated ExportAudit method writes the audit log to; the output is set
+// at runtime as it cannot be carried into the generated code. This is usually called when the application starts
+func SetAuditOutput(fn AuditOutput) {
+	auditOutputMu.Lock()
+	defer auditOutputMu.Unlock()
+
+	auditOutput = fn
+}
+
+// OpenAuditOutput opens the writer of the audit output set using SetAuditOutput, returning ErrAuditOutputNotSet
+// without an audit output
+func OpenAuditOutput(ctx context.Context, format AuditFormat) (io.WriteCloser, error) {
+	auditOutputMu.RLock()
+	open := auditOutput
+	auditOutputMu.RUnlock()
+
+	if open == nil {
+		return nil, ErrAuditOutputNotSet
+	}
+
+	return open(ctx, format)
+}
+
+// WriteAuditLog writes the records of the audit log, as returned by the generated Audit method, to the writer in the
+// format; the first record holds the column names
+func WriteAuditLog(w io.Writer, format AuditFormat, records [][]string) error {
+	switch format {
+	case AuditFormatCSV, "":
+		cw := csv.NewWriter(w)
+
+		return cw.WriteAll(records)
+	case AuditFormatJSON:
+		rows := make([]map[string]string, 0, max(len(records)-1, 0))
+
+		for i := 1; i < len(records); i++ {
+			row := make(map[string]string, len(records[0]))
+
+			for j, column := range records[0] {
+				if j < len(records[i]) {
+					row[column] = records[i][j]
+				}
+			}
+
+			rows = append(rows, row)
+		}
+
+		return json.NewEncoder(w).Encode(rows)
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnsupportedAuditFormat, format)
+}
+
+// validateAuditConfig checks the format of the audit config is supported
+func validateAuditConfig(c AuditConfig) error {
+	if c.Format != "" && !slices.Contains(auditFormats, c.Format) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAuditFormat, c.Format)
+	}
+
+	return nil
+}
+
+// auditedTable checks if the history schema (e.g. TodoHistory) is included in the audit log of the tables, all history
+// schemas are included when there are no tables
+func auditedTable(tables []string, name string) bool {
+	return len(tables) == 0 || slices.Contains(tables, strings.TrimSuffix(name, "History"))
+}
+
+// auditProgressKey is the context key for the audit progress function
+type auditProgressKey struct{}
+
+// AuditProgress is the progress of the audit log built by the generated Audit and AuditWithFilter methods
+type AuditProgress struct {
+	// Table is the history schema (e.g. TodoHistory) being audited
+	Table string
+	// Tables is the number of history schemas included in the audit log
+	Tables int
+	// TablesDone is the number of history schemas audited so far
+	TablesDone int
+	// Rows is the number of history rows processed so far
+	Rows int
+}
+
+// AuditProgressFunc is called by the generated Audit and AuditWithFilter methods as the history rows of each ref,
+// and each history schema, are processed
+type AuditProgressFunc func(ctx context.Context, progress AuditProgress)
+
+// NewAuditProgressContext returns a copy of the context with the audit progress function, the generated Audit and
+// AuditWithFilter methods called with this context report their progress to it, e.g. to update the progress bar of
+// an admin tool
+func NewAuditProgressContext(ctx context.Context, fn AuditProgressFunc) context.Context {
+	return context.WithValue(ctx, auditProgressKey{}, fn)
+}
+
+// ReportAuditProgress reports the progress to the audit progress function on the context, if it was set
+func ReportAuditProgress(ctx context.Context, progress AuditProgress) {
+	if fn, ok := ctx.Value(auditProgressKey{}).(AuditProgressFunc); ok && fn != nil {
+		fn(ctx, progress)
+	}
+}
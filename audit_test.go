@@ -0,0 +1,109 @@
+package enthistory
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAuditOutput is an audit output writing to a buffer
+type testAuditOutput struct {
+	bytes.Buffer
+}
+
+// Close closes the output
+func (o *testAuditOutput) Close() error {
+	return nil
+}
+
+func TestWriteAuditLog(t *testing.T) {
+	records := [][]string{
+		{"Table", "Ref Id", "Operation", "Changes"},
+		{"TodoHistory", "1", "INSERT", "name: \"a, b\""},
+		{"TodoHistory", "1", "UPDATE"},
+	}
+
+	var b bytes.Buffer
+
+	require.NoError(t, WriteAuditLog(&b, AuditFormatCSV, records))
+	assert.Equal(t, "Table,Ref Id,Operation,Changes\nTodoHistory,1,INSERT,\"name: \"\"a, b\"\"\"\nTodoHistory,1,UPDATE\n", b.String())
+
+	// csv is the default format
+	b.Reset()
+	require.NoError(t, WriteAuditLog(&b, "", records[:1]))
+	assert.Equal(t, "Table,Ref Id,Operation,Changes\n", b.String())
+
+	b.Reset()
+	require.NoError(t, WriteAuditLog(&b, AuditFormatJSON, records))
+	assert.JSONEq(t, `[
+		{"Table": "TodoHistory", "Ref Id": "1", "Operation": "INSERT", "Changes": "name: \"a, b\""},
+		{"Table": "TodoHistory", "Ref Id": "1", "Operation": "UPDATE"}
+	]`, b.String())
+
+	b.Reset()
+	require.NoError(t, WriteAuditLog(&b, AuditFormatJSON, nil))
+	assert.JSONEq(t, `[]`, b.String())
+
+	assert.ErrorIs(t, WriteAuditLog(&b, "xml", records), ErrUnsupportedAuditFormat)
+}
+
+func TestOpenAuditOutput(t *testing.T) {
+	t.Cleanup(func() { SetAuditOutput(nil) })
+
+	_, err := OpenAuditOutput(context.Background(), AuditFormatCSV)
+	assert.ErrorIs(t, err, ErrAuditOutputNotSet)
+
+	output := &testAuditOutput{}
+
+	SetAuditOutput(func(_ context.Context, format AuditFormat) (io.WriteCloser, error) {
+		assert.Equal(t, AuditFormatJSON, format)
+
+		return output, nil
+	})
+
+	w, err := OpenAuditOutput(context.Background(), AuditFormatJSON)
+	require.NoError(t, err)
+	assert.Same(t, output, w)
+}
+
+func TestValidateAuditConfig(t *testing.T) {
+	assert.NoError(t, validateAuditConfig(AuditConfig{}))
+	assert.NoError(t, validateAuditConfig(AuditConfig{Format: AuditFormatJSON}))
+	assert.ErrorIs(t, validateAuditConfig(AuditConfig{Format: "xml"}), ErrUnsupportedAuditFormat)
+}
+
+func TestAuditedTable(t *testing.T) {
+	assert.True(t, auditedTable(nil, "TodoHistory"))
+	assert.True(t, auditedTable([]string{"Todo"}, "TodoHistory"))
+	assert.False(t, auditedTable([]string{"Todo"}, "ListHistory"))
+}
+
+func TestGenerateSchemasAuditFormat(t *testing.T) {
+	dir := copyTestSchemas(t)
+
+	err := New(WithSchemaPath(dir), WithAuditing(WithAuditFormat("xml"))).GenerateSchemas()
+	assert.ErrorIs(t, err, ErrUnsupportedAuditFormat)
+}
+
+func TestReportAuditProgress(t *testing.T) {
+	// the progress is not reported without a progress function
+	assert.NotPanics(t, func() { ReportAuditProgress(context.Background(), AuditProgress{Tables: 1}) })
+
+	var reported []AuditProgress
+
+	ctx := NewAuditProgressContext(context.Background(), func(_ context.Context, progress AuditProgress) {
+		reported = append(reported, progress)
+	})
+
+	ReportAuditProgress(ctx, AuditProgress{Table: "TodoHistory", Tables: 2, Rows: 3})
+	ReportAuditProgress(ctx, AuditProgress{Table: "TodoHistory", Tables: 2, TablesDone: 1, Rows: 5})
+
+	assert.Equal(t, []AuditProgress{
+		{Table: "TodoHistory", Tables: 2, Rows: 3},
+		{Table: "TodoHistory", Tables: 2, TablesDone: 1, Rows: 5},
+	}, reported)
+}
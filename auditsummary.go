@@ -0,0 +1,157 @@
+package enthistory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"entgo.io/ent/dialect"
+)
+
+const (
+	// auditSummaryName is the name of the audit summary view, or table on dialects without materialized views
+	auditSummaryName = "audit_summary"
+)
+
+// auditSummaryDialects are the dialects the DDL of the audit summary is written for, and the dialects supported by
+// WithDialect
+var auditSummaryDialects = []string{dialect.Postgres, dialect.MySQL, dialect.SQLite}
+
+// AuditSummaryTable is a history table aggregated by the audit summary
+type AuditSummaryTable struct {
+	// Entity is the name of the tracked schema
+	Entity string
+	// Table is the name of the history table
+	Table string
+	// Actor is true when the history table has the updated_by field, which is the actor of the changes
+	Actor bool
+	// Tenant is true when the history table has the tenant_id field, the changes are then aggregated per tenant
+	Tenant bool
+}
+
+// auditSummaryQuery returns the query aggregating the number of changes of the history tables per entity, tenant,
+// actor, and day (formatted as YYYY-MM-DD), for the dialect
+func auditSummaryQuery(d string, tables []AuditSummaryTable) string {
+	day, actor := "STRFTIME('%Y-%m-%d', history_time)", "CAST(updated_by AS TEXT)"
+
+	switch d {
+	case dialect.Postgres:
+		day = "TO_CHAR(history_time, 'YYYY-MM-DD')"
+	case dialect.MySQL:
+		day, actor = "DATE_FORMAT(history_time, '%Y-%m-%d')", "CAST(updated_by AS CHAR)"
+	}
+
+	selects := make([]string, 0, len(tables))
+
+	for _, t := range tables {
+		// NULL columns are not grouped by, which is not allowed on Postgres
+		tenantCol, actorCol, groupBy := "NULL", "NULL", []string{day}
+
+		if t.Tenant {
+			tenantCol = tenantFieldName
+			groupBy = append(groupBy, tenantCol)
+		}
+
+		if t.Actor {
+			actorCol = actor
+			groupBy = append(groupBy, actorCol)
+		}
+
+		selects = append(selects, fmt.Sprintf("SELECT '%s' AS entity, %s AS tenant_id, %s AS actor, %s AS day, "+
+			"SUM(CASE WHEN operation = '%s' THEN 1 ELSE 0 END) AS creates, "+
+			"SUM(CASE WHEN operation = '%s' THEN 1 ELSE 0 END) AS updates, "+
+			"SUM(CASE WHEN operation = '%s' THEN 1 ELSE 0 END) AS deletes, "+
+			"COUNT(*) AS changes FROM %s GROUP BY %s",
+			t.Entity, tenantCol, actorCol, day, OpTypeInsert, OpTypeUpdate, OpTypeDelete, t.Table, strings.Join(groupBy, ", ")))
+	}
+
+	return strings.Join(selects, " UNION ALL ")
+}
+
+// auditSummaryDDL returns the DDL creating the audit summary for the dialect; a materialized view on Postgres, with
+// the unique index needed to refresh it concurrently, and a table on dialects without materialized views, the tables
+// are prefixed with the schema, if any
+func auditSummaryDDL(d, schemaName string, tables []AuditSummaryTable) string {
+	name := auditSummaryName
+	if schemaName != "" {
+		name = schemaName + "." + name
+		tables = slices.Clone(tables)
+
+		for i := range tables {
+			tables[i].Table = schemaName + "." + tables[i].Table
+		}
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- Code generated by enthistory, the number of changes per entity, tenant, actor, and day (%s)\n", d)
+
+	if d == dialect.Postgres {
+		fmt.Fprintf(&b, "CREATE MATERIALIZED VIEW %s AS %s;\n", name, auditSummaryQuery(d, tables))
+		fmt.Fprintf(&b, "CREATE UNIQUE INDEX %s_key ON %s (entity, tenant_id, actor, day);\n", auditSummaryName, name)
+
+		return b.String()
+	}
+
+	b.WriteString("-- materialized views are not supported, the table is populated by RefreshAuditSummary\n")
+	fmt.Fprintf(&b, "CREATE TABLE %s (entity VARCHAR(255) NOT NULL, tenant_id VARCHAR(255) NULL, actor VARCHAR(255) NULL, "+
+		"day VARCHAR(10) NOT NULL, creates BIGINT NOT NULL, updates BIGINT NOT NULL, deletes BIGINT NOT NULL, "+
+		"changes BIGINT NOT NULL);\n", name)
+	fmt.Fprintf(&b, "CREATE UNIQUE INDEX %s_key ON %s (entity, tenant_id, actor, day);\n", auditSummaryName, name)
+
+	return b.String()
+}
+
+// writeAuditSummary writes the DDL of the audit summary for each of the dialects to the directory, the files are named
+// after the dialect (e.g. audit_summary.postgres.sql)
+func writeAuditSummary(dir, schemaName string, tables []AuditSummaryTable, dialects []string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, d := range dialects {
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s.sql", auditSummaryName, d))
+
+		if err := os.WriteFile(path, []byte(auditSummaryDDL(d, schemaName, tables)), 0o600); err != nil { //nolint:mnd
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RefreshAuditSummary refreshes the audit summary of the history tables, concurrently on Postgres so it can be
+// queried while refreshing, and by replacing the rows of the audit summary table in a transaction on dialects without
+// materialized views; this is used by the generated RefreshAuditSummary of the client
+func RefreshAuditSummary(ctx context.Context, drv dialect.Driver, tables []AuditSummaryTable) error {
+	if drv.Dialect() == dialect.Postgres {
+		return drv.Exec(ctx, fmt.Sprintf("REFRESH MATERIALIZED VIEW CONCURRENTLY %s", auditSummaryName), []any{}, nil)
+	}
+
+	tx, err := drv.Tx(ctx)
+	if err != nil {
+		return err
+	}
+
+	stmts := []string{
+		fmt.Sprintf("DELETE FROM %s", auditSummaryName),
+	}
+
+	if len(tables) > 0 {
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s (entity, tenant_id, actor, day, creates, updates, deletes, changes) %s",
+			auditSummaryName, auditSummaryQuery(drv.Dialect(), tables)))
+	}
+
+	for _, stmt := range stmts {
+		if err := tx.Exec(ctx, stmt, []any{}, nil); err != nil {
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
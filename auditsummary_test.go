@@ -0,0 +1,108 @@
+package enthistory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDriver is a dialect.Driver recording the executed statements
+type recordingDriver struct {
+	dialect string
+	stmts   []string
+}
+
+func (d *recordingDriver) Exec(_ context.Context, query string, _, _ any) error {
+	d.stmts = append(d.stmts, query)
+
+	return nil
+}
+
+func (d *recordingDriver) Query(_ context.Context, query string, _, _ any) error {
+	d.stmts = append(d.stmts, query)
+
+	return nil
+}
+
+func (d *recordingDriver) Tx(context.Context) (dialect.Tx, error) {
+	return dialect.NopTx(d), nil
+}
+
+func (d *recordingDriver) Close() error { return nil }
+
+func (d *recordingDriver) Dialect() string { return d.dialect }
+
+func TestAuditSummaryQuery(t *testing.T) {
+	tables := []AuditSummaryTable{
+		{Entity: "Todo", Table: "todo_history", Actor: true, Tenant: true},
+		{Entity: "Beat", Table: "beat_history"},
+	}
+
+	query := auditSummaryQuery(dialect.Postgres, tables)
+
+	assert.Contains(t, query, "SELECT 'Todo' AS entity, tenant_id AS tenant_id, CAST(updated_by AS TEXT) AS actor, "+
+		"TO_CHAR(history_time, 'YYYY-MM-DD') AS day")
+	assert.Contains(t, query, "SUM(CASE WHEN operation = 'INSERT' THEN 1 ELSE 0 END) AS creates")
+	assert.Contains(t, query, "FROM todo_history GROUP BY TO_CHAR(history_time, 'YYYY-MM-DD'), tenant_id, CAST(updated_by AS TEXT)")
+	assert.Contains(t, query, " UNION ALL SELECT 'Beat' AS entity, NULL AS tenant_id, NULL AS actor")
+	assert.Contains(t, query, "FROM beat_history GROUP BY TO_CHAR(history_time, 'YYYY-MM-DD')")
+
+	query = auditSummaryQuery(dialect.MySQL, tables)
+	assert.Contains(t, query, "CAST(updated_by AS CHAR) AS actor, DATE_FORMAT(history_time, '%Y-%m-%d') AS day")
+
+	query = auditSummaryQuery(dialect.SQLite, tables)
+	assert.Contains(t, query, "STRFTIME('%Y-%m-%d', history_time) AS day")
+}
+
+func TestAuditSummaryDDL(t *testing.T) {
+	tables := []AuditSummaryTable{{Entity: "Todo", Table: "todo_history"}}
+
+	ddl := auditSummaryDDL(dialect.Postgres, "audit", tables)
+	assert.Contains(t, ddl, "CREATE MATERIALIZED VIEW audit.audit_summary AS SELECT 'Todo' AS entity")
+	assert.Contains(t, ddl, "FROM audit.todo_history")
+	assert.Contains(t, ddl, "CREATE UNIQUE INDEX audit_summary_key ON audit.audit_summary (entity, tenant_id, actor, day);")
+
+	ddl = auditSummaryDDL(dialect.SQLite, "", []AuditSummaryTable{{Entity: "Todo", Table: "todo_history"}})
+	assert.Contains(t, ddl, "CREATE TABLE audit_summary (entity VARCHAR(255) NOT NULL")
+	assert.NotContains(t, ddl, "MATERIALIZED")
+
+	dir := filepath.Join(t.TempDir(), "summary")
+	require.NoError(t, writeAuditSummary(dir, "audit", tables, auditSummaryDialects))
+
+	for _, d := range auditSummaryDialects {
+		out, err := os.ReadFile(filepath.Join(dir, "audit_summary."+d+".sql"))
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "CREATE UNIQUE INDEX audit_summary_key ON audit.audit_summary")
+	}
+
+	dir = filepath.Join(t.TempDir(), "mysql")
+	require.NoError(t, writeAuditSummary(dir, "", tables, []string{dialect.MySQL}))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "audit_summary.mysql.sql", entries[0].Name())
+
+	// the tables passed are not prefixed with the schema
+	assert.Equal(t, "todo_history", tables[0].Table)
+}
+
+func TestRefreshAuditSummary(t *testing.T) {
+	tables := []AuditSummaryTable{{Entity: "Todo", Table: "todo_history", Actor: true}}
+
+	drv := &recordingDriver{dialect: dialect.Postgres}
+	require.NoError(t, RefreshAuditSummary(context.Background(), drv, tables))
+	assert.Equal(t, []string{"REFRESH MATERIALIZED VIEW CONCURRENTLY audit_summary"}, drv.stmts)
+
+	drv = &recordingDriver{dialect: dialect.SQLite}
+	require.NoError(t, RefreshAuditSummary(context.Background(), drv, tables))
+	require.Len(t, drv.stmts, 2)
+	assert.Equal(t, "DELETE FROM audit_summary", drv.stmts[0])
+	assert.Equal(t, "INSERT INTO audit_summary (entity, tenant_id, actor, day, creates, updates, deletes, changes) "+
+		auditSummaryQuery(dialect.SQLite, tables), drv.stmts[1])
+}
@@ -0,0 +1,31 @@
+package enthistory
+
+import (
+	"context"
+)
+
+// AuthzDecision is the authorization decision that permitted a mutation, the subject and the relation of the subject
+// to the mutated object (e.g. user:123 with the can_edit relation)
+type AuthzDecision struct {
+	// Subject is the subject permitted to make the mutation, e.g. user:123
+	Subject string
+	// Relation is the relation of the subject to the mutated object that permitted the mutation, e.g. can_edit
+	Relation string
+}
+
+// authzDecisionKey is the context key for the authorization decision
+type authzDecisionKey struct{}
+
+// NewAuthzDecisionContext returns a copy of the context with the authorization decision, history rows created with
+// this context are stamped with the subject and relation when using WithAuthzDecision, so audits capture under which
+// permission the data was changed; this is usually set by the authorization check of the mutation (e.g. the FGA check)
+func NewAuthzDecisionContext(ctx context.Context, decision AuthzDecision) context.Context {
+	return context.WithValue(ctx, authzDecisionKey{}, decision)
+}
+
+// AuthzDecisionFromContext returns the authorization decision from the context, if it was set
+func AuthzDecisionFromContext(ctx context.Context) (AuthzDecision, bool) {
+	decision, ok := ctx.Value(authzDecisionKey{}).(AuthzDecision)
+
+	return decision, ok && (decision.Subject != "" || decision.Relation != "")
+}
@@ -0,0 +1,48 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthzDecisionFromContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		want   AuthzDecision
+		wantOk bool
+	}{
+		{
+			name:   "happy path",
+			ctx:    NewAuthzDecisionContext(context.Background(), AuthzDecision{Subject: "user:123", Relation: "can_edit"}),
+			want:   AuthzDecision{Subject: "user:123", Relation: "can_edit"},
+			wantOk: true,
+		},
+		{
+			name:   "relation only",
+			ctx:    NewAuthzDecisionContext(context.Background(), AuthzDecision{Relation: "can_edit"}),
+			want:   AuthzDecision{Relation: "can_edit"},
+			wantOk: true,
+		},
+		{
+			name:   "empty decision",
+			ctx:    NewAuthzDecisionContext(context.Background(), AuthzDecision{}),
+			wantOk: false,
+		},
+		{
+			name:   "not set",
+			ctx:    context.Background(),
+			wantOk: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := AuthzDecisionFromContext(tc.ctx)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantOk, ok)
+		})
+	}
+}
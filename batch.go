@@ -0,0 +1,96 @@
+package enthistory
+
+import (
+	"context"
+	"sync"
+)
+
+// batchKey is the context key for the history batch
+type batchKey struct{}
+
+// historyBatch holds the history rows of creates buffered in a batch context, by history table
+type historyBatch struct {
+	mu sync.Mutex
+	// tables are the history tables in the order their first row was buffered
+	tables []string
+	// rows are the buffered history rows by history table
+	rows map[string][]any
+	// save inserts the history rows of the table, using the client and context of its first row
+	save map[string]func([]any) error
+}
+
+// NewBatchContext returns a copy of the context buffering the history rows of the creates using it (e.g. the records
+// created by CreateBulk), instead of inserting a history row for each record, the buffered history rows are inserted
+// using a single bulk create for each history table by FlushBatch
+func NewBatchContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, batchKey{}, &historyBatch{
+		rows: map[string][]any{},
+		save: map[string]func([]any) error{},
+	})
+}
+
+// FlushBatch inserts the history rows buffered in the batch context, using a single bulk create for each history
+// table; this does nothing when the context is not a batch context
+func FlushBatch(ctx context.Context) error {
+	b, ok := ctx.Value(batchKey{}).(*historyBatch)
+	if !ok {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, table := range b.tables {
+		if err := b.save[table](b.rows[table]); err != nil {
+			return err
+		}
+	}
+
+	b.tables = nil
+	b.rows = map[string][]any{}
+	b.save = map[string]func([]any) error{}
+
+	return nil
+}
+
+// Batch runs the function with a batch context, and inserts the buffered history rows once the function succeeds, e.g.
+// to bulk import records using CreateBulk with a single bulk create of their history rows; run this in a transaction,
+// so the records are not committed without their history when inserting the history rows fails
+func Batch(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx = NewBatchContext(ctx)
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	return FlushBatch(ctx)
+}
+
+// SaveHistory inserts the history row of a create using the save function, or buffers it when the context is a batch
+// context, in which case the buffered history rows of the table are inserted together using the save function of the
+// first; this is used by the generated history hooks
+func SaveHistory[T any](ctx context.Context, table string, row T, save func(context.Context, []T) error) error {
+	b, ok := ctx.Value(batchKey{}).(*historyBatch)
+	if !ok {
+		return save(ctx, []T{row})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.save[table]; !ok {
+		b.tables = append(b.tables, table)
+		b.save[table] = func(rows []any) error {
+			typed := make([]T, 0, len(rows))
+			for _, r := range rows {
+				typed = append(typed, r.(T))
+			}
+
+			return save(ctx, typed)
+		}
+	}
+
+	b.rows[table] = append(b.rows[table], row)
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveHistory(t *testing.T) {
+	var saved [][]string
+
+	save := func(_ context.Context, rows []string) error {
+		saved = append(saved, rows)
+
+		return nil
+	}
+
+	require.NoError(t, SaveHistory(context.Background(), "todo_history", "a", save))
+	require.NoError(t, SaveHistory(context.Background(), "todo_history", "b", save))
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, saved)
+}
+
+func TestBatch(t *testing.T) {
+	var saved []any
+
+	err := Batch(context.Background(), func(ctx context.Context) error {
+		todos := func(_ context.Context, rows []string) error {
+			saved = append(saved, rows)
+
+			return nil
+		}
+
+		users := func(_ context.Context, rows []int) error {
+			saved = append(saved, rows)
+
+			return nil
+		}
+
+		require.NoError(t, SaveHistory(ctx, "todo_history", "a", todos))
+		require.NoError(t, SaveHistory(ctx, "user_history", 1, users))
+		require.NoError(t, SaveHistory(ctx, "todo_history", "b", todos))
+
+		assert.Empty(t, saved)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{[]string{"a", "b"}, []int{1}}, saved)
+}
+
+func TestBatchError(t *testing.T) {
+	errCreate := errors.New("create failed")
+	saved := false
+
+	err := Batch(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, SaveHistory(ctx, "todo_history", "a", func(context.Context, []string) error {
+			saved = true
+
+			return nil
+		}))
+
+		return errCreate
+	})
+	assert.ErrorIs(t, err, errCreate)
+	assert.False(t, saved)
+}
+
+func TestFlushBatch(t *testing.T) {
+	require.NoError(t, FlushBatch(context.Background()))
+
+	ctx := NewBatchContext(context.Background())
+	calls := 0
+
+	save := func(_ context.Context, rows []string) error {
+		calls++
+
+		assert.Equal(t, []string{"a"}, rows)
+
+		return nil
+	}
+
+	require.NoError(t, SaveHistory(ctx, "todo_history", "a", save))
+	require.NoError(t, FlushBatch(ctx))
+	require.NoError(t, FlushBatch(ctx))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,72 @@
+package enthistory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Invalidator is called with the ref of the record once history rows written for it are committed, e.g. to evict the cached
+// history queries of the record from a query cache such as entcache
+type Invalidator func(ctx context.Context, table string, ref any)
+
+var (
+	// invalidators are the invalidators registered using RegisterInvalidator
+	invalidators []Invalidator
+	// queryContext returns the context of the queries run by the history hooks, set using SetQueryContext
+	queryContext func(context.Context) context.Context
+	// cacheMu guards the invalidators and the query context
+	cacheMu sync.RWMutex
+)
+
+// CacheKey returns the key of the cached history queries of the ref in the history table, e.g. to cache the history of
+// a record using entcache.WithKey, and evict it using an invalidator when history rows are written for the record
+func CacheKey(table string, ref any) string {
+	return fmt.Sprintf("enthistory:%s:%v", table, ref)
+}
+
+// RegisterInvalidator registers invalidators called once history rows written for a record are committed, or the history
+// of the record is erased or compacted; this is usually called when the application starts
+func RegisterInvalidator(i ...Invalidator) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	invalidators = append(invalidators, i...)
+}
+
+// InvalidateRef invalidates the cached latest history row of the ref in the history table, and calls the registered
+// invalidators; this is used by the generated history hooks once history rows are committed
+func InvalidateRef(ctx context.Context, table string, ref any) {
+	InvalidateLatest(ctx, table, ref)
+
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+
+	for _, invalidate := range invalidators {
+		invalidate(ctx, table, ref)
+	}
+}
+
+// SetQueryContext sets the function returning the context of the queries run by the history hooks, e.g. entcache.Skip
+// so the history rows are created from the stored records instead of cached query results when the client uses a
+// caching driver; the context is not changed when it is nil
+func SetQueryContext(fn func(context.Context) context.Context) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	queryContext = fn
+}
+
+// newQueryContext returns the context of the queries run by the history hooks, using the function set using
+// SetQueryContext
+func newQueryContext(ctx context.Context) context.Context {
+	cacheMu.RLock()
+	fn := queryContext
+	cacheMu.RUnlock()
+
+	if fn == nil {
+		return ctx
+	}
+
+	return fn(ctx)
+}
@@ -0,0 +1,57 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testQueryKey is the context key set by the test query context
+type testQueryKey struct{}
+
+func TestCacheKey(t *testing.T) {
+	assert.Equal(t, "enthistory:todo_history:7", CacheKey("todo_history", 7))
+	assert.Equal(t, "enthistory:todo_history:abc", CacheKey("todo_history", "abc"))
+}
+
+func TestInvalidateRef(t *testing.T) {
+	t.Cleanup(func() {
+		invalidators = nil
+
+		SetLatestCache(nil)
+	})
+
+	ctx := context.Background()
+
+	latest := NewMemoryLatestCache(10)
+	latest.Set(ctx, "todo_history", 7, "row")
+
+	SetLatestCache(latest)
+
+	var got []string
+
+	RegisterInvalidator(func(_ context.Context, table string, ref any) {
+		got = append(got, CacheKey(table, ref))
+	})
+
+	InvalidateRef(ctx, "todo_history", 7)
+
+	assert.Equal(t, []string{"enthistory:todo_history:7"}, got)
+	assert.Equal(t, 0, latest.Len())
+}
+
+func TestSetQueryContext(t *testing.T) {
+	ctx := newHistoryContext(context.Background())
+
+	assert.Nil(t, ctx.Value(testQueryKey{}))
+
+	SetQueryContext(func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, testQueryKey{}, true)
+	})
+	t.Cleanup(func() { SetQueryContext(nil) })
+
+	ctx = newHistoryContext(context.Background())
+
+	assert.Equal(t, true, ctx.Value(testQueryKey{}))
+}
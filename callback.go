@@ -0,0 +1,60 @@
+package enthistory
+
+import (
+	"context"
+	"sync"
+
+	"entgo.io/ent"
+)
+
+// Callback is called with the history event of each history row once it is written, e.g. to send notifications,
+// invalidate caches, or index the changes for search
+type Callback func(ctx context.Context, event HistoryEvent)
+
+var (
+	// callbacks are the callbacks registered using RegisterCallback
+	callbacks []Callback
+	// callbacksMu guards the registered callbacks
+	callbacksMu sync.RWMutex
+)
+
+// RegisterCallback registers callbacks called after each history row of all schemas is written, when using
+// WithCallbacks; this is usually called when the application starts
+func RegisterCallback(cb ...Callback) {
+	callbacksMu.Lock()
+	defer callbacksMu.Unlock()
+
+	callbacks = append(callbacks, cb...)
+}
+
+// CallbackHook returns a hook calling the callbacks registered using RegisterCallback once the history rows are
+// committed, or right away outside of transactions, so the history rows of rolled back transactions are not sent; this
+// is added to the generated history schemas when using WithCallbacks
+func CallbackHook() ent.Hook {
+	return On(func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			v, err := next.Mutate(ctx, m)
+			if err != nil {
+				return v, err
+			}
+
+			callbacksMu.RLock()
+			registered := callbacks
+			callbacksMu.RUnlock()
+
+			if len(registered) == 0 {
+				return v, nil
+			}
+
+			event := newHistoryEvent(m, v)
+
+			return v, afterCommit(m, func() error {
+				for _, cb := range registered {
+					cb(ctx, event)
+				}
+
+				return nil
+			})
+		})
+	}, ent.OpCreate)
+}
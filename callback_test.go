@@ -0,0 +1,73 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackHook(t *testing.T) {
+	t.Cleanup(func() {
+		callbacks = nil
+	})
+
+	now := time.Now()
+
+	m := &testSinkMutation{
+		fields: map[string]ent.Value{
+			"ref":          7,
+			"operation":    OpTypeInsert,
+			"history_time": now,
+		},
+	}
+
+	mutator := CallbackHook()(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return &testSinkRow{ID: 3}, nil
+	}))
+
+	_, err := mutator.Mutate(context.Background(), m)
+	require.NoError(t, err)
+
+	var first, second []HistoryEvent
+
+	RegisterCallback(
+		func(_ context.Context, e HistoryEvent) { first = append(first, e) },
+		func(_ context.Context, e HistoryEvent) { second = append(second, e) },
+	)
+
+	_, err = mutator.Mutate(context.Background(), m)
+	require.NoError(t, err)
+
+	require.Len(t, first, 1)
+	assert.Equal(t, first, second)
+	assert.Equal(t, "Todo", first[0].Entity)
+	assert.Equal(t, 3, first[0].ID)
+	assert.Equal(t, 7, first[0].Ref)
+	assert.Equal(t, OpTypeInsert, first[0].Operation)
+	assert.Equal(t, now, first[0].HistoryTime)
+
+	errWrite := errors.New("history not written")
+
+	failing := CallbackHook()(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return nil, errWrite
+	}))
+
+	_, err = failing.Mutate(context.Background(), m)
+	require.ErrorIs(t, err, errWrite)
+	assert.Len(t, first, 1)
+
+	// the callbacks of transactions are called once committed
+	tx := &testTxMutation{testSinkMutation: *m}
+
+	_, err = mutator.Mutate(context.Background(), tx)
+	require.NoError(t, err)
+	assert.Len(t, first, 1)
+
+	require.NoError(t, tx.commit())
+	assert.Len(t, first, 2)
+}
@@ -0,0 +1,85 @@
+package enthistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ClickHouseOption is a functional option for NewClickHouseSink
+type ClickHouseOption = SinkOption
+
+// ClickHouseSink is the reference Sink writing the history events to a ClickHouse table, created using ClickHouseDDL,
+// in batches; the events are buffered and written when the batch size is reached, at the flush interval, and when
+// the sink is closed. The database must be opened using a ClickHouse database/sql driver (e.g. clickhouse-go), which
+// sends the rows inserted using a prepared statement in a transaction as a single batch
+type ClickHouseSink struct {
+	*bufferedSink
+
+	db    *sql.DB
+	table string
+}
+
+// NewClickHouseSink returns a sink writing the history events to the ClickHouse table in batches, the sink must be
+// closed to write the buffered events
+func NewClickHouseSink(db *sql.DB, table string, opts ...ClickHouseOption) *ClickHouseSink {
+	s := &ClickHouseSink{
+		db:    db,
+		table: table,
+	}
+
+	s.bufferedSink = newBufferedSink(table, newSinkConfig(opts...), s.insert)
+
+	return s
+}
+
+// insert writes the history events to ClickHouse as a single batch, the values of the fields are stored as JSON
+func (s *ClickHouseSink) insert(ctx context.Context, events []HistoryEvent) error {
+	rows, err := sinkRows(events)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s)", s.table, strings.Join(sinkColumns, ", "))
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClickHouseDDL returns the DDL creating the ClickHouse table the history events are written to by the ClickHouseSink,
+// ordered by entity, ref, and history time, the values of the fields of the history rows are stored as JSON
+func ClickHouseDDL(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n"+
+		"\thistory_type LowCardinality(String),\n"+
+		"\tentity LowCardinality(String),\n"+
+		"\tid String,\n"+
+		"\tref String,\n"+
+		"\toperation LowCardinality(String),\n"+
+		"\thistory_time DateTime64(6, 'UTC'),\n"+
+		"\tfields String\n"+
+		") ENGINE = MergeTree\n"+
+		"PARTITION BY toYYYYMM(history_time)\n"+
+		"ORDER BY (entity, ref, history_time, id)\n", table)
+}
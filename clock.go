@@ -0,0 +1,62 @@
+package enthistory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock provides the time used to stamp the history_time of the history rows
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is a function implementing Clock
+type ClockFunc func() time.Time
+
+// Now returns the time of the clock
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+var (
+	// defaultClock is the clock used when there is no clock on the context, set using SetClock
+	defaultClock Clock = ClockFunc(time.Now)
+	// defaultClockMu guards the default clock
+	defaultClockMu sync.RWMutex
+)
+
+// SetClock sets the clock used by the history hooks of all schemas when there is no clock on the context,
+// e.g. a fixed clock for deterministic tests; a nil clock resets it to time.Now
+func SetClock(c Clock) {
+	defaultClockMu.Lock()
+	defer defaultClockMu.Unlock()
+
+	if c == nil {
+		c = ClockFunc(time.Now)
+	}
+
+	defaultClock = c
+}
+
+// clockKey is the context key for the clock
+type clockKey struct{}
+
+// NewClockContext returns a copy of the context with the clock, history rows created with this context
+// are stamped with the time of the clock, e.g. for backdated imports
+func NewClockContext(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// Now returns the time used to stamp the history rows, from the clock on the context or the clock
+// set using SetClock, which defaults to time.Now
+func Now(ctx context.Context) time.Time {
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok && c != nil {
+		return c.Now()
+	}
+
+	defaultClockMu.RLock()
+	defer defaultClockMu.RUnlock()
+
+	return defaultClock.Now()
+}
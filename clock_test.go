@@ -0,0 +1,28 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNow(t *testing.T) {
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	backdated := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	before := time.Now()
+	assert.False(t, Now(context.Background()).Before(before))
+
+	SetClock(ClockFunc(func() time.Time { return fixed }))
+	t.Cleanup(func() { SetClock(nil) })
+
+	assert.Equal(t, fixed, Now(context.Background()))
+
+	ctx := NewClockContext(context.Background(), ClockFunc(func() time.Time { return backdated }))
+	assert.Equal(t, backdated, Now(ctx))
+
+	SetClock(nil)
+	assert.False(t, Now(context.Background()).Before(before))
+}
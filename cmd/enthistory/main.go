@@ -0,0 +1,84 @@
+// Command enthistory inspects the history tables generated by enthistory, for debugging changes from the terminal:
+//
+//	enthistory [-driver postgres] [-dsn DSN] show <table> <ref>
+//	enthistory [-driver postgres] [-dsn DSN] diff <table> <ref> <from> <to>
+//
+// show lists the history rows of the ref in the history table, and diff lists the columns that changed between the
+// history rows from and to of the ref; the database is connected to using the dsn, DATABASE_URL by default
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/datumforge/enthistory"
+)
+
+// DSNEnv is the environment variable of the data source name used when the dsn flag is not set
+const DSNEnv = "DATABASE_URL"
+
+// usage is the usage of the enthistory command
+const usage = `usage:
+  enthistory [-driver postgres] [-dsn DSN] show <table> <ref>
+  enthistory [-driver postgres] [-dsn DSN] diff <table> <ref> <from> <to>
+`
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the enthistory command with the arguments, and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("enthistory", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	driver := flags.String("driver", "postgres", "database driver, postgres or sqlite3")
+	dsn := flags.String("dsn", os.Getenv(DSNEnv), "data source name of the database, $"+DSNEnv+" by default")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	args = flags.Args()
+
+	if len(args) == 0 || (args[0] == "show" && len(args) != 3) || (args[0] == "diff" && len(args) != 5) ||
+		(args[0] != "show" && args[0] != "diff") {
+		flags.Usage()
+
+		return 2
+	}
+
+	db, err := sql.Open(*driver, *dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return 1
+	}
+
+	defer db.Close()
+
+	if args[0] == "show" {
+		err = enthistory.ShowHistory(ctx, db, stdout, args[1], args[2])
+	} else {
+		err = enthistory.DiffHistory(ctx, db, stdout, args[1], args[2], args[3], args[4])
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return 1
+	}
+
+	return 0
+}
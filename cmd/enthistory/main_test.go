@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "history.db")
+
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`CREATE TABLE todo_history (id integer PRIMARY KEY, history_time text, ref text, name text);
+	INSERT INTO todo_history VALUES (1, '2024-01-01', '7', 'a'), (2, '2024-01-02', '7', 'b')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+	}{
+		{
+			name:   "show",
+			args:   []string{"-driver", "sqlite3", "-dsn", dsn, "show", "todo_history", "7"},
+			stdout: "ID  HISTORY_TIME  REF  NAME\n1   2024-01-01    7    a\n2   2024-01-02    7    b\n",
+		},
+		{
+			name:   "diff",
+			args:   []string{"-driver", "sqlite3", "-dsn", dsn, "diff", "todo_history", "7", "1", "2"},
+			stdout: "COLUMN        1           2\nhistory_time  2024-01-01  2024-01-02\nname          a           b\n",
+		},
+		{
+			name: "not found",
+			args: []string{"-driver", "sqlite3", "-dsn", dsn, "diff", "todo_history", "7", "1", "9"},
+			code: 1,
+		},
+		{
+			name: "missing arguments",
+			args: []string{"-driver", "sqlite3", "-dsn", dsn, "show", "todo_history"},
+			code: 2,
+		},
+		{
+			name: "unknown command",
+			args: []string{"restore", "todo_history", "7"},
+			code: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			assert.Equal(t, tt.code, run(context.Background(), tt.args, &stdout, &stderr))
+			assert.Equal(t, tt.stdout, stdout.String())
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package enthistory
+
+import (
+	"time"
+)
+
+// CompactRows returns the history rows of a record removed by compacting them, the rows must be ordered by their
+// history_time; runs of update rows recorded before olderThan are collapsed into snapshots, keeping the first and
+// last rows of each run and at most one row every keepEvery in between, all other rows are kept. This is used by
+// the generated CompactHistory methods of the history clients
+func CompactRows[T any](rows []T, olderThan time.Time, keepEvery time.Duration, op func(T) OpType, historyTime func(T) time.Time) []T {
+	var (
+		removed []T
+		run     []T
+	)
+
+	collapse := func() {
+		if len(run) > 2 { //nolint:mnd
+			kept := historyTime(run[0])
+
+			for _, row := range run[1 : len(run)-1] {
+				if keepEvery > 0 && !historyTime(row).Before(kept.Add(keepEvery)) {
+					kept = historyTime(row)
+
+					continue
+				}
+
+				removed = append(removed, row)
+			}
+		}
+
+		run = nil
+	}
+
+	for _, row := range rows {
+		if op(row) != OpTypeUpdate || !historyTime(row).Before(olderThan) {
+			collapse()
+
+			continue
+		}
+
+		run = append(run, row)
+	}
+
+	collapse()
+
+	return removed
+}
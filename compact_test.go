@@ -0,0 +1,73 @@
+package enthistory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type compactRow struct {
+	id int
+	op OpType
+	at time.Duration
+}
+
+func TestCompactRows(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	rows := []compactRow{
+		{id: 1, op: OpTypeInsert},
+		{id: 2, op: OpTypeUpdate, at: time.Minute},
+		{id: 3, op: OpTypeUpdate, at: 2 * time.Minute},
+		{id: 4, op: OpTypeUpdate, at: 30 * time.Minute},
+		{id: 5, op: OpTypeUpdate, at: 70 * time.Minute},
+		{id: 6, op: OpTypeUpdate, at: 80 * time.Minute},
+		{id: 7, op: OpTypeUpdate, at: 90 * time.Minute},
+		{id: 8, op: OpTypeDelete, at: 100 * time.Minute},
+		{id: 9, op: OpTypeInsert, at: 110 * time.Minute},
+		{id: 10, op: OpTypeUpdate, at: 120 * time.Minute},
+		{id: 11, op: OpTypeUpdate, at: 130 * time.Minute},
+		{id: 12, op: OpTypeUpdate, at: 140 * time.Minute},
+		// rows recorded after the threshold are not compacted
+		{id: 13, op: OpTypeUpdate, at: 200 * time.Minute},
+		{id: 14, op: OpTypeUpdate, at: 210 * time.Minute},
+		{id: 15, op: OpTypeUpdate, at: 220 * time.Minute},
+	}
+
+	tests := []struct {
+		name      string
+		keepEvery time.Duration
+		want      []int
+	}{
+		{
+			name: "endpoints only",
+			want: []int{3, 4, 5, 6, 11},
+		},
+		{
+			name:      "hourly snapshots",
+			keepEvery: time.Hour,
+			want:      []int{3, 4, 6, 11},
+		},
+		{
+			name:      "snapshots wider than the runs",
+			keepEvery: 24 * time.Hour,
+			want:      []int{3, 4, 5, 6, 11},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed := CompactRows(rows, start.Add(3*time.Hour), tt.keepEvery,
+				func(r compactRow) OpType { return r.op },
+				func(r compactRow) time.Time { return start.Add(r.at) },
+			)
+
+			ids := make([]int, 0, len(removed))
+			for _, r := range removed {
+				ids = append(ids, r.id)
+			}
+
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package enthistory
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/schema/field"
+)
+
+// Compression is the name of the algorithm used to compress the values of a history column
+type Compression string
+
+const (
+	// CompressionGzip compresses the values using gzip, this is the default
+	CompressionGzip Compression = "gzip"
+	// CompressionZlib compresses the values using zlib
+	CompressionZlib Compression = "zlib"
+	// CompressionZstd compresses the values using zstd, the Compressor must be registered using RegisterCompressor
+	CompressionZstd Compression = "zstd"
+)
+
+const (
+	// maxCompressionNameLen is the maximum length of the name of a compression stored before the compressed values
+	maxCompressionNameLen = 16
+)
+
+// Compressor compresses and decompresses the values of the history columns
+type Compressor interface {
+	// Compress returns the compressed value
+	Compress(value []byte) ([]byte, error)
+	// Decompress returns the value decompressed
+	Decompress(value []byte) ([]byte, error)
+}
+
+var (
+	// compressors are the registered compressors by name
+	compressors = map[Compression]Compressor{
+		CompressionGzip: gzipCompressor{},
+		CompressionZlib: zlibCompressor{},
+	}
+	// compressorsMu guards the compressors
+	compressorsMu sync.RWMutex
+)
+
+// RegisterCompressor registers the compressor used for the compression, e.g. a zstd compressor for CompressionZstd;
+// compressors must be registered before history is created or queried
+func RegisterCompressor(name Compression, c Compressor) {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+
+	compressors[name] = c
+}
+
+// getCompressor returns the compressor registered for the compression
+func getCompressor(name Compression) (Compressor, error) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+
+	c, ok := compressors[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCompressorNotRegistered, name)
+	}
+
+	return c, nil
+}
+
+// compressValue compresses the value, the name of the compression is stored before the compressed value
+// followed by a NUL byte, so values compressed using other compressions can still be decompressed
+func compressValue(name Compression, value []byte) ([]byte, error) {
+	c, err := getCompressor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed, err := c.Compress(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte(name), 0), compressed...), nil
+}
+
+// decompressValue decompresses the value using the compression stored before it, values stored before
+// the column was compressed are returned as is
+func decompressValue(value []byte) ([]byte, error) {
+	i := bytes.IndexByte(value, 0)
+	if i <= 0 || i > maxCompressionNameLen || !isCompressionName(value[:i]) {
+		return value, nil
+	}
+
+	c, err := getCompressor(Compression(value[:i]))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Decompress(value[i+1:])
+}
+
+// isCompressionName checks if the value can be the name of a compression, lowercase letters and digits
+func isCompressionName(value []byte) bool {
+	for _, b := range value {
+		if (b < 'a' || b > 'z') && (b < '0' || b > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// gzipCompressor compresses the values using gzip
+type gzipCompressor struct{}
+
+// Compress returns the gzip compressed value
+func (gzipCompressor) Compress(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(value); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the gzip decompressed value
+func (gzipCompressor) Decompress(value []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// zlibCompressor compresses the values using zlib
+type zlibCompressor struct{}
+
+// Compress returns the zlib compressed value
+func (zlibCompressor) Compress(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(value); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the zlib decompressed value
+func (zlibCompressor) Decompress(value []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// compressedStringScanner is the field.TypeValueScanner of compressed string fields, the values are
+// compressed when written and decompressed when read, so the history rows hold the original values
+type compressedStringScanner struct {
+	compression Compression
+}
+
+// Value returns the compressed value
+func (s compressedStringScanner) Value(v string) (driver.Value, error) {
+	return compressValue(s.compression, []byte(v))
+}
+
+// ScanValue returns the scanner of the compressed value
+func (compressedStringScanner) ScanValue() field.ValueScanner {
+	return &compressedValue{}
+}
+
+// FromValue returns the decompressed value
+func (compressedStringScanner) FromValue(v driver.Value) (string, error) {
+	b, err := scannedBytes(v)
+	if err != nil {
+		return "", err
+	}
+
+	b, err = decompressValue(b)
+
+	return string(b), err
+}
+
+// compressedBytesScanner is the field.TypeValueScanner of compressed bytes fields, the values are
+// compressed when written and decompressed when read, so the history rows hold the original values
+type compressedBytesScanner struct {
+	compression Compression
+}
+
+// Value returns the compressed value
+func (s compressedBytesScanner) Value(v []byte) (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return compressValue(s.compression, v)
+}
+
+// ScanValue returns the scanner of the compressed value
+func (compressedBytesScanner) ScanValue() field.ValueScanner {
+	return &compressedValue{}
+}
+
+// FromValue returns the decompressed value
+func (compressedBytesScanner) FromValue(v driver.Value) ([]byte, error) {
+	b, err := scannedBytes(v)
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	return decompressValue(b)
+}
+
+// compressedValue is the scanned value of the compressed fields
+type compressedValue struct {
+	value []byte
+}
+
+// Scan copies the scanned value
+func (v *compressedValue) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		v.value = nil
+	case []byte:
+		v.value = bytes.Clone(src)
+	case string:
+		v.value = []byte(src)
+	default:
+		return fmt.Errorf("%w: unexpected compressed value %T", ErrUnsupportedType, src)
+	}
+
+	return nil
+}
+
+// Value returns the scanned value
+func (v *compressedValue) Value() (driver.Value, error) {
+	if v.value == nil {
+		return nil, nil
+	}
+
+	return v.value, nil
+}
+
+// scannedBytes returns the bytes scanned by the ScanValue of the compressed scanners
+func scannedBytes(v driver.Value) ([]byte, error) {
+	scanned, ok := v.(*compressedValue)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected scanned value %T", ErrUnsupportedType, v)
+	}
+
+	return scanned.value, nil
+}
+
+// compressedSchemaType are the binary column types of the compressed string fields
+var compressedSchemaType = map[string]string{
+	dialect.Postgres: "bytea",
+	dialect.MySQL:    "longblob",
+	dialect.SQLite:   "blob",
+}
+
+// compressField sets the ValueScanner compressing the values of the string or bytes field descriptor,
+// string fields are stored in binary columns because the compressed values are not valid text
+func compressField(desc *field.Descriptor, compression Compression) {
+	if compression == "" {
+		compression = CompressionGzip
+	}
+
+	switch desc.Info.Type {
+	case field.TypeString:
+		desc.ValueScanner = compressedStringScanner{compression: compression}
+		desc.SchemaType = compressedSchemaType
+	case field.TypeBytes:
+		desc.ValueScanner = compressedBytesScanner{compression: compression}
+	}
+}
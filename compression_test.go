@@ -0,0 +1,113 @@
+package enthistory
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/schema/field"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseCompressor is a test compressor reversing the values
+type reverseCompressor struct{}
+
+func (reverseCompressor) Compress(value []byte) ([]byte, error) {
+	out := bytes.Clone(value)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return out, nil
+}
+
+func (c reverseCompressor) Decompress(value []byte) ([]byte, error) {
+	return c.Compress(value)
+}
+
+func TestCompressValue(t *testing.T) {
+	value := []byte(strings.Repeat("history ", 100))
+
+	RegisterCompressor("reverse", reverseCompressor{})
+	t.Cleanup(func() {
+		compressorsMu.Lock()
+		delete(compressors, "reverse")
+		compressorsMu.Unlock()
+	})
+
+	for _, compression := range []Compression{CompressionGzip, CompressionZlib, "reverse"} {
+		t.Run(string(compression), func(t *testing.T) {
+			compressed, err := compressValue(compression, value)
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(compressed, append([]byte(compression), 0)))
+
+			got, err := decompressValue(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, value, got)
+		})
+	}
+
+	// values stored before the column was compressed are returned as is
+	got, err := decompressValue([]byte("plain value"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("plain value"), got)
+
+	_, err = compressValue(CompressionZstd, value)
+	assert.ErrorIs(t, err, ErrCompressorNotRegistered)
+
+	_, err = decompressValue(append([]byte("zstd\x00"), value...))
+	assert.ErrorIs(t, err, ErrCompressorNotRegistered)
+}
+
+func TestCompressedScanners(t *testing.T) {
+	value := strings.Repeat("history ", 100)
+
+	s := compressedStringScanner{compression: CompressionGzip}
+
+	stored, err := s.Value(value)
+	require.NoError(t, err)
+	assert.Less(t, len(stored.([]byte)), len(value))
+
+	scanned := s.ScanValue()
+	require.NoError(t, scanned.Scan(stored))
+
+	got, err := s.FromValue(scanned)
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	b := compressedBytesScanner{compression: CompressionZlib}
+
+	stored, err = b.Value(nil)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	scanned = b.ScanValue()
+	require.NoError(t, scanned.Scan(nil))
+
+	gotBytes, err := b.FromValue(scanned)
+	require.NoError(t, err)
+	assert.Nil(t, gotBytes)
+}
+
+func TestHistoryFieldsCompressed(t *testing.T) {
+	fields := HistoryFields([]ent.Field{
+		field.Text("body"),
+		field.Bytes("attachment"),
+		field.String("title"),
+	}, FieldConfig{
+		Compressed: map[string]Compression{"body": CompressionGzip, "attachment": ""},
+	})
+
+	body := fields[0].Descriptor()
+	assert.Equal(t, compressedStringScanner{compression: CompressionGzip}, body.ValueScanner)
+	assert.Equal(t, "bytea", body.SchemaType[dialect.Postgres])
+
+	attachment := fields[1].Descriptor()
+	assert.Equal(t, compressedBytesScanner{compression: CompressionGzip}, attachment.ValueScanner)
+	assert.Empty(t, attachment.SchemaType)
+
+	assert.Nil(t, fields[2].Descriptor().ValueScanner)
+}
@@ -0,0 +1,168 @@
+package enthistory
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ConsistencyIssueKind is the kind of invariant broken by the history rows of a record
+type ConsistencyIssueKind string
+
+const (
+	// IssueMissingCreate is a live record without a create (INSERT) history row
+	IssueMissingCreate ConsistencyIssueKind = "MISSING_CREATE"
+	// IssueUpdateAfterDelete is a history row, other than a create, recorded after the DELETE history row of the record
+	IssueUpdateAfterDelete ConsistencyIssueKind = "UPDATE_AFTER_DELETE"
+	// IssueHistoryTimeOutOfOrder is a history row with a history_time before the history row recorded before it
+	IssueHistoryTimeOutOfOrder ConsistencyIssueKind = "HISTORY_TIME_OUT_OF_ORDER"
+)
+
+// ConsistencyIssue is an invariant broken by the history rows of a record
+type ConsistencyIssue struct {
+	// Schema is the name of the tracked schema, e.g. Todo
+	Schema string
+	// Ref is the id of the record
+	Ref any
+	// HistoryID is the id of the history row breaking the invariant, nil for records missing a history row
+	HistoryID any
+	// Kind of invariant broken
+	Kind ConsistencyIssueKind
+}
+
+// String returns the issue in a readable form
+func (i ConsistencyIssue) String() string {
+	if i.HistoryID == nil {
+		return fmt.Sprintf("%s %v: %s", i.Schema, i.Ref, i.Kind)
+	}
+
+	return fmt.Sprintf("%s %v: %s (history %v)", i.Schema, i.Ref, i.Kind, i.HistoryID)
+}
+
+// ConsistencyReport is the report returned by the generated CheckHistoryConsistency
+type ConsistencyReport struct {
+	// Records is the number of live records checked by schema
+	Records map[string]int
+	// Rows is the number of history rows checked by schema
+	Rows map[string]int
+	// Issues are the invariants broken by the history rows
+	Issues []ConsistencyIssue
+
+	// current is the record of the history rows being checked
+	current refSequence
+}
+
+// refSequence is the history rows of a record, in the order they were recorded
+type refSequence struct {
+	schema string
+	ref    any
+	rows   []historyRow
+}
+
+// historyRow is a history row checked by the consistency report
+type historyRow struct {
+	id          any
+	op          OpType
+	historyTime time.Time
+	synthetic   bool
+}
+
+// NewConsistencyReport returns an empty consistency report
+func NewConsistencyReport() *ConsistencyReport {
+	return &ConsistencyReport{
+		Records: map[string]int{},
+		Rows:    map[string]int{},
+	}
+}
+
+// OK returns true when no invariant is broken
+func (r *ConsistencyReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// CheckRecord checks that the live record of the schema has a create history row
+func (r *ConsistencyReport) CheckRecord(schema string, ref any, hasCreate bool) {
+	r.Records[schema]++
+
+	if !hasCreate {
+		r.Issues = append(r.Issues, ConsistencyIssue{Schema: schema, Ref: ref, Kind: IssueMissingCreate})
+	}
+}
+
+// CheckRow adds the history row to the rows of its record, the rows must be added grouped by record and in the order
+// they were recorded; synthetic rows, inserted by the generated RepairHistory, are recorded after the rows they precede
+// so they are checked using their history_time instead. The rows of a record are checked once the rows of the next
+// record are added, or when calling Done
+func (r *ConsistencyReport) CheckRow(schema string, ref, historyID any, op OpType, historyTime time.Time, synthetic bool) {
+	r.Rows[schema]++
+
+	if r.current.schema != schema || r.current.ref != ref {
+		r.Done()
+
+		r.current = refSequence{schema: schema, ref: ref}
+	}
+
+	r.current.rows = append(r.current.rows, historyRow{id: historyID, op: op, historyTime: historyTime, synthetic: synthetic})
+}
+
+// Done checks the history rows of the record added last using CheckRow
+func (r *ConsistencyReport) Done() {
+	seq := r.current
+	r.current = refSequence{}
+
+	if len(seq.rows) == 0 {
+		return
+	}
+
+	// the history_time of the rows increases in the order they were recorded, synthetic rows are left out
+	var last time.Time
+
+	for _, row := range seq.rows {
+		if row.synthetic {
+			continue
+		}
+
+		if row.historyTime.Before(last) {
+			r.Issues = append(r.Issues, ConsistencyIssue{Schema: seq.schema, Ref: seq.ref, HistoryID: row.id, Kind: IssueHistoryTimeOutOfOrder})
+
+			continue
+		}
+
+		last = row.historyTime
+	}
+
+	// a deleted record can only be created again (e.g. restored), any other operation follows a missing create;
+	// synthetic rows are checked before the first row recorded at, or after, their history_time
+	rows := make([]historyRow, 0, len(seq.rows))
+
+	for _, row := range seq.rows {
+		if !row.synthetic {
+			rows = append(rows, row)
+		}
+	}
+
+	for _, row := range seq.rows {
+		if !row.synthetic {
+			continue
+		}
+
+		i := slices.IndexFunc(rows, func(other historyRow) bool {
+			return !other.synthetic && !other.historyTime.Before(row.historyTime)
+		})
+		if i < 0 {
+			i = len(rows)
+		}
+
+		rows = slices.Insert(rows, i, row)
+	}
+
+	deleted := false
+
+	for _, row := range rows {
+		if deleted && row.op != OpTypeInsert {
+			r.Issues = append(r.Issues, ConsistencyIssue{Schema: seq.schema, Ref: seq.ref, HistoryID: row.id, Kind: IssueUpdateAfterDelete})
+		}
+
+		deleted = row.op == OpTypeDelete
+	}
+}
@@ -0,0 +1,63 @@
+package enthistory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsistencyReport(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	report := NewConsistencyReport()
+	assert.True(t, report.OK())
+
+	report.CheckRecord("Todo", 1, true)
+	report.CheckRecord("Todo", 2, false)
+
+	// ref 1 is created, updated, deleted, and restored
+	report.CheckRow("Todo", 1, 1, OpTypeInsert, start, false)
+	report.CheckRow("Todo", 1, 2, OpTypeUpdate, start.Add(time.Minute), false)
+	report.CheckRow("Todo", 1, 3, OpTypeDelete, start.Add(2*time.Minute), false)
+	report.CheckRow("Todo", 1, 4, OpTypeInsert, start.Add(3*time.Minute), false)
+
+	// ref 3 is updated after it was deleted, and has a history row recorded out of order
+	report.CheckRow("Todo", 3, 5, OpTypeInsert, start, false)
+	report.CheckRow("Todo", 3, 6, OpTypeDelete, start.Add(time.Minute), false)
+	report.CheckRow("Todo", 3, 7, OpTypeUpdate, start.Add(2*time.Minute), false)
+	report.CheckRow("Todo", 3, 8, OpTypeUpdate, start.Add(time.Second), false)
+
+	// the same ref of another schema starts a new sequence
+	report.CheckRow("Note", 3, 1, OpTypeUpdate, start, false)
+	report.Done()
+
+	assert.False(t, report.OK())
+	assert.Equal(t, map[string]int{"Todo": 2}, report.Records)
+	assert.Equal(t, map[string]int{"Todo": 8, "Note": 1}, report.Rows)
+	assert.Equal(t, []ConsistencyIssue{
+		{Schema: "Todo", Ref: 2, Kind: IssueMissingCreate},
+		{Schema: "Todo", Ref: 3, HistoryID: 8, Kind: IssueHistoryTimeOutOfOrder},
+		{Schema: "Todo", Ref: 3, HistoryID: 7, Kind: IssueUpdateAfterDelete},
+	}, report.Issues)
+
+	assert.Equal(t, "Todo 2: MISSING_CREATE", report.Issues[0].String())
+	assert.Equal(t, "Todo 3: UPDATE_AFTER_DELETE (history 7)", report.Issues[2].String())
+}
+
+func TestConsistencyReportSyntheticRows(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	report := NewConsistencyReport()
+
+	// the synthetic create, inserted after the update following the delete, is checked before it
+	report.CheckRow("Todo", 1, 1, OpTypeInsert, start, false)
+	report.CheckRow("Todo", 1, 2, OpTypeDelete, start.Add(time.Minute), false)
+	report.CheckRow("Todo", 1, 3, OpTypeUpdate, start.Add(2*time.Minute), false)
+	report.CheckRow("Todo", 1, 4, OpTypeUpdate, start.Add(3*time.Minute), false)
+	report.CheckRow("Todo", 1, 5, OpTypeInsert, start.Add(2*time.Minute), true)
+	report.Done()
+
+	assert.True(t, report.OK())
+	assert.Equal(t, map[string]int{"Todo": 5}, report.Rows)
+}
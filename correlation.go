@@ -0,0 +1,22 @@
+package enthistory
+
+import (
+	"context"
+)
+
+// correlationIDKey is the context key for the correlation id
+type correlationIDKey struct{}
+
+// NewCorrelationContext returns a copy of the context with the correlation id, history rows created
+// with this context are stamped with the id when using WithCorrelationID, so changes committed
+// together (e.g. deleting a record along with its children) can be found later
+func NewCorrelationContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation id from the context, if it was set
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+
+	return id, ok && id != ""
+}
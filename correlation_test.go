@@ -0,0 +1,43 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelationIDFromContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "happy path",
+			ctx:    NewCorrelationContext(context.Background(), "01HXYZ"),
+			want:   "01HXYZ",
+			wantOk: true,
+		},
+		{
+			name:   "empty id",
+			ctx:    NewCorrelationContext(context.Background(), ""),
+			want:   "",
+			wantOk: false,
+		},
+		{
+			name:   "not set",
+			ctx:    context.Background(),
+			want:   "",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CorrelationIDFromContext(tt.ctx)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package enthistory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultPageSize is the number of history rows returned by PageHistory when the limit is not positive
+	DefaultPageSize = 100
+)
+
+// cursor is the position of a history row in the pages of the history of a record, ordered by history time and id
+type cursor struct {
+	// HistoryTime is the history time of the row
+	HistoryTime time.Time `json:"t"`
+	// ID is the id of the row
+	ID json.RawMessage `json:"id"`
+}
+
+// EncodeCursor returns the opaque cursor of the history row with the history time and id, the cursor is URL safe
+// so it can be returned to API clients as is; this is used by the generated PageHistory methods of the history clients
+func EncodeCursor(historyTime time.Time, id any) (string, error) {
+	b, err := json.Marshal(id)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(cursor{HistoryTime: historyTime, ID: b})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(c), nil
+}
+
+// DecodeCursor decodes the cursor returned by EncodeCursor into the id, which must be a pointer to the id type of
+// the history rows, and returns the history time of the cursor
+func DecodeCursor(value string, id any) (time.Time, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if err := json.Unmarshal(c.ID, id); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return c.HistoryTime, nil
+}
@@ -0,0 +1,42 @@
+package enthistory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	historyTime := time.Date(2024, 6, 1, 12, 30, 0, 123456000, time.UTC)
+
+	cursor, err := EncodeCursor(historyTime, 42)
+	require.NoError(t, err)
+	assert.NotContains(t, cursor, "=")
+
+	var id int
+
+	got, err := DecodeCursor(cursor, &id)
+	require.NoError(t, err)
+	assert.True(t, historyTime.Equal(got))
+	assert.Equal(t, 42, id)
+
+	cursor, err = EncodeCursor(historyTime, "01HZX")
+	require.NoError(t, err)
+
+	var stringID string
+
+	_, err = DecodeCursor(cursor, &stringID)
+	require.NoError(t, err)
+	assert.Equal(t, "01HZX", stringID)
+
+	// the id of the cursor does not match the id type
+	_, err = DecodeCursor(cursor, &id)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	for _, invalid := range []string{"not a cursor!", "bm90IGpzb24"} {
+		_, err = DecodeCursor(invalid, &id)
+		require.ErrorIs(t, err, ErrInvalidCursor)
+	}
+}
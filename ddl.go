@@ -0,0 +1,206 @@
+package enthistory
+
+import (
+	"context"
+	stdsql "database/sql"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"ariga.io/atlas/sql/migrate"
+	"ariga.io/atlas/sql/mysql"
+	"ariga.io/atlas/sql/postgres"
+	atlas "ariga.io/atlas/sql/schema"
+	"ariga.io/atlas/sql/sqlite"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/schema"
+	"entgo.io/ent/entc"
+	"entgo.io/ent/entc/gen"
+)
+
+// ddlPlanners are the Atlas planners of the dialects, planning the DDL of the changes without a database connection
+var ddlPlanners = map[string]migrate.PlanApplier{
+	dialect.Postgres: postgres.DefaultPlan,
+	dialect.MySQL:    mysql.DefaultPlan,
+	dialect.SQLite:   sqlite.DefaultPlan,
+}
+
+// GenerateDDL writes the CREATE TABLE statements of all history tables in the schema path, and the CREATE INDEX
+// statements of their indexes, for the dialect; this is meant for migrations managed outside of ent and Atlas, the
+// history schemas must be generated by GenerateSchemas beforehand
+func (h *HistoryExtension) GenerateDDL(w io.Writer, d string) error {
+	planner, ok := ddlPlanners[d]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedDialect, d)
+	}
+
+	graph, err := entc.LoadGraph(h.config.SchemaPath, &gen.Config{})
+	if err != nil {
+		return fmt.Errorf("%w: failed loading ent graph: %v", ErrFailedToGenerateTemplate, err)
+	}
+
+	historyTables := make(map[string]bool)
+
+	for _, n := range graph.Nodes {
+		annotations, err := jsonUnmarshalAnnotations(n.Annotations[annotationName])
+		if err == nil && annotations.IsHistory {
+			historyTables[n.Table()] = true
+		}
+	}
+
+	tables, err := graph.Tables()
+	if err != nil {
+		return fmt.Errorf("%w: failed loading ent tables: %v", ErrFailedToGenerateTemplate, err)
+	}
+
+	tables = slices.DeleteFunc(tables, func(t *schema.Table) bool { return t.View || !historyTables[t.Name] })
+	slices.SortFunc(tables, func(a, b *schema.Table) int { return strings.Compare(a.Name, b.Name) })
+
+	stmts, err := planTablesDDL(context.Background(), d, planner, tables)
+	if err != nil {
+		return fmt.Errorf("%w: failed planning the history tables: %v", ErrFailedToGenerateTemplate, err)
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- Code generated by enthistory, the history tables (%s)\n", d)
+
+	for _, stmt := range stmts {
+		fmt.Fprintf(&b, "%s;\n", stmt)
+	}
+
+	_, err = io.WriteString(w, b.String())
+
+	return err
+}
+
+// planTablesDDL returns the statements creating the tables for the dialect, the tables are converted to Atlas tables
+// the same way as the ent migrations (e.g. with the column defaults, identities, and index methods) and planned
+// offline, quoting the identifiers; the tables are prefixed with their schema, if any, on dialects supporting schemas
+func planTablesDDL(ctx context.Context, d string, planner migrate.PlanApplier, tables []*schema.Table) (
+	[]string, error,
+) {
+	m, err := schema.NewMigrate(ddlDriver{dialect: d})
+	if err != nil {
+		return nil, err
+	}
+
+	realm, err := m.StateReader(tables...).ReadState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]atlas.Change, 0, len(tables))
+
+	for i, t := range realm.Schemas[0].Tables {
+		if s := tables[i].Schema; s != "" && d != dialect.SQLite {
+			t.Schema = atlas.New(s)
+		}
+
+		changes = append(changes, &atlas.AddTable{T: t})
+	}
+
+	plan, err := planner.PlanChanges(ctx, "history", changes)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts := make([]string, len(plan.Changes))
+	for i, c := range plan.Changes {
+		stmts[i] = c.Cmd
+	}
+
+	return stmts, nil
+}
+
+// ddlDriver is the driver of the offline planning, it answers the queries ent runs before converting the tables to
+// Atlas tables (e.g. the server version) and fails any other statement
+type ddlDriver struct {
+	dialect string
+}
+
+// ddlVersions are the rows answering the version queries of the dialects, the DDL targets Postgres 15 and MySQL 8,
+// and SQLite with the foreign keys enabled
+var ddlVersions = map[string][]any{
+	dialect.Postgres: {"150000"},
+	dialect.MySQL:    {"version", "8.0.36"},
+	dialect.SQLite:   {int64(1)},
+}
+
+// Query answers the version query of the dialect
+func (d ddlDriver) Query(_ context.Context, query string, _, v any) error {
+	rows, ok := v.(*sql.Rows)
+	if !ok {
+		return fmt.Errorf("%w: unexpected query %q", ErrUnsupportedDialect, query)
+	}
+
+	*rows = sql.Rows{ColumnScanner: &ddlRows{values: ddlVersions[d.dialect]}}
+
+	return nil
+}
+
+// Exec fails, the offline planning does not run statements
+func (d ddlDriver) Exec(_ context.Context, query string, _, _ any) error {
+	return fmt.Errorf("%w: unexpected statement %q", ErrUnsupportedDialect, query)
+}
+
+// Tx fails, the offline planning does not run transactions
+func (d ddlDriver) Tx(context.Context) (dialect.Tx, error) {
+	return nil, fmt.Errorf("%w: unexpected transaction", ErrUnsupportedDialect)
+}
+
+// Close is a no-op
+func (d ddlDriver) Close() error { return nil }
+
+// Dialect returns the dialect of the DDL
+func (d ddlDriver) Dialect() string { return d.dialect }
+
+// ddlRows is the single row answering a version query of the ddlDriver
+type ddlRows struct {
+	values []any
+	read   bool
+}
+
+// Close is a no-op
+func (r *ddlRows) Close() error { return nil }
+
+// ColumnTypes returns no column types, the version rows are scanned by position
+func (r *ddlRows) ColumnTypes() ([]*stdsql.ColumnType, error) { return nil, nil }
+
+// Columns returns a column per value of the row
+func (r *ddlRows) Columns() ([]string, error) { return make([]string, len(r.values)), nil }
+
+// Err returns no error
+func (r *ddlRows) Err() error { return nil }
+
+// Next returns true once, for the single row
+func (r *ddlRows) Next() bool {
+	next := !r.read
+	r.read = true
+
+	return next
+}
+
+// NextResultSet returns false, there is a single result set
+func (r *ddlRows) NextResultSet() bool { return false }
+
+// Scan copies the values of the row to the destinations
+func (r *ddlRows) Scan(dest ...any) error {
+	if len(dest) != len(r.values) {
+		return fmt.Errorf("%w: expected %d destinations, got %d", ErrUnsupportedType, len(r.values), len(dest))
+	}
+
+	for i, v := range r.values {
+		rv := reflect.ValueOf(dest[i])
+		if rv.Kind() != reflect.Pointer || !reflect.TypeOf(v).AssignableTo(rv.Elem().Type()) {
+			return fmt.Errorf("%w: cannot scan %T into %T", ErrUnsupportedType, v, dest[i])
+		}
+
+		rv.Elem().Set(reflect.ValueOf(v))
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package enthistory
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/dialect/sql/schema"
+	"entgo.io/ent/schema/field"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDDL(t *testing.T) {
+	h := New(WithSchemaPath("./testdata/schema"))
+
+	var b strings.Builder
+
+	require.ErrorIs(t, h.GenerateDDL(&b, "oracle"), ErrUnsupportedDialect)
+	assert.Empty(t, b.String())
+
+	for _, d := range auditSummaryDialects {
+		b.Reset()
+		require.NoError(t, h.GenerateDDL(&b, d))
+
+		ddl := b.String()
+		q := func(s string) string { return strings.ReplaceAll(s, `"`, "`") }
+		if d == dialect.Postgres {
+			q = func(s string) string { return s }
+		}
+
+		assert.Contains(t, ddl, q(`CREATE TABLE "user_histories" (`))
+		// only the history tables are written
+		assert.NotContains(t, ddl, q(`CREATE TABLE "users" (`))
+		assert.NotContains(t, ddl, q(`CREATE TABLE "todos" (`))
+	}
+
+	b.Reset()
+	require.NoError(t, h.GenerateDDL(&b, dialect.Postgres))
+	assert.Contains(t, b.String(), `"id" bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY`)
+	assert.Contains(t, b.String(), `CREATE UNIQUE INDEX "userhistory_age_name" ON "user_histories" ("age", "name");`)
+
+	b.Reset()
+	require.NoError(t, h.GenerateDDL(&b, dialect.MySQL))
+	assert.Contains(t, b.String(), "`id` bigint NOT NULL AUTO_INCREMENT")
+	assert.Contains(t, b.String(), "UNIQUE INDEX `userhistory_age_name` (`age`, `name`)")
+	assert.Contains(t, b.String(), "PRIMARY KEY (`id`)")
+
+	// the SQLite statements are applied to make sure they are valid
+	b.Reset()
+	require.NoError(t, h.GenerateDDL(&b, dialect.SQLite))
+	assert.Contains(t, b.String(), "`id` integer NOT NULL PRIMARY KEY AUTOINCREMENT")
+	assert.NotContains(t, b.String(), "PRIMARY KEY (`id`)")
+
+	db, err := sql.Open("sqlite3", "file:ddl?mode=memory&cache=shared")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { db.Close() })
+
+	for _, stmt := range strings.Split(b.String(), ";\n") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			_, err := db.Exec(stmt)
+			require.NoError(t, err, stmt)
+		}
+	}
+}
+
+func TestPlanTablesDDL(t *testing.T) {
+	id := &schema.Column{Name: "id", Type: field.TypeInt, Increment: true}
+	table := schema.NewTable("order_history").
+		SetSchema("audit").
+		AddPrimary(id).
+		AddColumn(&schema.Column{Name: "user", Type: field.TypeString}).
+		AddColumn(&schema.Column{Name: "synthetic", Type: field.TypeBool, Default: false}).
+		AddColumn(&schema.Column{Name: "history_time", Type: field.TypeTime, SchemaType: map[string]string{
+			dialect.Postgres: "timestamp(3) with time zone",
+		}})
+	table.AddIndex("orderhistory_history_time", false, []string{"history_time"})
+	table.Indexes[0].Annotation = entsql.IndexTypes(map[string]string{dialect.Postgres: "BRIN"})
+
+	ctx := context.Background()
+
+	stmts, err := planTablesDDL(ctx, dialect.Postgres, ddlPlanners[dialect.Postgres], []*schema.Table{table})
+	require.NoError(t, err)
+
+	ddl := strings.Join(stmts, ";\n")
+	assert.Contains(t, ddl, `CREATE TABLE "audit"."order_history" (`)
+	assert.Contains(t, ddl, `"user" character varying NOT NULL`)
+	assert.Contains(t, ddl, `"synthetic" boolean NOT NULL DEFAULT false`)
+	assert.Contains(t, ddl, `"history_time" timestamptz(3) NOT NULL`)
+	assert.Contains(t, ddl, `ON "audit"."order_history" USING BRIN ("history_time")`)
+
+	stmts, err = planTablesDDL(ctx, dialect.MySQL, ddlPlanners[dialect.MySQL], []*schema.Table{table})
+	require.NoError(t, err)
+
+	ddl = strings.Join(stmts, ";\n")
+	assert.Contains(t, ddl, "CREATE TABLE `audit`.`order_history` (")
+	assert.Contains(t, ddl, "`synthetic` bool NOT NULL DEFAULT false")
+	assert.NotContains(t, ddl, "BRIN")
+
+	// the SQLite table is not prefixed with the schema, and the rows are inserted with the defaults of the columns
+	stmts, err = planTablesDDL(ctx, dialect.SQLite, ddlPlanners[dialect.SQLite], []*schema.Table{table})
+	require.NoError(t, err)
+
+	db, err := sql.Open("sqlite3", "file:plan?mode=memory&cache=shared")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { db.Close() })
+
+	for _, stmt := range stmts {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	_, err = db.Exec("INSERT INTO `order_history` (`user`, `history_time`) VALUES ('a', CURRENT_TIMESTAMP)")
+	require.NoError(t, err)
+
+	var synthetic bool
+
+	require.NoError(t, db.QueryRow("SELECT `synthetic` FROM `order_history`").Scan(&synthetic))
+	assert.False(t, synthetic)
+}
@@ -0,0 +1,45 @@
+package enthistory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"entgo.io/ent"
+)
+
+// Enricher is called with the mutation of each history row before it is inserted, it can set the fields of the history
+// row on the mutation (e.g. classification labels) or veto the history row by returning an error, which fails the
+// mutation of the tracked record
+type Enricher func(ctx context.Context, m ent.Mutation) error
+
+var (
+	// enrichers are the enrichers registered using RegisterEnricher
+	enrichers []Enricher
+	// enrichersMu guards the registered enrichers
+	enrichersMu sync.RWMutex
+)
+
+// RegisterEnricher registers enrichers called with the history rows of all schemas before they are inserted, in the
+// order they were registered; this is usually called when the application starts
+func RegisterEnricher(e ...Enricher) {
+	enrichersMu.Lock()
+	defer enrichersMu.Unlock()
+
+	enrichers = append(enrichers, e...)
+}
+
+// Enrich calls the registered enrichers with the mutation of the history row, the error of the first enricher vetoing
+// the history row is returned wrapped with ErrHistoryVetoed; this is used by the generated CreateHistoryFrom methods
+func Enrich(ctx context.Context, m ent.Mutation) error {
+	enrichersMu.RLock()
+	defer enrichersMu.RUnlock()
+
+	for _, enrich := range enrichers {
+		if err := enrich(ctx, m); err != nil {
+			return fmt.Errorf("%w: %w", ErrHistoryVetoed, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrich(t *testing.T) {
+	t.Cleanup(func() {
+		enrichers = nil
+	})
+
+	m := &testSinkMutation{fields: map[string]ent.Value{}}
+
+	require.NoError(t, Enrich(context.Background(), m))
+
+	errNoReason := errors.New("change reason is required")
+	calls := []string{}
+
+	RegisterEnricher(
+		func(_ context.Context, m ent.Mutation) error {
+			calls = append(calls, "label")
+			m.(*testSinkMutation).fields["label"] = "pii"
+
+			return nil
+		},
+		func(_ context.Context, m ent.Mutation) error {
+			calls = append(calls, "reason")
+
+			if _, ok := m.Field("reason"); !ok {
+				return errNoReason
+			}
+
+			return nil
+		},
+	)
+
+	err := Enrich(context.Background(), m)
+	require.ErrorIs(t, err, ErrHistoryVetoed)
+	require.ErrorIs(t, err, errNoReason)
+	assert.Equal(t, []string{"label", "reason"}, calls)
+	assert.Equal(t, "pii", m.fields["label"])
+
+	m.fields["reason"] = "ticket-1"
+
+	require.NoError(t, Enrich(context.Background(), m))
+}
@@ -1,12 +1,35 @@
 package enthistory
 
 import (
+	"io/fs"
+	"sync"
+	"time"
+
 	"entgo.io/ent/entc"
 	"entgo.io/ent/entc/gen"
 )
 
 type ExtensionOption = func(*HistoryExtension)
 
+const (
+	// defaultSoftDeleteField is the default name of the field used to soft delete records
+	defaultSoftDeleteField = "deleted_at"
+	// tenantFieldName is the name of the field holding the tenant of the history rows
+	tenantFieldName = "tenant_id"
+	// historyTimeFieldName is the name of the field holding the time of the history rows
+	historyTimeFieldName = "history_time"
+	// defaultOwnerFieldName is the name of the mixed in field holding the owner of a schema, the object type
+	// of the owner is determined by the comment of the field
+	defaultOwnerFieldName = "owner_id"
+)
+
+const (
+	// OwnerObjectTypeOrganization is the object type of schemas owned by an organization
+	OwnerObjectTypeOrganization = "organization"
+	// OwnerObjectTypeUser is the object type of schemas owned by a user
+	OwnerObjectTypeUser = "user"
+)
+
 // UpdatedBy is a struct that holds the key and type for the updated_by field
 type UpdatedBy struct {
 	key       string
@@ -14,6 +37,30 @@ type UpdatedBy struct {
 	Nillable  bool
 }
 
+// DeletedBy is a struct that holds the key and type for the deleted_by field
+type DeletedBy struct {
+	key       string
+	valueType ValueType
+}
+
+// PolicyTemplate is a struct that holds the file system and path of the template replacing the policy
+// of the history schemas
+type PolicyTemplate struct {
+	fsys fs.FS
+	path string
+}
+
+// AdditionalField is a field added to every history schema, which is set from the value of the key on the context
+// when the history rows are created
+type AdditionalField struct {
+	// Name is the name of the field on the history schemas (e.g. region)
+	Name string
+	// ValueType is the type of the field, and of the value on the context
+	ValueType ValueType
+	// Key is the context key of the value of the field
+	Key string
+}
+
 // FieldProperties is a struct that holds the properties for the fields in the history schema
 type FieldProperties struct {
 	Nillable  bool
@@ -22,15 +69,123 @@ type FieldProperties struct {
 
 // Config is the configuration for the history extension
 type Config struct {
-	IncludeUpdatedBy bool
-	UpdatedBy        *UpdatedBy
-	Auditing         bool
-	SchemaPath       string
-	SchemaName       string
-	Query            bool
-	Skipper          string
-	FieldProperties  *FieldProperties
-	HistoryTimeIndex bool
+	IncludeUpdatedBy    bool
+	UpdatedBy           *UpdatedBy
+	DeletedBy           *DeletedBy
+	Auditing            bool
+	SchemaPath          string
+	SchemaName          string
+	Query               bool
+	Skipper             string
+	FieldProperties     *FieldProperties
+	HistoryTimeIndex    bool
+	RefHistoryTimeIndex bool
+	UpdatedByIndex      bool
+	// HistoryTimeIndexConfig is the configuration of the history_time index added when using WithHistoryTimeIndex
+	HistoryTimeIndexConfig IndexConfig
+	// HistoryTimePrecision is the fractional seconds precision of the history_time column (e.g. 3 for milliseconds,
+	// 6 for microseconds), when not set the default precision of the database is used
+	HistoryTimePrecision int
+	// Dialect is the database dialect (dialect.Postgres, dialect.MySQL, or dialect.SQLite) the history schemas are
+	// generated for, when empty the history schemas, and the DDL written by the generator, support all dialects
+	Dialect string
+	// HistoryTimeWithoutTimeZone stores history_time without the time zone (timestamp on postgres, datetime on mysql)
+	HistoryTimeWithoutTimeZone bool
+	// AllowedFieldAnnotations are the names of the field annotations that are copied to the
+	// history schema, when set all other field annotations are removed
+	AllowedFieldAnnotations []string
+	// StrippedFieldAnnotations are the names of the field annotations that are removed
+	// from the fields copied to the history schema
+	StrippedFieldAnnotations []string
+	// FieldDefaults keeps the defaults of the fields copied to the history schema
+	FieldDefaults bool
+	// FieldValidators keeps the validators of the fields copied to the history schema
+	FieldValidators bool
+	// SoftDeleteField is the name of the field used to soft delete records, defaults to deleted_at
+	SoftDeleteField string
+	// CorrelationID adds the correlation_id field to the history schemas, set from the context
+	CorrelationID bool
+	// OwnerReadRules adds the privacy rules filtering the history queries of owned schemas to the owners of the viewer
+	OwnerReadRules bool
+	// AuthzDecision adds the authz_subject and authz_relation fields to the history schemas, set from the context
+	AuthzDecision bool
+	// GQLFederation adds the Apollo Federation key and shareable directives to the GraphQL types of the history schemas
+	GQLFederation bool
+	// Comments adds the database comments of the history tables and their columns
+	Comments bool
+	// SchemaVersion adds the schema_version field to the history schemas, the version of the fields of the original
+	// schema which is bumped by the generator when the fields change
+	SchemaVersion bool
+	// LegacyFields keeps the fields removed from the original schemas on the history schemas as nullable legacy fields
+	LegacyFields bool
+	// MigrationGuidanceDir is the directory the migration guidance for the history tables is written to when the
+	// fields of the original schemas change
+	MigrationGuidanceDir string
+	// HistoryMeta adds the history_meta schema recording the tracked schemas, their history tables, and schema versions
+	HistoryMeta bool
+	// LatestHistoryViews adds a view schema for each history schema exposing the latest history row of each ref
+	LatestHistoryViews bool
+	// LatestHistoryViewsDir is the directory the DDL of the latest history views is written to, if any
+	LatestHistoryViewsDir string
+	// AuditSummary adds the audit_summary view schema aggregating the changes per entity, actor, and day, and
+	// generates RefreshAuditSummary on the client
+	AuditSummary bool
+	// AuditSummaryDir is the directory the DDL of the audit summary is written to, if any
+	AuditSummaryDir string
+	// TriggersDir is the directory the DDL of the triggers capturing the out-of-band writes to the tables of the tracked
+	// schemas is written to, if any
+	TriggersDir string
+	// TriggersAppUser is the database user of the application, whose writes are recorded by the history hooks instead of
+	// the triggers
+	TriggersAppUser string
+	// AuditMasking masks the values of the sensitive fields, and of the fields annotated using Redact, in the changes
+	// of the audit log and the history diffs
+	AuditMasking bool
+	// Audit is the configuration of the audit log generated when using WithAuditing
+	Audit AuditConfig
+	// RestoredFrom adds the restored_from field to the history schemas, set when restoring a history row
+	RestoredFrom bool
+	// UpdateDebounce merges the updates of a record recorded within the window of its latest update history row into it
+	UpdateDebounce time.Duration
+	// HistoryRepair adds the synthetic field to the history schemas, and generates RepairHistory on the client
+	HistoryRepair bool
+	// UpsertTracking reads back the values of created records, and records creates that
+	// resolved to an update (e.g. upserts using OnConflict) as updates
+	UpsertTracking bool
+	// TenantKey is the context key of the tenant (e.g. organization id) that is recorded in the tenant_id
+	// field of the history schemas
+	TenantKey string
+	// AdditionalFields are the fields added to every history schema, set from the values on the context
+	AdditionalFields []AdditionalField
+	// HistoryPolicy adds a privacy policy to the history schemas that denies mutations not created by the history hooks
+	HistoryPolicy bool
+	// PolicyTemplate is the template of the policy of the history schemas, replacing the generated policy
+	PolicyTemplate *PolicyTemplate
+	// InheritedPolicy evaluates the history queries of the history schemas using the policy of the original schema
+	InheritedPolicy bool
+	// EdgeHistory adds history schemas for the join tables of many-to-many edges without an edge schema,
+	// recording the edges that are added and removed
+	EdgeHistory bool
+	// Operations are the custom operations, in addition to the built-in operations, that can be
+	// recorded on history rows
+	Operations []OpType
+	// PostUpdateCapture captures the update history after the mutation in the generated history hook registrations
+	PostUpdateCapture bool
+	// OptIn only generates history schemas for the schemas marked for tracking by the history annotation or Mixin
+	OptIn bool
+	// AutoHooks registers the history hooks of the tracked schemas on every client created with NewClient
+	AutoHooks bool
+	// DefaultOrder generates the interceptors of the history types ordering history queries by history_time,
+	// newest first, unless the query sets its own order
+	DefaultOrder bool
+	// Sink adds a hook to the history schemas writing the history rows to the secondary sink set using SetSink
+	Sink bool
+	// Callbacks adds a hook to the history schemas calling the callbacks registered using RegisterCallback
+	Callbacks bool
+	// TestHarness generates the historytest package with a test of the history of each tracked schema
+	TestHarness bool
+	// AttemptedChanges adds the history_attempt schema recording the mutations of the tracked schemas that fail
+	AttemptedChanges bool
 	Auth             AuthzSettings
 }
 
@@ -38,10 +193,17 @@ type AuthzSettings struct {
 	// Enabled is a boolean that tells the extension to generate the authz policy
 	Enabled bool
 	// FirstRun is a boolean that tells the extension to only generate the policies after the first run
+	//
+	// Deprecated: the policies are generated once the history schemas exist from a previous run, setting
+	// this is only needed to leave out the policies of existing history schemas
 	FirstRun bool
 	// AllowedRelation is the name of the relation that should be used to restrict
 	// all audit log queries to users with that role, if not set the interceptor will not be added
 	AllowedRelation string
+	// OwnerFields maps the names of the fields holding the owner of a schema (e.g. org_id, account_id) to the
+	// object type of the owner (organization or user), when none of these fields exist on a schema the
+	// owner is determined by the comment of the mixed in owner_id field
+	OwnerFields map[string]string
 }
 
 // Name of the Config
@@ -53,6 +215,9 @@ func (c Config) Name() string {
 type HistoryExtension struct {
 	entc.DefaultExtension
 	config *Config
+	// generated are the history schemas written by GenerateSchemas
+	generated   []SchemaInfo
+	generatedMu sync.Mutex
 }
 
 // New creates a new history extension
@@ -63,6 +228,7 @@ func New(opts ...ExtensionOption) *HistoryExtension {
 			SchemaPath:      "./schema",
 			Auditing:        false,
 			FieldProperties: &FieldProperties{},
+			SoftDeleteField: defaultSoftDeleteField,
 		},
 	}
 
@@ -73,6 +239,22 @@ func New(opts ...ExtensionOption) *HistoryExtension {
 	return extension
 }
 
+// Extension returns an entc.Option generating the history schemas and adding the history extension to the code
+// generation, replacing the separate GenerateSchemas call; entc.Generate applies the option before loading the
+// schemas, so the history schemas exist at the time of code generation. The schema path set using WithSchemaPath must
+// be the path passed to entc.Generate
+func Extension(opts ...ExtensionOption) entc.Option {
+	return func(cfg *gen.Config) error {
+		h := New(opts...)
+
+		if err := h.GenerateSchemas(); err != nil {
+			return err
+		}
+
+		return entc.Extensions(h)(cfg)
+	}
+}
+
 // Templates returns the generated templates which include the client, history query, history from mutation
 // and an optional auditing template
 func (h *HistoryExtension) Templates() []*gen.Template {
@@ -80,15 +262,71 @@ func (h *HistoryExtension) Templates() []*gen.Template {
 		parseTemplate("historyFromMutation", "templates/historyFromMutation.tmpl"),
 		parseTemplate("historyQuery", "templates/historyQuery.tmpl"),
 		parseTemplate("historyClient", "templates/historyClient.tmpl"),
+		parseTemplate("historyBackfill", "templates/historyBackfill.tmpl"),
+		parseTemplate("historyConsistency", "templates/historyConsistency.tmpl"),
+		parseTemplate("historyAsOf", "templates/historyAsOf.tmpl"),
+		parseTemplate("historyExport", "templates/historyExport.tmpl"),
+		parseTemplate("historyJSON", "templates/historyJSON.tmpl"),
 	}
 
 	if h.config.Auditing {
 		templates = append(templates, parseTemplate("auditing", "templates/auditing.tmpl"))
 	}
 
+	if h.config.Query {
+		templates = append(templates, parseTemplate("historyResolver", "templates/historyResolver.tmpl"))
+	}
+
+	if h.config.AutoHooks {
+		templates = append(templates, parseTemplate("historyRuntime", "templates/historyRuntime.tmpl"))
+	}
+
+	if h.config.HistoryMeta {
+		templates = append(templates, parseTemplate("historyMeta", "templates/historyMeta.tmpl"))
+	}
+
+	if h.config.HistoryRepair {
+		templates = append(templates, parseTemplate("historyRepair", "templates/historyRepair.tmpl"))
+	}
+
+	if h.config.AuditSummary {
+		templates = append(templates, parseTemplate("auditSummary", "templates/auditSummary.tmpl"))
+	}
+
+	if h.config.TenantKey != "" {
+		templates = append(templates, parseTemplate("historyTenant", "templates/historyTenant.tmpl"))
+	}
+
+	if h.config.DefaultOrder {
+		templates = append(templates, parseTemplate("historyOrder", "templates/historyOrder.tmpl"))
+	}
+
+	if h.config.TestHarness {
+		templates = append(templates, parseTemplate("historytest/historytest", "templates/historyTest.tmpl"))
+	}
+
+	if h.config.AttemptedChanges {
+		templates = append(templates, parseTemplate("historyAttempt", "templates/historyAttempt.tmpl"))
+	}
+
 	return templates
 }
 
+// Hooks of the HistoryExtension
+func (h *HistoryExtension) Hooks() []gen.Hook {
+	var hooks []gen.Hook
+
+	if h.config.LatestHistoryViews || h.config.AuditSummary {
+		hooks = append(hooks, viewLastNodeHook)
+	}
+
+	if h.config.Query && h.config.Auditing && !fieldPropertiesNillable(*h.config) {
+		hooks = append(hooks, restoreMutationsHook)
+	}
+
+	return hooks
+}
+
 // Annotations of the HistoryExtension
 func (h *HistoryExtension) Annotations() []entc.Annotation {
 	return []entc.Annotation{
@@ -97,14 +335,29 @@ func (h *HistoryExtension) Annotations() []entc.Annotation {
 }
 
 // SetFirstRun sets the first run value for the history extension outside of the options
+//
+// Deprecated: the policies are generated once the history schemas exist from a previous run
 func (h *HistoryExtension) SetFirstRun(firstRun bool) {
 	h.config.Auth.FirstRun = firstRun
 }
 
-// WithAuditing allows you to turn on the code generation for the `.Audit()` method
-func WithAuditing() ExtensionOption {
+// WithAuditing allows you to turn on the code generation for the `.Audit()` method, the options set the format the
+// audit log is written in, and the tables it includes
+func WithAuditing(opts ...AuditOption) ExtensionOption {
 	return func(h *HistoryExtension) {
 		h.config.Auditing = true
+
+		for _, opt := range opts {
+			opt(&h.config.Audit)
+		}
+	}
+}
+
+// WithAuditMasking masks the values of the sensitive fields, and of the fields annotated using Redact, in the changes
+// returned by the `.Audit()` and `.Diff()` methods, which then hold enthistory.RedactedValue in place of the values
+func WithAuditMasking() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.AuditMasking = true
 	}
 }
 
@@ -121,10 +374,118 @@ func WithGQLQuery() ExtensionOption {
 	}
 }
 
-// WithHistoryTimeIndex allows you to add an index to the "history_time" fields
-func WithHistoryTimeIndex() ExtensionOption {
+// WithGQLFederation adds the @key(fields: "id") and @shareable directives of Apollo Federation to the GraphQL types of
+// the history schemas, so the history can be resolved across subgraphs; this implies WithGQLQuery
+func WithGQLFederation() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.Query = true
+		h.config.GQLFederation = true
+	}
+}
+
+// IndexConfig is the configuration of an index added to the history schemas
+type IndexConfig struct {
+	// Type is the Postgres index method of the index (e.g. BRIN), when empty the default method (B-tree) is used
+	Type string
+	// Name is the name of the index, formatted with the name of the history table (e.g. "%s_history_time_brin"),
+	// when empty the name is generated by ent
+	Name string
+}
+
+// IndexOption is a functional option for the indexes added to the history schemas
+type IndexOption func(*IndexConfig)
+
+// WithIndexType sets the Postgres index method of the index (e.g. BRIN), the other dialects use their default method
+func WithIndexType(t string) IndexOption {
+	return func(c *IndexConfig) {
+		c.Type = t
+	}
+}
+
+// WithIndexName sets the name of the index, formatted with the name of the history table as index names must be
+// unique across the tables of a schema (e.g. "%s_history_time_brin")
+func WithIndexName(name string) IndexOption {
+	return func(c *IndexConfig) {
+		c.Name = name
+	}
+}
+
+// WithHistoryTimeIndex allows you to add an index to the "history_time" fields, the options set the type and name of
+// the index, e.g. WithIndexType("BRIN") for the append-only history tables on Postgres
+func WithHistoryTimeIndex(opts ...IndexOption) ExtensionOption {
 	return func(h *HistoryExtension) {
 		h.config.HistoryTimeIndex = true
+
+		for _, opt := range opts {
+			opt(&h.config.HistoryTimeIndexConfig)
+		}
+	}
+}
+
+// WithRefHistoryTimeIndex allows you to add a composite index to the "ref" and "history_time" fields
+// which is used when querying the history of a single record ordered by time
+func WithRefHistoryTimeIndex() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.RefHistoryTimeIndex = true
+	}
+}
+
+// WithHistoryTimePrecision sets the fractional seconds precision of the "history_time" fields, from 0 to 6
+// (e.g. 3 for milliseconds, 6 for microseconds), so rapid successive changes can be ordered by time;
+// this sets the column type on postgres and mysql
+func WithHistoryTimePrecision(precision int) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.HistoryTimePrecision = precision
+	}
+}
+
+// WithHistoryTimeWithoutTimeZone stores the "history_time" fields without the time zone, using timestamp on postgres
+// instead of timestamptz, and datetime on mysql instead of timestamp
+func WithHistoryTimeWithoutTimeZone() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.HistoryTimeWithoutTimeZone = true
+	}
+}
+
+// WithUpdatedByIndex allows you to add an index to the "updated_by" field, this is only
+// added when updated_by is tracked using WithUpdatedBy or WithUpdatedByFromSchema
+func WithUpdatedByIndex() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.UpdatedByIndex = true
+	}
+}
+
+// WithStrippedFieldAnnotations allows you to remove field annotations by name (e.g. "EntGQL")
+// from the fields that are copied from the original schema to the history schema
+func WithStrippedFieldAnnotations(names ...string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.StrippedFieldAnnotations = append(h.config.StrippedFieldAnnotations, names...)
+	}
+}
+
+// WithAllowedFieldAnnotations allows you to only copy field annotations by name (e.g. "EntSQL")
+// from the original schema to the history schema, all other field annotations are removed
+// this can be used along with WithStrippedFieldAnnotations, which takes precedence
+func WithAllowedFieldAnnotations(names ...string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.AllowedFieldAnnotations = append(h.config.AllowedFieldAnnotations, names...)
+	}
+}
+
+// WithFieldDefaults keeps the default values of the fields copied from the original schema to the history schema
+// by default these are removed, other than the id field, because history rows copy all values from the original
+func WithFieldDefaults() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.FieldDefaults = true
+	}
+}
+
+// WithFieldValidators keeps the validators (e.g. MinLen, Match, or custom validators) of the fields copied from
+// the original schema to the history schema, by default these are removed because historical values
+// may predate the validation rules and would be rejected when creating the history row
+func WithFieldValidators() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.FieldValidators = true
 	}
 }
 
@@ -162,6 +523,9 @@ func WithSchemaPath(schemaPath string) ExtensionOption {
 
 // WithFirstRun tells the extension to generate the history schema on the first run
 // which leaves out the entfga policy
+//
+// Deprecated: the entfga policy is left out of history schemas that do not exist yet, and
+// is added once the history schema exists from a previous run
 func WithFirstRun(firstRun bool) ExtensionOption {
 	return func(h *HistoryExtension) {
 		h.config.Auth.FirstRun = firstRun
@@ -175,13 +539,332 @@ func WithAllowedRelation(relation string) ExtensionOption {
 	}
 }
 
+// WithOwnerField sets the name of a field holding the owner of a schema (e.g. org_id, account_id) and the object type
+// of the owner, OwnerObjectTypeOrganization or OwnerObjectTypeUser, which is used by the authz policy to restrict
+// the history queries of org owned and user owned schemas
+func WithOwnerField(name, objectType string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		if h.config.Auth.OwnerFields == nil {
+			h.config.Auth.OwnerFields = map[string]string{}
+		}
+
+		h.config.Auth.OwnerFields[name] = objectType
+	}
+}
+
+// WithOwnerReadRules adds privacy rules to the history schemas of schemas owned by an organization or user (see
+// WithOwnerField), which filter the history queries to the rows owned by the owners of the viewer returned by the
+// owner resolver set using SetOwnerResolver, so history of other owners is never returned
+func WithOwnerReadRules() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.OwnerReadRules = true
+	}
+}
+
 // WithSkipper allows you to set a skipper function to skip history tracking
+//
+// Deprecated: the skipper is the body of a function injected into the generated code, use RegisterSkipper
+// to register a typed Skipper, or NewSkipContext, instead
 func WithSkipper(skipper string) ExtensionOption {
 	return func(h *HistoryExtension) {
 		h.config.Skipper = skipper
 	}
 }
 
+// WithSoftDeleteField sets the name of the field used to soft delete records, defaults to "deleted_at"
+// when this field is set on an update the history is recorded as a soft delete, and when
+// cleared the history is recorded as a restore
+func WithSoftDeleteField(name string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.SoftDeleteField = name
+	}
+}
+
+// WithCorrelationID adds a correlation_id field to the history schemas, which is set from the context
+// using NewCorrelationContext, this groups the history rows of changes committed together and
+// is used by the generated RestoreCascade to restore deleted records along with their children
+func WithCorrelationID() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.CorrelationID = true
+	}
+}
+
+// WithAuthzDecision adds the authz_subject and authz_relation fields to the history schemas, which are set from the
+// authorization decision on the context using NewAuthzDecisionContext, recording the subject and the relation that
+// permitted each change
+func WithAuthzDecision() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.AuthzDecision = true
+	}
+}
+
+// WithDialect generates the history schemas for the database dialect (dialect.Postgres, dialect.MySQL, or
+// dialect.SQLite), instead of all dialects; the column types are only set for the dialect, and the DDL written by the
+// generator (e.g. the audit summary) is only written for the dialect
+func WithDialect(name string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.Dialect = name
+	}
+}
+
+// WithComments adds database comments to the history tables (e.g. "History of users table, generated by enthistory")
+// and their columns, the columns copied from the original schema keep the comments of their fields
+func WithComments() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.Comments = true
+	}
+}
+
+// WithSchemaVersion adds a schema_version field to the history schemas, which stamps each history row with the version
+// of the fields of the original schema, so consumers reading older rows know which shape to expect; the version starts
+// at 1 and is bumped by the generator each time the fields of the original schema change
+func WithSchemaVersion() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.SchemaVersion = true
+	}
+}
+
+// WithLegacyFields keeps the fields removed from the original schema on the history schema as optional, nillable
+// legacy fields, instead of dropping them with the historical values; the fields are carried forward until they are
+// added back to the original schema, and this enables WithSchemaVersion which records the fields of each version
+func WithLegacyFields() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.LegacyFields = true
+		h.config.SchemaVersion = true
+	}
+}
+
+// WithMigrationGuidance writes migration guidance, SQL comments and DDL, for the history table to the directory
+// when fields are added, removed, or retyped on the original schema, named after the table and schema version
+// (e.g. todo_history_v2.sql); this enables WithSchemaVersion which records the fields of each version
+func WithMigrationGuidance(dir string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.MigrationGuidanceDir = dir
+		h.config.SchemaVersion = true
+	}
+}
+
+// WithHistoryMeta adds a history_meta schema which records the schemas tracked by enthistory, the name of their history
+// table, the current schema version, and the time the version was generated, so the audit surface can be discovered
+// programmatically; the rows are written by the generated client.SyncHistoryMeta, and this enables WithSchemaVersion
+func WithHistoryMeta() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.HistoryMeta = true
+		h.config.SchemaVersion = true
+	}
+}
+
+// WithLatestHistoryViews adds a view schema for each history schema (e.g. TodoHistoryLatest) exposing only the latest
+// history row of each ref, as the <history table>_latest view; ent does not create views using automatic migrations,
+// so the DDL creating the views is written to the directory (e.g. todo_history_latest.sql), unless it is empty
+func WithLatestHistoryViews(dir string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.LatestHistoryViews = true
+		h.config.LatestHistoryViewsDir = dir
+	}
+}
+
+// WithAuditSummary adds an AuditSummary view schema, of the audit_summary materialized view aggregating the number of
+// changes of the history tables per entity, tenant, actor (updated_by), and day, and generates client.RefreshAuditSummary
+// to refresh it; the DDL creating the view is written to the directory for each dialect (e.g.
+// audit_summary.postgres.sql), unless it is empty, dialects without materialized views use a table instead
+func WithAuditSummary(dir string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.AuditSummary = true
+		h.config.AuditSummaryDir = dir
+	}
+}
+
+// WithTriggers writes the DDL of the triggers mirroring the writes to the tables of the tracked schemas made using raw
+// SQL into the history tables, with the EXTERNAL operation, to the directory for each dialect supporting them (e.g.
+// history_triggers.postgres.sql); the writes of the application database user are skipped, since they are recorded by
+// the history hooks, and the EXTERNAL operation is registered on the history schemas
+func WithTriggers(dir, appUser string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.TriggersDir = dir
+		h.config.TriggersAppUser = appUser
+		h.config.Operations = append(h.config.Operations, OpTypeExternal)
+	}
+}
+
+// WithRestoredFrom adds a restored_from field to the history schemas, which records the id of the history row
+// used by Restore, RestoreCascade, or RevertField so audit reviewers can trace undo operations
+func WithRestoredFrom() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.RestoredFrom = true
+	}
+}
+
+// WithHistoryRepair generates a RepairHistory method on the client which inserts synthetic history rows for the gaps
+// found by CheckHistoryConsistency (e.g. records created while the history hooks were disabled), and adds a synthetic
+// field to the history schemas marking the inserted rows
+func WithHistoryRepair() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.HistoryRepair = true
+	}
+}
+
+// WithTestHarness generates the historytest package, with a test for each tracked schema creating, updating, and
+// deleting a record on an in-memory SQLite database and asserting the history rows recorded for each operation
+func WithTestHarness() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.TestHarness = true
+	}
+}
+
+// WithAttemptedChanges adds a history_attempt schema recording the mutations of the tracked schemas that fail, e.g.
+// denied by a privacy policy, rejected by a validator, or violating a constraint, with the values, the error, and the
+// user; the attempts are recorded by the generated history hooks outside of the transaction of the mutation, so they
+// are kept when the transaction is rolled back
+func WithAttemptedChanges() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.AttemptedChanges = true
+	}
+}
+
+// WithUpdateDebounce merges the updates of a record recorded within the window of its latest update history row, e.g.
+// from autosaving clients, into a single history row: the latest history row is replaced by the new history row, which
+// holds the latest values and keeps the history_time of the replaced row, so the changes recorded against the history
+// row before it are the union of the changes of the merged updates; updates by another user are not merged
+func WithUpdateDebounce(window time.Duration) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.UpdateDebounce = window
+	}
+}
+
+// WithUpsertTracking records upserts (creates using OnConflict) with the effective operation, a create that
+// resolves to an update of an existing record is recorded as an update, and the values of the record are read
+// back after the create so the history row matches the stored record; this adds two queries to each create
+func WithUpsertTracking() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.UpsertTracking = true
+	}
+}
+
+// WithTenantField adds a tenant_id field, and index, to every history schema which is set from the string value
+// of the key on the context, usually done via a middleware, so history can be filtered and purged per tenant;
+// schemas that have their own tenant_id field keep the value copied from the original record; the queries, updates,
+// and deletes of the history rows are constrained to the tenant on the context by the generated client, and fail
+// with ErrTenantRequired without a tenant unless using the system context
+func WithTenantField(key string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.TenantKey = key
+	}
+}
+
+// WithAdditionalFields adds the fields to every history schema, including the edge history schemas, which are set from
+// the value of their key on the context, usually done via a middleware (e.g. region or source_system); the values are
+// not set when missing from the context or of a different type, and schemas that have their own field with the same
+// name keep the value copied from the original record
+func WithAdditionalFields(fields ...AdditionalField) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.AdditionalFields = append(h.config.AdditionalFields, fields...)
+	}
+}
+
+// WithHistoryPolicy adds a privacy policy to the history schemas that denies creating, updating, or deleting history
+// rows unless the mutation is created by the history hooks, making the history tables append-only from application
+// code, other than deletes by the generated Purge and DeleteHistoryByRef methods of the history clients; this requires
+// the ent privacy feature (gen.FeaturePrivacy)
+func WithHistoryPolicy() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.HistoryPolicy = true
+	}
+}
+
+// WithInheritedPolicy adds a privacy policy to the history schemas of schemas with a policy, that evaluates history
+// queries using the policy of the original schema, so history is readable by the same viewers as the original records;
+// mutations are evaluated using the history policy when using WithHistoryPolicy, and the authz policy takes precedence
+func WithInheritedPolicy() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.InheritedPolicy = true
+	}
+}
+
+// WithPolicyTemplate replaces the generated policy of the history schemas with the template at the path of the file
+// system, the template is executed with the information of each history schema (e.g. {{ .Schema.Name }} and
+// {{ .OriginalTableName }}) and should include the Policy method, e.g.
+//
+//	// Policy of the {{ .Schema.Name }}
+//	func ({{ .Schema.Name }}) Policy() ent.Policy {
+//		return rules.HistoryPolicy()
+//	}
+func WithPolicyTemplate(fsys fs.FS, path string) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.PolicyTemplate = &PolicyTemplate{
+			fsys: fsys,
+			path: path,
+		}
+	}
+}
+
+// WithEdgeHistory tracks the edges added to and removed from many-to-many edges without an edge schema, this
+// generates a history schema for each join table (e.g. user_groups_history) recording the ids of both ends of the
+// edge, the operation (INSERT when added, DELETE when removed), the time, and updated_by when using WithUpdatedBy
+func WithEdgeHistory() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.EdgeHistory = true
+	}
+}
+
+// WithOperations registers custom operations (e.g. IMPORT, MERGE) that are accepted by the operation
+// field of the history schemas, these are recorded on history rows by setting them on the context
+// using NewOperationContext
+func WithOperations(ops ...OpType) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.Operations = append(h.config.Operations, ops...)
+	}
+}
+
+// WithPostUpdateCapture captures the update history after the mutation, instead of before, in the history hooks
+// registered by the generated WithHistory and WithAutoHooks, see CapturePostMutation
+func WithPostUpdateCapture() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.PostUpdateCapture = true
+	}
+}
+
+// WithOptIn only tracks the history of schemas that opt in, using the history Mixin or the Track history annotation,
+// instead of tracking all schemas that are not excluded
+func WithOptIn() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.OptIn = true
+	}
+}
+
+// WithAutoHooks registers the history hooks of all tracked schemas on every client created with NewClient, so the
+// hooks do not need to be added to each schema or by calling WithHistory on the client, which becomes a no-op
+func WithAutoHooks() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.AutoHooks = true
+	}
+}
+
+// WithDefaultOrder generates an interceptor for each history type, registered on every client created with NewClient,
+// that orders the history queries by history_time, newest first, unless the query sets its own order using Order
+func WithDefaultOrder() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.DefaultOrder = true
+	}
+}
+
+// WithSink adds a hook to the history schemas writing the history rows, once committed, to the secondary sink set
+// at runtime using SetSink (e.g. a ClickHouseSink), so long-term analytics are kept while the history tables are purged
+func WithSink() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.Sink = true
+	}
+}
+
+// WithCallbacks adds a hook to the history schemas calling the callbacks registered at runtime using RegisterCallback
+// after each history row is committed, so applications can react to the changes (e.g. notifications, cache invalidation,
+// or search indexing) without forking the templates
+func WithCallbacks() ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.Callbacks = true
+	}
+}
+
 // WithUpdatedBy sets the key and type for pulling updated_by from the context,
 // usually done via a middleware to track which users are making which changes
 func WithUpdatedBy(key string, valueType ValueType) ExtensionOption {
@@ -195,12 +878,23 @@ func WithUpdatedBy(key string, valueType ValueType) ExtensionOption {
 	}
 }
 
+// WithDeletedBy sets the key and type for pulling deleted_by from the context,
+// this is recorded on delete and soft delete history rows to track which users are deleting records
+func WithDeletedBy(key string, valueType ValueType) ExtensionOption {
+	return func(h *HistoryExtension) {
+		h.config.DeletedBy = &DeletedBy{
+			key:       key,
+			valueType: valueType,
+		}
+	}
+}
+
 // WithUpdatedByFromSchema uses the original update_by value in the schema and includes in the audit results
 func WithUpdatedByFromSchema(valueType ValueType, nillable bool) ExtensionOption {
 	return func(h *HistoryExtension) {
 		h.config.IncludeUpdatedBy = true
 		h.config.UpdatedBy = &UpdatedBy{
-			valueType: ValueTypeString,
+			valueType: valueType,
 			Nillable:  nillable,
 		}
 	}
@@ -0,0 +1,353 @@
+package enthistory
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"entgo.io/ent/entc/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtension(t *testing.T) {
+	dir := copyTestSchemas(t)
+
+	cfg := &gen.Config{}
+	require.NoError(t, Extension(WithSchemaPath(dir), WithAuditing())(cfg))
+
+	assert.FileExists(t, filepath.Join(dir, "user_history.go"))
+	assert.FileExists(t, filepath.Join(dir, "list_history.go"))
+	assert.Len(t, cfg.Templates, len(New(WithAuditing()).Templates()))
+	assert.Contains(t, cfg.Annotations, Config{}.Name())
+
+	assert.Error(t, Extension(WithSchemaPath(filepath.Join(dir, "missing")))(&gen.Config{}))
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ExtensionOption
+		want []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON"},
+		},
+		{
+			name: "auditing and auto hooks",
+			opts: []ExtensionOption{WithAuditing(), WithAutoHooks()},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "auditing", "historyRuntime"},
+		},
+		{
+			name: "gql query",
+			opts: []ExtensionOption{WithGQLQuery()},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "historyResolver"},
+		},
+		{
+			name: "history repair",
+			opts: []ExtensionOption{WithHistoryRepair()},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "historyRepair"},
+		},
+		{
+			name: "history meta",
+			opts: []ExtensionOption{WithHistoryMeta()},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "historyMeta"},
+		},
+		{
+			name: "audit summary",
+			opts: []ExtensionOption{WithAuditSummary("")},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "auditSummary"},
+		},
+		{
+			name: "tenant field",
+			opts: []ExtensionOption{WithTenantField("organizationID")},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "historyTenant"},
+		},
+		{
+			name: "default order",
+			opts: []ExtensionOption{WithDefaultOrder()},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "historyOrder"},
+		},
+		{
+			name: "test harness",
+			opts: []ExtensionOption{WithTestHarness()},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "historytest/historytest"},
+		},
+		{
+			name: "attempted changes",
+			opts: []ExtensionOption{WithAttemptedChanges()},
+			want: []string{"historyFromMutation", "historyQuery", "historyClient", "historyBackfill", "historyConsistency", "historyAsOf", "historyExport", "historyJSON", "historyAttempt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates := New(tt.opts...).Templates()
+
+			names := make([]string, 0, len(templates))
+			for _, tmpl := range templates {
+				names = append(names, tmpl.Name())
+			}
+
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestWithHistoryMeta(t *testing.T) {
+	h := New(WithHistoryMeta())
+
+	assert.True(t, h.config.HistoryMeta)
+	assert.True(t, h.config.SchemaVersion)
+}
+
+func TestWithAuditing(t *testing.T) {
+	h := New(WithAuditing())
+
+	assert.True(t, h.config.Auditing)
+	assert.Equal(t, AuditConfig{}, h.config.Audit)
+
+	h = New(WithAuditing(WithAuditFormat(AuditFormatJSON), WithAuditTables("Todo"), WithAuditTables("List"),
+		WithAuditDiffOnly()))
+
+	assert.Equal(t, AuditConfig{Format: AuditFormatJSON, Tables: []string{"Todo", "List"}, DiffOnly: true}, h.config.Audit)
+}
+
+func TestWithGQLFederation(t *testing.T) {
+	h := New(WithGQLFederation())
+
+	assert.True(t, h.config.Query)
+	assert.True(t, h.config.GQLFederation)
+	assert.False(t, New(WithGQLQuery()).config.GQLFederation)
+}
+
+func TestWithHistoryTimeIndex(t *testing.T) {
+	h := New(WithHistoryTimeIndex())
+
+	assert.True(t, h.config.HistoryTimeIndex)
+	assert.Equal(t, IndexConfig{}, h.config.HistoryTimeIndexConfig)
+
+	h = New(WithHistoryTimeIndex(WithIndexType("BRIN"), WithIndexName("%s_time_brin")))
+
+	assert.Equal(t, IndexConfig{Type: "BRIN", Name: "%s_time_brin"}, h.config.HistoryTimeIndexConfig)
+}
+
+func TestWithAuditMasking(t *testing.T) {
+	assert.False(t, New().config.AuditMasking)
+	assert.True(t, New(WithAuditMasking()).config.AuditMasking)
+}
+
+func TestWithDialect(t *testing.T) {
+	assert.Empty(t, New().config.Dialect)
+	assert.Equal(t, "mysql", New(WithDialect("mysql")).config.Dialect)
+}
+
+func TestWithComments(t *testing.T) {
+	assert.False(t, New().config.Comments)
+	assert.True(t, New(WithComments()).config.Comments)
+}
+
+func TestWithUpdateDebounce(t *testing.T) {
+	h := New(WithUpdateDebounce(5 * time.Second))
+
+	assert.Equal(t, 5*time.Second, h.config.UpdateDebounce)
+}
+
+func TestWithLegacyFields(t *testing.T) {
+	h := New(WithLegacyFields())
+
+	assert.True(t, h.config.LegacyFields)
+	assert.True(t, h.config.SchemaVersion)
+}
+
+func TestWithMigrationGuidance(t *testing.T) {
+	h := New(WithMigrationGuidance("./migrations/history"))
+
+	assert.Equal(t, "./migrations/history", h.config.MigrationGuidanceDir)
+	assert.True(t, h.config.SchemaVersion)
+}
+
+func TestWithLatestHistoryViews(t *testing.T) {
+	h := New(WithLatestHistoryViews("./migrations/views"))
+
+	assert.True(t, h.config.LatestHistoryViews)
+	assert.Equal(t, "./migrations/views", h.config.LatestHistoryViewsDir)
+	assert.Len(t, h.Hooks(), 1)
+
+	assert.Empty(t, New().Hooks())
+}
+
+func TestWithAuditSummary(t *testing.T) {
+	h := New(WithAuditSummary("./migrations/summary"))
+
+	assert.True(t, h.config.AuditSummary)
+	assert.Equal(t, "./migrations/summary", h.config.AuditSummaryDir)
+	assert.Len(t, h.Hooks(), 1)
+}
+
+func TestWithAuthzDecision(t *testing.T) {
+	assert.False(t, New().config.AuthzDecision)
+	assert.True(t, New(WithAuthzDecision()).config.AuthzDecision)
+}
+
+func TestWithTriggers(t *testing.T) {
+	h := New(WithTriggers("./migrations/triggers", "app"))
+
+	assert.Equal(t, "./migrations/triggers", h.config.TriggersDir)
+	assert.Equal(t, "app", h.config.TriggersAppUser)
+	assert.Equal(t, []OpType{OpTypeExternal}, h.config.Operations)
+}
+
+func TestWithSink(t *testing.T) {
+	h := New(WithSink())
+
+	assert.True(t, h.config.Sink)
+}
+
+func TestWithCallbacks(t *testing.T) {
+	h := New(WithCallbacks())
+
+	assert.True(t, h.config.Callbacks)
+}
+
+func TestWithTestHarness(t *testing.T) {
+	h := New(WithTestHarness())
+
+	assert.True(t, h.config.TestHarness)
+}
+
+// harnessModule is the go.mod of the module of the fixture schemas, using the enthistory of the working tree
+const harnessModule = `module harness
+
+go 1.22.5
+
+require github.com/datumforge/enthistory v0.0.0
+
+replace github.com/datumforge/enthistory => %s
+`
+
+// harnessEntc generates the history schemas and the ent code of the fixture schemas, with the test harness and the
+// options of the test
+const harnessEntc = `//go:build ignore
+
+package main
+
+import (
+	"log"
+
+	"entgo.io/ent/entc"
+	"entgo.io/ent/entc/gen"
+
+	"github.com/datumforge/enthistory"
+)
+
+func main() {
+	ext := enthistory.New(enthistory.WithSchemaPath("./schema"), enthistory.WithTestHarness(), %s)
+	if err := ext.GenerateSchemas(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := entc.Generate("./schema", &gen.Config{}, entc.Extensions(ext)); err != nil {
+		log.Fatal(err)
+	}
+}
+`
+
+// harnessTest runs the generated history tests of the fixture schemas
+const harnessTest = `package harness_test
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"harness/ent/historytest"
+)
+
+func TestHistory(t *testing.T) {
+	historytest.Run(t, context.WithValue(context.Background(), "userID", "user-1"))
+}
+`
+
+// runGo runs the go command in the directory, adding the missing requirements to the go.mod like go generate does for
+// ent, and fails the test with the output of the command on errors
+func runGo(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "go %s: %s", strings.Join(args, " "), out)
+}
+
+func TestGeneratedTestHarness(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generating and compiling the ent code of the fixture schemas")
+	}
+
+	root, err := os.Getwd()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		opts []string
+	}{
+		{
+			name: "minimal",
+			opts: []string{"enthistory.WithAutoHooks()"},
+		},
+		{
+			name: "all options",
+			opts: []string{
+				"enthistory.WithAutoHooks()",
+				`enthistory.WithUpdatedBy("userID", enthistory.ValueTypeString)`,
+				"enthistory.WithAuditing()",
+				"enthistory.WithHistoryMeta()",
+				"enthistory.WithPostUpdateCapture()",
+				"enthistory.WithCorrelationID()",
+				"enthistory.WithRestoredFrom()",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			entDir := filepath.Join(dir, "ent")
+			require.NoError(t, os.MkdirAll(filepath.Join(entDir, "schema"), 0o755))
+
+			for _, name := range []string{"todo.go", "user.go"} {
+				content, err := os.ReadFile(filepath.Join("testdata", "harness", name))
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(entDir, "schema", name), content, 0o600))
+			}
+
+			files := map[string]string{
+				filepath.Join(dir, "go.mod"):          fmt.Sprintf(harnessModule, root),
+				filepath.Join(entDir, "entc.go"):      fmt.Sprintf(harnessEntc, strings.Join(tc.opts, ", ")),
+				filepath.Join(dir, "harness_test.go"): harnessTest,
+			}
+
+			for path, content := range files {
+				require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			}
+
+			runGo(t, entDir, "run", "entc.go")
+			runGo(t, dir, "mod", "tidy")
+			runGo(t, dir, "test", "./...")
+		})
+	}
+}
+
+func TestWithAttemptedChanges(t *testing.T) {
+	h := New(WithAttemptedChanges())
+
+	assert.True(t, h.config.AttemptedChanges)
+}
@@ -0,0 +1,135 @@
+// Package enthistorytest provides test helpers for the schemas generated by enthistory
+package enthistorytest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/datumforge/enthistory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// UpdateEnv is the environment variable updating the golden files instead of comparing them when set, e.g.
+	// ENTHISTORY_UPDATE_GOLDEN=1 go test ./...
+	UpdateEnv = "ENTHISTORY_UPDATE_GOLDEN"
+	// GoldenDir is the directory of the golden files, relative to the directory of the test
+	GoldenDir = "testdata/enthistory"
+	// generatedHeader is the header of the files generated by enthistory
+	generatedHeader = "// Code generated by enthistory, DO NOT EDIT."
+)
+
+// generatedAt matches the generation time of the history schemas, which changes on every run for new schemas
+var generatedAt = regexp.MustCompile(`SchemaGeneratedAt:(\s+)\d+,`)
+
+// AssertGeneratedSchemas generates the history schemas of the schemas in the schema directory using the options,
+// into a copy of the directory, and compares the generated schemas with the golden files in GoldenDir, named after the
+// generated files (e.g. todo_history.go.golden); the golden files are written instead when UpdateEnv is set. The
+// copy is created next to the schema directory, so it is in the same module, and removed once the test is done; the
+// files generated outside of the schema directory (e.g. the DDL of the latest history views) are not compared
+func AssertGeneratedSchemas(t testing.TB, schemaDir string, opts ...enthistory.ExtensionOption) {
+	t.Helper()
+
+	generated := generateSchemas(t, schemaDir, opts...)
+
+	if os.Getenv(UpdateEnv) != "" {
+		updateGoldenFiles(t, generated)
+
+		return
+	}
+
+	golden, err := filepath.Glob(filepath.Join(GoldenDir, "*.golden"))
+	require.NoError(t, err)
+
+	for _, path := range golden {
+		name := strings.TrimSuffix(filepath.Base(path), ".golden")
+		if _, ok := generated[name]; !ok {
+			t.Errorf("%s was not generated, run the test with %s=1 to update the golden files", name, UpdateEnv)
+		}
+	}
+
+	for name, got := range generated {
+		want, err := os.ReadFile(filepath.Join(GoldenDir, name+".golden"))
+		if os.IsNotExist(err) {
+			t.Errorf("%s has no golden file, run the test with %s=1 to update the golden files", name, UpdateEnv)
+
+			continue
+		}
+
+		require.NoError(t, err)
+
+		assert.Equal(t, string(normalize(want)), string(got), "%s differs from its golden file", name)
+	}
+}
+
+// generateSchemas generates the history schemas into a copy of the schema directory, and returns the normalized
+// contents of the generated files by name
+func generateSchemas(t testing.TB, schemaDir string, opts ...enthistory.ExtensionOption) map[string][]byte {
+	t.Helper()
+
+	abs, err := filepath.Abs(schemaDir)
+	require.NoError(t, err)
+
+	tmp, err := os.MkdirTemp(filepath.Dir(abs), ".enthistorytest-")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(tmp) })
+
+	// the package name of the history schemas is the name of the schema directory
+	dir := filepath.Join(tmp, filepath.Base(abs))
+	require.NoError(t, os.Mkdir(dir, 0o755)) //nolint:mnd
+
+	entries, err := os.ReadDir(abs)
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".go" {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(abs, entry.Name()))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, entry.Name()), content, 0o600)) //nolint:mnd
+	}
+
+	opts = append(opts, enthistory.WithSchemaPath(dir))
+	require.NoError(t, enthistory.New(opts...).GenerateSchemas())
+
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+
+	generated := map[string][]byte{}
+
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+
+		if bytes.HasPrefix(content, []byte(generatedHeader)) {
+			generated[entry.Name()] = normalize(content)
+		}
+	}
+
+	return generated
+}
+
+// updateGoldenFiles replaces the golden files with the generated files
+func updateGoldenFiles(t testing.TB, generated map[string][]byte) {
+	t.Helper()
+
+	require.NoError(t, os.RemoveAll(GoldenDir))
+	require.NoError(t, os.MkdirAll(GoldenDir, 0o755)) //nolint:mnd
+
+	for name, content := range generated {
+		require.NoError(t, os.WriteFile(filepath.Join(GoldenDir, name+".golden"), content, 0o600)) //nolint:mnd
+	}
+}
+
+// normalize replaces the generation time of the history schemas, so new history schemas match their golden files
+func normalize(content []byte) []byte {
+	return generatedAt.ReplaceAll(content, []byte("SchemaGeneratedAt:${1}0,"))
+}
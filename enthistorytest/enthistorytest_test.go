@@ -0,0 +1,18 @@
+package enthistorytest
+
+import (
+	"testing"
+
+	"github.com/datumforge/enthistory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertGeneratedSchemas(t *testing.T) {
+	AssertGeneratedSchemas(t, "../testdata/schema", enthistory.WithSchemaVersion())
+}
+
+func TestNormalize(t *testing.T) {
+	got := normalize([]byte("SchemaGeneratedAt: 1792264810,\n\t\t\tSchemaGeneratedAt:    42,"))
+
+	assert.Equal(t, "SchemaGeneratedAt: 0,\n\t\t\tSchemaGeneratedAt:    0,", string(got))
+}
@@ -0,0 +1,21 @@
+package enthistory
+
+import (
+	"context"
+)
+
+// erasureKey is the context key of the erasure order allowing the history of a record to be deleted
+type erasureKey struct{}
+
+// NewErasureContext returns a copy of the context holding the erasure order, e.g. the reference of a legal deletion
+// order, which is the capability required by the generated DeleteHistoryByRef to remove all traces of a record
+func NewErasureContext(ctx context.Context, order string) context.Context {
+	return context.WithValue(ctx, erasureKey{}, order)
+}
+
+// ErasureFromContext returns the erasure order of the context, if it was set
+func ErasureFromContext(ctx context.Context) (string, bool) {
+	order, ok := ctx.Value(erasureKey{}).(string)
+
+	return order, ok && order != ""
+}
@@ -0,0 +1,20 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErasureContext(t *testing.T) {
+	_, ok := ErasureFromContext(context.Background())
+	assert.False(t, ok)
+
+	_, ok = ErasureFromContext(NewErasureContext(context.Background(), ""))
+	assert.False(t, ok)
+
+	order, ok := ErasureFromContext(NewErasureContext(context.Background(), "order-1"))
+	assert.True(t, ok)
+	assert.Equal(t, "order-1", order)
+}
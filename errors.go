@@ -14,12 +14,99 @@ var (
 	// ErrNoIDType is returned when the id type cannot be determined from the schema
 	ErrNoIDType = errors.New("could not get id type for schema")
 
+	// ErrUnsupportedDialect is returned when generating the history schemas for a dialect other than postgres, mysql,
+	// or sqlite
+	ErrUnsupportedDialect = errors.New("unsupported dialect, only postgres, mysql, and sqlite3 are allowed")
+
 	// ErrInvalidSchemaPath is returned when the schema path cannot be determined
 	ErrInvalidSchemaPath = errors.New("invalid schema path, unable to find package name in path")
 
 	// ErrFailedToGenerateTemplate is returned when the template cannot be generated
 	ErrFailedToGenerateTemplate = errors.New("failed to generate template")
 
+	// ErrInvalidIndexName is returned when the name of an index added to the history schemas does not hold the
+	// history table name
+	ErrInvalidIndexName = errors.New("invalid index name")
+	// ErrIndexNotFound is returned when an index set in the history annotations does not exist on the original schema
+	ErrIndexNotFound = errors.New("index not found in schema")
+
+	// ErrFieldNotFound is returned when a field set in the history annotations does not exist on the original schema
+	ErrFieldNotFound = errors.New("field not found in schema")
+
+	// ErrInvalidRefFields is returned when the ref fields set in the history annotations are not valid
+	ErrInvalidRefFields = errors.New("invalid ref fields")
+
+	// ErrInvalidSkipOp is returned when an operation set in the SkipOps of the history annotations is not valid
+	ErrInvalidSkipOp = errors.New("invalid skip operation, must be CREATE, UPDATE, or DELETE")
+
+	// ErrInvalidFieldLimit is returned when a field limit set in the history annotations is not valid
+	ErrInvalidFieldLimit = errors.New("invalid field limit")
+
+	// ErrBlobSinkNotSet is returned when storing a large history value using LimitExternal before calling SetBlobSink
+	ErrBlobSinkNotSet = errors.New("blob sink not set, use SetBlobSink to store large history values externally")
+
+	// ErrCompressorNotRegistered is returned when compressing or decompressing a history value using a compression
+	// without a registered Compressor
+	ErrCompressorNotRegistered = errors.New("compressor not registered, use RegisterCompressor to add it")
+
+	// ErrInvalidHistoryTimePrecision is returned when the precision of the history_time field is not between 0 and 6
+	ErrInvalidHistoryTimePrecision = errors.New("invalid history_time precision, must be between 0 and 6")
+
 	// ErrFailedToWriteTemplate is returned when the template cannot be written
 	ErrFailedToWriteTemplate = errors.New("failed to write template")
+
+	// ErrRestoreConflict is returned when a restored record collides with the unique fields of an existing record
+	ErrRestoreConflict = errors.New("restored record conflicts with an existing record")
+
+	// ErrRestoreNewID is returned when restoring a deleted record whose id is generated by the database without
+	// WithNewID, the record cannot be recreated under its original id
+	ErrRestoreNewID = errors.New("restored record would be recreated under a new id")
+
+	// ErrInvalidCursor is returned when paging history using a cursor that was not returned by PageHistory
+	ErrInvalidCursor = errors.New("invalid history cursor")
+
+	// ErrSinkClosed is returned when writing history events to a sink that was closed
+	ErrSinkClosed = errors.New("history sink closed")
+
+	// ErrCopyFromNotSet is returned when creating a sink writing using WriteCopyFrom without a CopyFrom function
+	ErrCopyFromNotSet = errors.New("copy from function not set, use WithSinkCopyFrom to write using the driver")
+
+	// ErrInvalidWatermark is returned when exporting changes using a watermark that was not returned by ExportChanges
+	ErrInvalidWatermark = errors.New("invalid export watermark")
+
+	// ErrTxRequired is returned when reconstructing a temporary table outside of a transaction, temporary tables are
+	// only visible on the connection creating them
+	ErrTxRequired = errors.New("a transaction is required to reconstruct a temporary table")
+
+	// ErrFieldNotRevertible is returned when reverting a field that does not exist or is immutable
+	ErrFieldNotRevertible = errors.New("field cannot be reverted")
+
+	// ErrUnrepairableIssue is returned when repairing a consistency issue that cannot be repaired by inserting a history row
+	ErrUnrepairableIssue = errors.New("consistency issue cannot be repaired")
+
+	// ErrHistoryMutationDenied is returned by the history policy when history is mutated outside of the history hooks
+	ErrHistoryMutationDenied = errors.New("history can only be created by the history hooks, and deleted using purge")
+
+	// ErrHistoryQueryDenied is returned by the owner read rules when the viewer does not own any history
+	ErrHistoryQueryDenied = errors.New("history can only be read by its owners")
+	// ErrUnsupportedAuditFormat is returned when the audit log is written in a format other than csv or json
+	ErrUnsupportedAuditFormat = errors.New("unsupported audit format, only csv and json are allowed")
+	// ErrAuditOutputNotSet is returned when exporting the audit log without an audit output
+	ErrAuditOutputNotSet = errors.New("audit output not set, use SetAuditOutput to set it")
+
+	// ErrHistoryVetoed is returned when an enricher registered using RegisterEnricher vetoes a history row
+	ErrHistoryVetoed = errors.New("history row vetoed by an enricher")
+
+	// ErrErasureNotAuthorized is returned when deleting the history of a record without an erasure order on the context
+	ErrErasureNotAuthorized = errors.New("erasing history requires an erasure order, use NewErasureContext to set it")
+
+	// ErrInvalidTableName is returned when inspecting a history table with a name that is not a valid identifier
+	ErrInvalidTableName = errors.New("invalid history table name")
+
+	// ErrHistoryRowNotFound is returned when diffing a history row that is not a history row of the ref
+	ErrHistoryRowNotFound = errors.New("history row not found")
+
+	// ErrTenantRequired is returned when querying or deleting history rows with a tenant field without a tenant on the
+	// context, use the generated NewHistorySystemContext for background jobs that work across all tenants
+	ErrTenantRequired = errors.New("history rows can only be read or deleted with a tenant on the context")
 )
@@ -0,0 +1,190 @@
+package enthistory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"entgo.io/ent/entc/load"
+	"entgo.io/ent/schema/field"
+)
+
+// FieldChangeKind is the kind of change made to a tracked field of the original schema
+type FieldChangeKind string
+
+const (
+	// FieldAdded is a field added to the original schema
+	FieldAdded FieldChangeKind = "ADDED"
+	// FieldRemoved is a field removed from the original schema
+	FieldRemoved FieldChangeKind = "REMOVED"
+	// FieldRetyped is a field of the original schema whose type changed
+	FieldRetyped FieldChangeKind = "RETYPED"
+)
+
+// FieldChange is a change made to a tracked field of the original schema between two schema versions
+type FieldChange struct {
+	// Field is the name of the field
+	Field string
+	// Kind of change
+	Kind FieldChangeKind
+	// OldType is the ent field type (e.g. TypeString) before the change, empty when the field was added
+	OldType string
+	// NewType is the ent field type (e.g. TypeInt64) after the change, empty when the field was removed
+	NewType string
+}
+
+// schemaFields returns the ent field types (e.g. TypeString) of the fields of the schema by name
+func schemaFields(schema *load.Schema) map[string]string {
+	fields := make(map[string]string, len(schema.Fields))
+
+	for _, f := range schema.Fields {
+		if f.Info == nil {
+			continue
+		}
+
+		fields[f.Name] = f.Info.Type.ConstName()
+	}
+
+	return fields
+}
+
+// diffSchemaFields returns the changes between the previous and current fields, sorted by field name
+func diffSchemaFields(previous, current map[string]string) []FieldChange {
+	var changes []FieldChange
+
+	for name, newType := range current {
+		oldType, ok := previous[name]
+
+		switch {
+		case !ok:
+			changes = append(changes, FieldChange{Field: name, Kind: FieldAdded, NewType: newType})
+		case oldType != newType:
+			changes = append(changes, FieldChange{Field: name, Kind: FieldRetyped, OldType: oldType, NewType: newType})
+		}
+	}
+
+	for name, oldType := range previous {
+		if _, ok := current[name]; !ok {
+			changes = append(changes, FieldChange{Field: name, Kind: FieldRemoved, OldType: oldType})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b FieldChange) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+
+	return changes
+}
+
+// legacyFields returns the fields kept on the history schema as legacy fields, the previous legacy fields
+// and the removed fields, leaving out the fields that exist on the original schema again
+func legacyFields(previous map[string]string, changes []FieldChange, current map[string]string) map[string]string {
+	legacy := map[string]string{}
+
+	for name, t := range previous {
+		if _, ok := current[name]; !ok {
+			legacy[name] = t
+		}
+	}
+
+	for _, c := range changes {
+		if c.Kind == FieldRemoved && legacyFieldBuilder(c.OldType) != "" {
+			legacy[c.Field] = c.OldType
+		}
+	}
+
+	return legacy
+}
+
+// legacyFieldBuilder returns the ent field builder of the legacy field of the ent field type, enums are kept
+// as strings, and an empty string is returned for types that cannot be kept (e.g. TypeOther)
+func legacyFieldBuilder(t string) string {
+	switch t {
+	case field.TypeEnum.ConstName():
+		return "String"
+	case field.TypeFloat64.ConstName():
+		return "Float"
+	case field.TypeOther.ConstName(), field.TypeInvalid.ConstName(), "":
+		return ""
+	default:
+		return strings.TrimPrefix(t, "Type")
+	}
+}
+
+// applySchemaChanges sets the legacy fields of the history schema, when using WithLegacyFields, and writes the
+// migration guidance for the changes made to the fields of the original schema, when using WithMigrationGuidance
+func applySchemaChanges(info *templateInfo, version *schemaVersion, config *Config) error {
+	var legacy map[string]string
+
+	if config.LegacyFields {
+		legacy = legacyFields(version.Legacy, version.Changes, version.Fields)
+
+		names := make([]string, 0, len(legacy))
+		for name := range legacy {
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+
+		for _, name := range names {
+			info.LegacyFields = append(info.LegacyFields, legacyFieldInfo{
+				Name:    name,
+				Type:    legacy[name],
+				Builder: legacyFieldBuilder(legacy[name]),
+			})
+		}
+	}
+
+	if config.MigrationGuidanceDir == "" || len(version.Changes) == 0 {
+		return nil
+	}
+
+	return writeMigrationGuidance(config.MigrationGuidanceDir, info.TableName, info.SchemaVersion, version.Changes, legacy)
+}
+
+// migrationGuidance returns the guidance, and DDL, for migrating the history table after the changes
+// made to the fields of the original schema
+func migrationGuidance(table string, version int, changes []FieldChange, legacy map[string]string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- Code generated by enthistory, migration guidance for %s schema version %d\n", table, version)
+
+	for _, c := range changes {
+		b.WriteString("\n")
+
+		switch c.Kind {
+		case FieldAdded:
+			fmt.Fprintf(&b, "-- %s was added (%s), the column is added to %s as a nullable column by the ent migration,\n", c.Field, c.NewType, table)
+			fmt.Fprintf(&b, "-- history rows created before schema version %d have no value\n", version)
+		case FieldRetyped:
+			fmt.Fprintf(&b, "-- %s was changed from %s to %s, existing history values must be convertible to the new type,\n", c.Field, c.OldType, c.NewType)
+			b.WriteString("-- review the type change of the column generated by the ent migration before applying it\n")
+		case FieldRemoved:
+			if _, ok := legacy[c.Field]; ok {
+				fmt.Fprintf(&b, "-- %s was removed (%s), the column is kept on %s as a legacy field so history values are not lost\n", c.Field, c.OldType, table)
+
+				continue
+			}
+
+			fmt.Fprintf(&b, "-- %s was removed (%s), the column is no longer part of %s, keep it to retain the history values,\n", c.Field, c.OldType, table)
+			b.WriteString("-- or drop it once the history values are no longer needed (or use WithLegacyFields to keep it)\n")
+			fmt.Fprintf(&b, "-- ALTER TABLE %s DROP COLUMN %s;\n", table, c.Field)
+		}
+	}
+
+	return b.String()
+}
+
+// writeMigrationGuidance writes the migration guidance for the schema version of the history table to the
+// directory, the file is named after the table and version (e.g. todo_history_v2.sql)
+func writeMigrationGuidance(dir, table string, version int, changes []FieldChange, legacy map[string]string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s_v%d.sql", table, version))
+
+	return os.WriteFile(path, []byte(migrationGuidance(table, version, changes, legacy)), 0o600) //nolint:mnd
+}
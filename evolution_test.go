@@ -0,0 +1,153 @@
+package enthistory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"entgo.io/ent/entc/load"
+	"entgo.io/ent/schema/field"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSchemaFields(t *testing.T) {
+	previous := map[string]string{
+		"name":     "TypeString",
+		"priority": "TypeInt",
+		"due":      "TypeTime",
+	}
+
+	current := map[string]string{
+		"name":     "TypeString",
+		"priority": "TypeInt64",
+		"owner":    "TypeUUID",
+	}
+
+	assert.Equal(t, []FieldChange{
+		{Field: "due", Kind: FieldRemoved, OldType: "TypeTime"},
+		{Field: "owner", Kind: FieldAdded, NewType: "TypeUUID"},
+		{Field: "priority", Kind: FieldRetyped, OldType: "TypeInt", NewType: "TypeInt64"},
+	}, diffSchemaFields(previous, current))
+
+	assert.Empty(t, diffSchemaFields(previous, previous))
+}
+
+func TestSchemaFields(t *testing.T) {
+	schema := &load.Schema{
+		Name: "Todo",
+		Fields: []*load.Field{
+			{Name: "name", Info: &field.TypeInfo{Type: field.TypeString}},
+			{Name: "status", Info: &field.TypeInfo{Type: field.TypeEnum}},
+			{Name: "unknown"},
+		},
+	}
+
+	assert.Equal(t, map[string]string{"name": "TypeString", "status": "TypeEnum"}, schemaFields(schema))
+}
+
+func TestLegacyFields(t *testing.T) {
+	changes := []FieldChange{
+		{Field: "due", Kind: FieldRemoved, OldType: "TypeTime"},
+		{Field: "location", Kind: FieldRemoved, OldType: "TypeOther"},
+		{Field: "owner", Kind: FieldAdded, NewType: "TypeUUID"},
+	}
+
+	// archived was removed in a previous version, and notes was added back to the original schema
+	previous := map[string]string{"archived": "TypeBool", "notes": "TypeString"}
+	current := map[string]string{"name": "TypeString", "notes": "TypeString", "owner": "TypeUUID"}
+
+	assert.Equal(t, map[string]string{
+		"archived": "TypeBool",
+		"due":      "TypeTime",
+	}, legacyFields(previous, changes, current))
+}
+
+func TestLegacyFieldBuilder(t *testing.T) {
+	tests := []struct {
+		fieldType string
+		want      string
+	}{
+		{fieldType: "TypeString", want: "String"},
+		{fieldType: "TypeEnum", want: "String"},
+		{fieldType: "TypeFloat64", want: "Float"},
+		{fieldType: "TypeFloat32", want: "Float32"},
+		{fieldType: "TypeInt64", want: "Int64"},
+		{fieldType: "TypeUUID", want: "UUID"},
+		{fieldType: "TypeJSON", want: "JSON"},
+		{fieldType: "TypeOther", want: ""},
+		{fieldType: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fieldType, func(t *testing.T) {
+			assert.Equal(t, tt.want, legacyFieldBuilder(tt.fieldType))
+		})
+	}
+}
+
+func TestApplySchemaChanges(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+
+	version := &schemaVersion{
+		Version: 2,
+		Fields:  map[string]string{"name": "TypeString"},
+		Changes: []FieldChange{
+			{Field: "due", Kind: FieldRemoved, OldType: "TypeTime"},
+			{Field: "priority", Kind: FieldRemoved, OldType: "TypeInt"},
+		},
+		Legacy: map[string]string{"archived": "TypeBool"},
+	}
+
+	t.Run("legacy fields", func(t *testing.T) {
+		info := &templateInfo{TableName: "todo_history", SchemaVersion: 2}
+
+		err := applySchemaChanges(info, version, &Config{LegacyFields: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, []legacyFieldInfo{
+			{Name: "archived", Type: "TypeBool", Builder: "Bool"},
+			{Name: "due", Type: "TypeTime", Builder: "Time"},
+			{Name: "priority", Type: "TypeInt", Builder: "Int"},
+		}, info.LegacyFields)
+	})
+
+	t.Run("migration guidance", func(t *testing.T) {
+		info := &templateInfo{TableName: "todo_history", SchemaVersion: 2}
+
+		err := applySchemaChanges(info, version, &Config{MigrationGuidanceDir: dir})
+		require.NoError(t, err)
+		assert.Empty(t, info.LegacyFields)
+
+		out, err := os.ReadFile(filepath.Join(dir, "todo_history_v2.sql"))
+		require.NoError(t, err)
+
+		assert.Contains(t, string(out), "ALTER TABLE todo_history DROP COLUMN due;")
+		assert.Contains(t, string(out), "ALTER TABLE todo_history DROP COLUMN priority;")
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		info := &templateInfo{TableName: "todo_history", SchemaVersion: 1}
+
+		err := applySchemaChanges(info, &schemaVersion{Version: 1}, &Config{MigrationGuidanceDir: dir})
+		require.NoError(t, err)
+
+		_, err = os.Stat(filepath.Join(dir, "todo_history_v1.sql"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestMigrationGuidance(t *testing.T) {
+	changes := []FieldChange{
+		{Field: "due", Kind: FieldRemoved, OldType: "TypeTime"},
+		{Field: "owner", Kind: FieldAdded, NewType: "TypeUUID"},
+		{Field: "priority", Kind: FieldRetyped, OldType: "TypeInt", NewType: "TypeInt64"},
+	}
+
+	got := migrationGuidance("todo_history", 3, changes, map[string]string{"due": "TypeTime"})
+
+	assert.Contains(t, got, "migration guidance for todo_history schema version 3")
+	assert.Contains(t, got, "due was removed (TypeTime), the column is kept on todo_history as a legacy field")
+	assert.NotContains(t, got, "DROP COLUMN due")
+	assert.Contains(t, got, "owner was added (TypeUUID)")
+	assert.Contains(t, got, "priority was changed from TypeInt to TypeInt64")
+}
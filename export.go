@@ -0,0 +1,57 @@
+package enthistory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// DefaultExportBatchSize is the maximum number of rows of each history table returned by ExportChanges
+	DefaultExportBatchSize = 1000
+)
+
+// ExportRecord is a history row returned by the generated ExportChanges of the client
+type ExportRecord struct {
+	// Entity is the name of the tracked schema (e.g. Todo)
+	Entity string `json:"entity"`
+	// Table is the name of the history table (e.g. todo_history)
+	Table string `json:"table"`
+	// Row is the history row (e.g. *ent.TodoHistory)
+	Row any `json:"row"`
+}
+
+// Watermark is the position of an export in each history table, the cursor (see EncodeCursor) of the last row
+// exported from each table by name, so loaders only keep a single opaque watermark across the history tables
+type Watermark map[string]string
+
+// ParseWatermark parses the watermark returned by ExportChanges, all history is exported from an empty watermark
+func ParseWatermark(value string) (Watermark, error) {
+	w := Watermark{}
+	if value == "" {
+		return w, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWatermark, err)
+	}
+
+	if err := json.Unmarshal(b, &w); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWatermark, err)
+	}
+
+	return w, nil
+}
+
+// String returns the opaque watermark, which is URL safe
+func (w Watermark) String() string {
+	if len(w) == 0 {
+		return ""
+	}
+
+	// a map of strings is always marshaled
+	b, _ := json.Marshal(w)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,30 @@
+package enthistory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatermark(t *testing.T) {
+	w, err := ParseWatermark("")
+	require.NoError(t, err)
+	assert.Empty(t, w)
+	assert.Equal(t, "", w.String())
+
+	cursor, err := EncodeCursor(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), 7)
+	require.NoError(t, err)
+
+	w["todo_history"] = cursor
+
+	got, err := ParseWatermark(w.String())
+	require.NoError(t, err)
+	assert.Equal(t, w, got)
+
+	for _, invalid := range []string{"not a watermark!", "bm90IGpzb24"} {
+		_, err = ParseWatermark(invalid)
+		require.ErrorIs(t, err, ErrInvalidWatermark)
+	}
+}
@@ -0,0 +1,38 @@
+package enthistory
+
+import (
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+)
+
+// FieldChanged returns a predicate matching the history rows of the ref changing the value of the column, compared
+// to the previous history row of the ref by history time and id, the first history row of the ref sets the value;
+// the rows are compared in SQL using the LAG window function, available on Postgres, MySQL 8.0+, and SQLite 3.25+.
+// The column is used as is, so it must be validated (e.g. using ValidColumn of the history package)
+func FieldChanged(ref any, column string) func(*sql.Selector) {
+	return func(s *sql.Selector) {
+		s.Where(sql.P(func(b *sql.Builder) {
+			window := "OVER (ORDER BY " + b.Quote("history_time") + ", " + b.Quote("id") + ")"
+
+			b.Ident(s.C("id")).WriteString(" IN (SELECT ").Ident("id").WriteString(" FROM (")
+			b.WriteString("SELECT ").Ident("id").WriteString(", ").Ident(column).WriteString(" AS field_value, ")
+			b.WriteString("LAG(").Ident(column).WriteString(") " + window + " AS prev_value, ")
+			b.WriteString("ROW_NUMBER() " + window + " AS row_num ")
+			b.WriteString("FROM ").Ident(s.TableName()).WriteString(" WHERE ").Ident("ref").WriteString(" = ").Arg(ref)
+			b.WriteString(") changes WHERE row_num = 1 OR " + distinctFrom(b.Dialect(), "field_value", "prev_value") + ")")
+		}))
+	}
+}
+
+// distinctFrom returns the NULL-safe comparison of the expressions for the dialect, which is true when only one
+// of them is NULL
+func distinctFrom(d, x, y string) string {
+	switch d {
+	case dialect.MySQL:
+		return "NOT (" + x + " <=> " + y + ")"
+	case dialect.SQLite:
+		return x + " IS NOT " + y
+	default:
+		return x + " IS DISTINCT FROM " + y
+	}
+}
@@ -0,0 +1,45 @@
+package enthistory
+
+import (
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldChanged(t *testing.T) {
+	tests := []struct {
+		dialect string
+		want    string
+	}{
+		{
+			dialect: dialect.Postgres,
+			want: `SELECT * FROM "todo_history" WHERE "todo_history"."id" IN (SELECT "id" FROM (SELECT "id", "name" AS field_value, ` +
+				`LAG("name") OVER (ORDER BY "history_time", "id") AS prev_value, ROW_NUMBER() OVER (ORDER BY "history_time", "id") AS row_num ` +
+				`FROM "todo_history" WHERE "ref" = $1) changes WHERE row_num = 1 OR field_value IS DISTINCT FROM prev_value)`,
+		},
+		{
+			dialect: dialect.MySQL,
+			want: "SELECT * FROM `todo_history` WHERE `todo_history`.`id` IN (SELECT `id` FROM (SELECT `id`, `name` AS field_value, " +
+				"LAG(`name`) OVER (ORDER BY `history_time`, `id`) AS prev_value, ROW_NUMBER() OVER (ORDER BY `history_time`, `id`) AS row_num " +
+				"FROM `todo_history` WHERE `ref` = ?) changes WHERE row_num = 1 OR NOT (field_value <=> prev_value))",
+		},
+		{
+			dialect: dialect.SQLite,
+			want: "SELECT * FROM `todo_history` WHERE `todo_history`.`id` IN (SELECT `id` FROM (SELECT `id`, `name` AS field_value, " +
+				"LAG(`name`) OVER (ORDER BY `history_time`, `id`) AS prev_value, ROW_NUMBER() OVER (ORDER BY `history_time`, `id`) AS row_num " +
+				"FROM `todo_history` WHERE `ref` = ?) changes WHERE row_num = 1 OR field_value IS NOT prev_value)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			s := sql.Dialect(tt.dialect).Select().From(sql.Table("todo_history"))
+			FieldChanged(1, "name")(s)
+
+			query, args := s.Query()
+			assert.Equal(t, tt.want, query)
+			assert.Equal(t, []any{1}, args)
+		})
+	}
+}
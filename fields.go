@@ -0,0 +1,104 @@
+package enthistory
+
+import (
+	"slices"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+)
+
+const (
+	idFieldName = "id"
+)
+
+// FieldConfig is the configuration used when copying fields from the original schema
+// to the history schema, this is set by the generated history schemas
+type FieldConfig struct {
+	// AllowedAnnotations are the names of the only field annotations that are copied, if empty all
+	// field annotations are copied
+	AllowedAnnotations []string
+	// StrippedAnnotations are the names of the field annotations that are not copied
+	StrippedAnnotations []string
+	// KeepValidators keeps the validators of the fields, by default these are removed
+	KeepValidators bool
+	// KeepDefaults keeps the default values (literal, function, and sql expression) of the fields,
+	// by default these are removed from all fields except the id field
+	KeepDefaults bool
+	// Compressed are the string and bytes fields stored compressed by the name of the compression
+	Compressed map[string]Compression
+}
+
+// copyAnnotation checks if the field annotation should be copied to the history schema
+func (c FieldConfig) copyAnnotation(a schema.Annotation) bool {
+	if len(c.AllowedAnnotations) > 0 && !slices.Contains(c.AllowedAnnotations, a.Name()) {
+		return false
+	}
+
+	return !slices.Contains(c.StrippedAnnotations, a.Name())
+}
+
+// removeDefaults removes the default values from the field descriptor, including the
+// default values and expressions set using the entsql annotation
+func removeDefaults(desc *field.Descriptor) {
+	desc.Default = nil
+	desc.UpdateDefault = nil
+
+	for i, a := range desc.Annotations {
+		switch ant := a.(type) {
+		case entsql.Annotation:
+			ant.Default, ant.DefaultExpr, ant.DefaultExprs = "", "", nil
+			desc.Annotations[i] = ant
+		case *entsql.Annotation:
+			if ant == nil {
+				continue
+			}
+
+			cp := *ant
+			cp.Default, cp.DefaultExpr, cp.DefaultExprs = "", "", nil
+			desc.Annotations[i] = &cp
+		}
+	}
+}
+
+// HistoryFields prepares the fields from the original schema (or its mixins) to be used in the history schema
+// unique constraints are removed, because history tables will contain the same values many times, and
+// validators and defaults are removed unless configured otherwise;
+// all other properties of the fields are kept as is, such as the SchemaType, so the history columns match
+// the dialect specific types of the original columns
+func HistoryFields(fields []ent.Field, config FieldConfig) []ent.Field {
+	historyFields := make([]ent.Field, 0, len(fields))
+
+	for _, f := range fields {
+		desc := f.Descriptor()
+
+		// make sure the fields do not have unique constraints
+		desc.Unique = false
+
+		// make sure the fields do not have validators, historical values may predate the validation rules
+		if !config.KeepValidators {
+			desc.Validators = nil
+		}
+
+		// remove the annotations that should not be copied to the history schema
+		desc.Annotations = slices.DeleteFunc(desc.Annotations, func(a schema.Annotation) bool {
+			return !config.copyAnnotation(a)
+		})
+
+		// history rows copy all values from the original entity, so defaults would only add values
+		// that never existed; the id default is kept because it is used to create the history row id
+		if !config.KeepDefaults && desc.Name != idFieldName {
+			removeDefaults(desc)
+		}
+
+		// compressed fields are compressed when written and decompressed when read using a ValueScanner
+		if compression, ok := config.Compressed[desc.Name]; ok {
+			compressField(desc, compression)
+		}
+
+		historyFields = append(historyFields, f)
+	}
+
+	return historyFields
+}
@@ -0,0 +1,223 @@
+package enthistory
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/entc/load"
+	"entgo.io/ent/schema/field"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeRange is a custom type used to test field.Other fields
+type timeRange struct{}
+
+func (*timeRange) Scan(any) error              { return nil }
+func (timeRange) Value() (driver.Value, error) { return "", nil }
+
+// gqlAnnotation is a test annotation
+type gqlAnnotation struct{}
+
+func (gqlAnnotation) Name() string { return "EntGQL" }
+
+func TestHistoryFieldsSchemaType(t *testing.T) {
+	tests := []struct {
+		name       string
+		field      ent.Field
+		schemaType map[string]string
+	}{
+		{
+			name: "postgres jsonb",
+			field: field.JSON("settings", map[string]any{}).
+				SchemaType(map[string]string{dialect.Postgres: "jsonb"}),
+			schemaType: map[string]string{dialect.Postgres: "jsonb"},
+		},
+		{
+			name: "postgres text array",
+			field: field.Strings("tags").
+				SchemaType(map[string]string{dialect.Postgres: "text[]"}),
+			schemaType: map[string]string{dialect.Postgres: "text[]"},
+		},
+		{
+			name: "postgres tstzrange",
+			field: field.Other("period", &timeRange{}).
+				SchemaType(map[string]string{dialect.Postgres: "tstzrange", dialect.MySQL: "varchar(255)", dialect.SQLite: "text"}),
+			schemaType: map[string]string{dialect.Postgres: "tstzrange", dialect.MySQL: "varchar(255)", dialect.SQLite: "text"},
+		},
+		{
+			name: "mysql json",
+			field: field.JSON("settings", map[string]any{}).
+				SchemaType(map[string]string{dialect.MySQL: "json"}),
+			schemaType: map[string]string{dialect.MySQL: "json"},
+		},
+		{
+			name: "mysql mediumtext",
+			field: field.Text("body").
+				SchemaType(map[string]string{dialect.MySQL: "mediumtext"}),
+			schemaType: map[string]string{dialect.MySQL: "mediumtext"},
+		},
+		{
+			name: "mysql decimal and postgres numeric",
+			field: field.Float("amount").
+				SchemaType(map[string]string{dialect.MySQL: "decimal(6,2)", dialect.Postgres: "numeric"}),
+			schemaType: map[string]string{dialect.MySQL: "decimal(6,2)", dialect.Postgres: "numeric"},
+		},
+		{
+			name:       "no schema type",
+			field:      field.String("name"),
+			schemaType: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HistoryFields([]ent.Field{tt.field}, FieldConfig{AllowedAnnotations: []string{"EntSQL"}})
+			require.Len(t, got, 1)
+
+			// check the descriptor and the loaded field used by entc
+			assert.Equal(t, tt.schemaType, got[0].Descriptor().SchemaType)
+
+			loaded, err := load.NewField(got[0].Descriptor())
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.schemaType, loaded.SchemaType)
+		})
+	}
+}
+
+func TestHistoryFields(t *testing.T) {
+	fields := func() []ent.Field {
+		return []ent.Field{
+			field.String("name").
+				Unique().
+				NotEmpty().
+				Annotations(
+					entsql.Annotation{Size: 10},
+					gqlAnnotation{},
+				),
+			field.Int("age").
+				Positive(),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		config      FieldConfig
+		annotations []string
+	}{
+		{
+			name:        "copy all annotations",
+			config:      FieldConfig{},
+			annotations: []string{"EntSQL", "EntGQL"},
+		},
+		{
+			name: "stripped annotations",
+			config: FieldConfig{
+				StrippedAnnotations: []string{"EntGQL"},
+			},
+			annotations: []string{"EntSQL"},
+		},
+		{
+			name: "allowed annotations",
+			config: FieldConfig{
+				AllowedAnnotations: []string{"EntGQL"},
+			},
+			annotations: []string{"EntGQL"},
+		},
+		{
+			name: "allowed and stripped annotations",
+			config: FieldConfig{
+				AllowedAnnotations:  []string{"EntGQL", "EntSQL"},
+				StrippedAnnotations: []string{"EntGQL"},
+			},
+			annotations: []string{"EntSQL"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HistoryFields(fields(), tt.config)
+			require.Len(t, got, 2)
+
+			for _, f := range got {
+				assert.False(t, f.Descriptor().Unique)
+				assert.Empty(t, f.Descriptor().Validators)
+			}
+
+			// validators are only kept when configured
+			kept := HistoryFields(fields(), FieldConfig{KeepValidators: true})
+			for _, f := range kept {
+				assert.False(t, f.Descriptor().Unique)
+				assert.NotEmpty(t, f.Descriptor().Validators)
+			}
+
+			names := []string{}
+			for _, a := range got[0].Descriptor().Annotations {
+				names = append(names, a.Name())
+			}
+
+			assert.Equal(t, tt.annotations, names)
+		})
+	}
+}
+
+func TestHistoryFieldsDefaults(t *testing.T) {
+	fields := func() []ent.Field {
+		return []ent.Field{
+			field.String("id").
+				DefaultFunc(func() string { return "ulid" }),
+			field.String("name").
+				Default("meow"),
+			field.Time("updated_at").
+				Default(time.Now).
+				UpdateDefault(time.Now),
+			field.String("token").
+				Annotations(
+					entsql.Annotation{DefaultExpr: "gen_random_uuid()"},
+				),
+			field.String("status").
+				Annotations(
+					&entsql.Annotation{Default: "ACTIVE", Size: 10},
+				),
+		}
+	}
+
+	t.Run("remove defaults", func(t *testing.T) {
+		got := HistoryFields(fields(), FieldConfig{})
+		require.Len(t, got, 5)
+
+		// the id default is kept
+		assert.NotNil(t, got[0].Descriptor().Default)
+
+		for _, f := range got[1:] {
+			desc := f.Descriptor()
+
+			assert.Nil(t, desc.Default, desc.Name)
+			assert.Nil(t, desc.UpdateDefault, desc.Name)
+
+			loaded, err := load.NewField(desc)
+			require.NoError(t, err)
+
+			assert.False(t, loaded.Default, desc.Name)
+			assert.False(t, loaded.UpdateDefault, desc.Name)
+		}
+
+		assert.Equal(t, entsql.Annotation{}, got[3].Descriptor().Annotations[0])
+		assert.Equal(t, &entsql.Annotation{Size: 10}, got[4].Descriptor().Annotations[0])
+	})
+
+	t.Run("keep defaults", func(t *testing.T) {
+		got := HistoryFields(fields(), FieldConfig{KeepDefaults: true})
+		require.Len(t, got, 5)
+
+		assert.NotNil(t, got[0].Descriptor().Default)
+		assert.Equal(t, "meow", got[1].Descriptor().Default)
+		assert.NotNil(t, got[2].Descriptor().Default)
+		assert.NotNil(t, got[2].Descriptor().UpdateDefault)
+		assert.Equal(t, entsql.Annotation{DefaultExpr: "gen_random_uuid()"}, got[3].Descriptor().Annotations[0])
+		assert.Equal(t, &entsql.Annotation{Default: "ACTIVE", Size: 10}, got[4].Descriptor().Annotations[0])
+	})
+}
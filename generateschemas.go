@@ -1,17 +1,24 @@
 package enthistory
 
 import (
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
+	"time"
 
+	"entgo.io/ent/dialect"
 	"entgo.io/ent/entc"
 	"entgo.io/ent/entc/gen"
 	"entgo.io/ent/entc/load"
 	"github.com/datumforge/fgax/entfga"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -25,6 +32,13 @@ type templateInfo struct {
 	Schema *load.Schema
 	// IDType is the type of the id field in the schema (e.g. int, string)
 	IDType string
+	// CompositeID are the fields of the composite id of edge schemas, the history schema of these
+	// records the JSON encoded id field values as the ref and uses its own int id
+	CompositeID []string
+	// RefFields are the fields recorded as the ref instead of the id, the ref of the history schema is a string
+	RefFields []string
+	// SkipOps are the operations of the original schema that do not create history
+	SkipOps []string
 	// SchemaPkg is the package of the schema
 	SchemaPkg string
 	// TableName is the name of the history table
@@ -33,20 +47,218 @@ type templateInfo struct {
 	SchemaName string
 	// Query is a boolean that tells the extension to add the entgql query annotations
 	Query bool
+	// Federation is a boolean that tells the extension to add the federation directives to the entgql annotations
+	Federation bool
 	// OriginalTableName is the name of the original schema
 	OriginalTableName string
 	// WithUpdatedBy is a boolean that tells the extension to add the updated_by fields
 	WithUpdatedBy bool
-	// UpdatedByValueType is the type of the updated_by field (e..g int, string)
+	// UpdatedByValueType is the type of the updated_by field (e.g. Int, String, UUID)
 	UpdatedByValueType string
+	// UpdatedByGoType is the go type of the updated_by field set using ValueTypeOf (e.g. ids.UserID), if any
+	UpdatedByGoType string
+	// WithDeletedBy is a boolean that tells the extension to add the deleted_by field
+	WithDeletedBy bool
+	// DeletedByValueType is the type of the deleted_by field (e.g. Int, String, UUID)
+	DeletedByValueType string
+	// DeletedByGoType is the go type of the deleted_by field set using ValueTypeOf (e.g. ids.UserID), if any
+	DeletedByGoType string
+	// ValueTypeImports are the imports of the go types of the updated_by, deleted_by, and additional fields
+	ValueTypeImports []goImport
+	// WithCorrelationID is a boolean that tells the extension to add the correlation_id field and index
+	WithCorrelationID bool
+	// WithAuthzDecision is a boolean that tells the extension to add the authz_subject and authz_relation fields
+	WithAuthzDecision bool
+	// OwnerField is the field holding the owner of the schema used by the owner read rules, if any
+	OwnerField string
+	// OwnerObjectType is the object type of the owner (organization or user) used by the owner read rules
+	OwnerObjectType string
+	// Comment is the database comment of the history table, the comments are added to the table and its columns
+	// when set
+	Comment string
+	// WithRestoredFrom is a boolean that tells the extension to add the restored_from field
+	WithRestoredFrom bool
+	// WithSynthetic is a boolean that tells the extension to add the synthetic field
+	WithSynthetic bool
+	// SchemaVersion is the version of the fields of the original schema, the schema_version field is added when set
+	SchemaVersion int
+	// SchemaHash is the hash of the fields of the original schema at the SchemaVersion
+	SchemaHash string
+	// SchemaGeneratedAt is the unix time the SchemaVersion was generated
+	SchemaGeneratedAt int64
+	// SchemaFields are the ent field types of the fields of the original schema at the SchemaVersion
+	SchemaFields map[string]string
+	// LegacyFields are the fields removed from the original schema which are kept on the history schema
+	LegacyFields []legacyFieldInfo
+	// WithTenantField is a boolean that tells the extension to add the tenant_id field and index
+	WithTenantField bool
+	// AdditionalFields are the fields added using WithAdditionalFields
+	AdditionalFields []additionalFieldInfo
+	// WithHistoryPolicy is a boolean that tells the extension to add the policy denying direct history mutations
+	WithHistoryPolicy bool
+	// WithInheritedPolicy is a boolean that tells the extension to add the policy of the original schema
+	WithInheritedPolicy bool
+	// PolicyTemplate is the template replacing the generated policy, if any
+	PolicyTemplate string
+	// Operations are the custom operations accepted by the operation field
+	Operations []string
+	// HistoryTimeSchemaType is the column type of the history_time field by the name of the dialect constant
+	// (e.g. Postgres), if any
+	HistoryTimeSchemaType map[string]string
 	// WithHistoryTimeIndex is a boolean that tells the extension to add the history_time index
 	WithHistoryTimeIndex bool
+	// HistoryTimeIndexType is the Postgres index method of the history_time index, if any
+	HistoryTimeIndexType string
+	// HistoryTimeIndexName is the name of the history_time index, if any
+	HistoryTimeIndexName string
+	// WithRefHistoryTimeIndex is a boolean that tells the extension to add the composite ref, history_time index
+	WithRefHistoryTimeIndex bool
+	// WithUpdatedByIndex is a boolean that tells the extension to add the updated_by index
+	WithUpdatedByIndex bool
+	// Indexes are the fields of the indexes mirrored from the original schema
+	Indexes [][]string
+	// FieldLimits are the size limits of the fields copied to the history schema
+	FieldLimits []fieldLimitInfo
+	// RedactedFields are the fields annotated using Redact, stored as RedactedValue on the history schema
+	RedactedFields []string
+	// RestrictedFields are the fields annotated using Restrict, hidden from the viewers who cannot see them by the
+	// restrict interceptor
+	RestrictedFields []string
+	// WithSink is a boolean that tells the extension to add the hook writing the history rows to the secondary sink
+	WithSink bool
+	// WithCallbacks is a boolean that tells the extension to add the hook calling the registered callbacks
+	WithCallbacks bool
+	// CompressedFields are the fields stored compressed on the history schema by the name of the compression
+	CompressedFields map[string]string
+	// AllowedFieldAnnotations are the names of the only field annotations kept on the copied fields
+	AllowedFieldAnnotations []string
+	// StrippedFieldAnnotations are the names of the field annotations removed from the copied fields
+	StrippedFieldAnnotations []string
+	// KeepFieldDefaults is a boolean that tells the extension to keep the defaults of the copied fields
+	KeepFieldDefaults bool
+	// KeepFieldValidators is a boolean that tells the extension to keep the validators of the copied fields
+	KeepFieldValidators bool
 	// AuthzPolicy is the authz policy information
 	AuthzPolicy authzPolicyInfo
 	// AddPolicy is a boolean that tells the extension to add the policy to the schema
 	AddPolicy bool
 }
 
+// edgeTemplateInfo holds the information needed to generate the history schema of a many-to-many edge
+// without an edge schema, which records the edges added to and removed from the join table
+type edgeTemplateInfo struct {
+	// Name is the name of the edge history schema
+	Name string
+	// Owner is the name of the schema that owns the edge
+	Owner string
+	// Edge is the name of the edge on the owner schema
+	Edge string
+	// SchemaPkg is the package of the schema
+	SchemaPkg string
+	// TableName is the name of the edge history table
+	TableName string
+	// SchemaName is the name of the schema
+	SchemaName string
+	// Comment is the database comment of the edge history table, the comments are added to the table and its
+	// columns when set
+	Comment string
+	// Columns are the columns of the join table, holding the ids of both ends of the edge
+	Columns []edgeColumn
+	// WithUpdatedBy is a boolean that tells the extension to add the updated_by field
+	WithUpdatedBy bool
+	// UpdatedByValueType is the type of the updated_by field (e.g. Int, String, UUID)
+	UpdatedByValueType string
+	// UpdatedByGoType is the go type of the updated_by field set using ValueTypeOf (e.g. ids.UserID), if any
+	UpdatedByGoType string
+	// ValueTypeImports are the imports of the go types of the updated_by and additional fields
+	ValueTypeImports []goImport
+	// WithTenantField is a boolean that tells the extension to add the tenant_id field and index
+	WithTenantField bool
+	// WithHistoryPolicy is a boolean that tells the extension to add the policy denying direct history mutations
+	WithHistoryPolicy bool
+	// AdditionalFields are the fields added using WithAdditionalFields
+	AdditionalFields []additionalFieldInfo
+	// HistoryTimeSchemaType is the column type of the history_time field by the name of the dialect constant
+	// (e.g. Postgres), if any
+	HistoryTimeSchemaType map[string]string
+}
+
+// additionalFieldInfo is a field added to the history schema using WithAdditionalFields
+type additionalFieldInfo struct {
+	// Name of the field
+	Name string
+	// ValueType is the type of the field (e.g. Int, String, UUID)
+	ValueType string
+	// GoType is the go type of the field set using ValueTypeOf (e.g. ids.UserID), if any
+	GoType string
+}
+
+// legacyFieldInfo is a field removed from the original schema which is kept on the history schema using WithLegacyFields
+type legacyFieldInfo struct {
+	// Name of the field
+	Name string
+	// Type is the ent field type of the field (e.g. TypeString)
+	Type string
+	// Builder is the ent field builder of the field (e.g. String)
+	Builder string
+}
+
+// fieldLimitInfo is the size limit of a field copied to the history schema, set using the FieldLimits annotation
+type fieldLimitInfo struct {
+	// Field is the name of the field
+	Field string
+	// MaxSize is the maximum size of the values in bytes
+	MaxSize int
+	// Strategy is the name of the LimitStrategy constant (e.g. LimitTruncate)
+	Strategy string
+}
+
+// latestViewTemplateInfo holds the information needed to generate the latest history view schema of a history schema
+type latestViewTemplateInfo struct {
+	// SchemaPkg is the package of the schema
+	SchemaPkg string
+	// Name is the name of the view schema
+	Name string
+	// HistoryName is the name of the history schema
+	HistoryName string
+	// ViewName is the name of the view
+	ViewName string
+	// SchemaName is the name of the schema
+	SchemaName string
+	// Query is the query of the view
+	Query string
+	// IDType is the type of the default id field of the history schema (e.g. int, string)
+	IDType string
+}
+
+// historyMetaTemplateInfo holds the information needed to generate the history_meta and history_attempt schemas
+type historyMetaTemplateInfo struct {
+	// SchemaPkg is the package of the schema
+	SchemaPkg string
+	// TableName is the name of the history meta table
+	TableName string
+	// SchemaName is the name of the schema
+	SchemaName string
+}
+
+// auditSummaryTemplateInfo holds the information needed to generate the audit_summary view schema
+type auditSummaryTemplateInfo struct {
+	// SchemaPkg is the package of the schema
+	SchemaPkg string
+	// ViewName is the name of the audit summary view
+	ViewName string
+	// SchemaName is the name of the schema
+	SchemaName string
+}
+
+// edgeColumn is a column of a join table
+type edgeColumn struct {
+	// Name of the column
+	Name string
+	// IDType is the type of the id stored in the column (e.g. int, string)
+	IDType string
+}
+
 // authzPolicyInfo is a struct that holds the object type and id field for the authz policy
 type authzPolicyInfo struct {
 	// Enabled is a boolean that tells the extension to generate the authz policy
@@ -69,45 +281,320 @@ var (
 	historyTableSuffix = "_history"
 )
 
+const (
+	// historyMetaTableName is the name of the table recording the tracked schemas when using WithHistoryMeta
+	historyMetaTableName = "history_meta"
+	// historyAttemptTableName is the name of the table recording the failed mutations when using WithAttemptedChanges
+	historyAttemptTableName = "history_attempt"
+	// schemaHashLength is the length of the hash of the fields of the original schema recorded on the history schema
+	schemaHashLength = 16
+)
+
+// SchemaInfo describes a history schema written by GenerateSchemas
+type SchemaInfo struct {
+	// Name of the history schema (e.g. TodoHistory)
+	Name string
+	// Table is the name of the history table (e.g. todo_history)
+	Table string
+	// Source is the name of the schema the history schema tracks, the owner of the edge for edge history schemas
+	Source string
+	// Path is the absolute path of the file of the history schema
+	Path string
+}
+
+// GeneratedSchemas returns the history schemas, including the edge history schemas, written by the last call to
+// GenerateSchemas ordered by name, so tooling (e.g. migration generators) can use them without parsing the files
+func (h *HistoryExtension) GeneratedSchemas() []SchemaInfo {
+	h.generatedMu.Lock()
+	defer h.generatedMu.Unlock()
+
+	return slices.Clone(h.generated)
+}
+
+// addGeneratedSchema records a history schema written by GenerateSchemas, this is called by the goroutines writing
+// the history schemas
+func (h *HistoryExtension) addGeneratedSchema(info SchemaInfo) {
+	h.generatedMu.Lock()
+	defer h.generatedMu.Unlock()
+
+	h.generated = append(h.generated, info)
+}
+
 // GenerateSchemas generates the history schema for all schemas in the schema path
 // this should be called before the entc.Generate call
 // so the schemas exist at the time of code generation
 func (h *HistoryExtension) GenerateSchemas() error {
+	if h.config.Dialect != "" && !slices.Contains(auditSummaryDialects, h.config.Dialect) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedDialect, h.config.Dialect)
+	}
+
+	if err := validateAuditConfig(h.config.Audit); err != nil {
+		return err
+	}
+
+	if err := validateValueTypes(h.config); err != nil {
+		return err
+	}
+
+	if name := h.config.HistoryTimeIndexConfig.Name; name != "" && strings.Count(name, "%s") != 1 {
+		return fmt.Errorf("%w: %s must hold the history table name as %%s", ErrInvalidIndexName, name)
+	}
+
 	graph, err := entc.LoadGraph(h.config.SchemaPath, &gen.Config{})
 	if err != nil {
 		return fmt.Errorf("%w: failed loading ent graph: %v", ErrFailedToGenerateTemplate, err)
 	}
 
-	// Create history schemas concurrently
-	var wg sync.WaitGroup
+	h.generatedMu.Lock()
+	h.generated = nil
+	h.generatedMu.Unlock()
+
+	// Create history schemas concurrently, the first error (e.g. an invalid annotation) is returned once all are done
+	var g errgroup.Group
+
+	nodes := make(map[string]*gen.Type, len(graph.Nodes))
+	for _, n := range graph.Nodes {
+		nodes[n.Name] = n
+	}
+
+	// the ids of the tracked schemas are validated before generating any history schema
+	if err := validateIDTypes(graph.Schemas, nodes, h.config.OptIn); err != nil {
+		return err
+	}
+
+	// the history schemas generated by a previous run hold the schema version of the original schemas
+	schemas := make(map[string]*load.Schema, len(graph.Schemas))
+	for _, schema := range graph.Schemas {
+		schemas[schema.Name] = schema
+	}
 
 	// loop through all schemas and generate history schema, if needed
 	for _, schema := range graph.Schemas {
-		if shouldGenerate(schema) {
-			wg.Add(1)
+		if shouldGenerate(schema, h.config.OptIn) {
+			previous := schemas[fmt.Sprintf("%vHistory", schema.Name)]
+
+			idType := graph.IDType.String()
+			if n := nodes[schema.Name]; n != nil && n.ID != nil {
+				idType = n.ID.Type.String()
+			}
+
+			g.Go(func() error {
+				return generateHistorySchema(schema, previous, h.config, idType, h.addGeneratedSchema)
+			})
+
+			if !h.config.EdgeHistory {
+				continue
+			}
+
+			// generate the history schemas of the many-to-many edges owned by the schema
+			for _, e := range edgeHistoryEdges(nodes[schema.Name]) {
+				g.Go(func() error {
+					return generateEdgeHistorySchema(e, h.config, h.addGeneratedSchema)
+				})
+			}
+		}
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	slices.SortFunc(h.generated, func(a, b SchemaInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	if h.config.HistoryMeta {
+		if err := generateHistoryMetaSchema(h.config); err != nil {
+			return err
+		}
+	}
+
+	if h.config.AttemptedChanges {
+		if err := generateHistoryAttemptSchema(h.config); err != nil {
+			return err
+		}
+	}
 
-			go generateHistorySchema(schema, h.config, graph.IDType.String(), &wg)
+	if h.config.TriggersDir != "" {
+		if err := generateHistoryTriggers(h.config, graph.Schemas, nodes); err != nil {
+			return err
 		}
 	}
 
-	wg.Wait()
+	if h.config.AuditSummary {
+		return generateAuditSummarySchema(h.config, graph.Schemas)
+	}
 
 	return nil
 }
 
-// shouldGenerate checks if the history schema should be generated for the given schema
-func shouldGenerate(schema *load.Schema) bool {
+// generateHistoryTriggers writes the DDL of the triggers capturing the out-of-band writes to the tables of the tracked
+// schemas, for the dialect set by WithDialect or for each dialect supporting triggers
+func generateHistoryTriggers(config *Config, schemas []*load.Schema, nodes map[string]*gen.Type) error {
+	triggers, err := getHistoryTriggers(config, schemas, nodes)
+	if err != nil {
+		return err
+	}
+
+	dialects := historyTriggerDialects
+	if config.Dialect != "" {
+		dialects = []string{config.Dialect}
+	}
+
+	return writeHistoryTriggers(config.TriggersDir, config.TriggersAppUser, triggers, dialects)
+}
+
+// generateHistoryMetaSchema creates the history_meta schema recording the tracked schemas
+func generateHistoryMetaSchema(config *Config) error {
+	pkg, err := getPkgFromSchemaPath(config.SchemaPath)
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(config.SchemaPath)
+	if err != nil {
+		return err
+	}
+
+	info := historyMetaTemplateInfo{
+		SchemaPkg:  pkg,
+		TableName:  historyMetaTableName,
+		SchemaName: config.SchemaName,
+	}
+
+	return parseHistoryMetaSchemaTemplate(info, filepath.Join(abs, historyMetaTableName+".go"))
+}
+
+// generateHistoryAttemptSchema creates the history_attempt schema recording the failed mutations
+func generateHistoryAttemptSchema(config *Config) error {
+	pkg, err := getPkgFromSchemaPath(config.SchemaPath)
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(config.SchemaPath)
+	if err != nil {
+		return err
+	}
+
+	info := historyMetaTemplateInfo{
+		SchemaPkg:  pkg,
+		TableName:  historyAttemptTableName,
+		SchemaName: config.SchemaName,
+	}
+
+	return parseHistoryAttemptSchemaTemplate(info, filepath.Join(abs, historyAttemptTableName+".go"))
+}
+
+// generateAuditSummarySchema creates the audit_summary view schema aggregating the changes of the history tables
+// of the tracked schemas, and writes the DDL of the audit summary when using a directory
+func generateAuditSummarySchema(config *Config, schemas []*load.Schema) error {
+	pkg, err := getPkgFromSchemaPath(config.SchemaPath)
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(config.SchemaPath)
+	if err != nil {
+		return err
+	}
+
+	info := auditSummaryTemplateInfo{
+		SchemaPkg:  pkg,
+		ViewName:   auditSummaryName,
+		SchemaName: config.SchemaName,
+	}
+
+	if err := parseAuditSummarySchemaTemplate(info, filepath.Join(abs, auditSummaryName+".go")); err != nil {
+		return err
+	}
+
+	if config.AuditSummaryDir == "" {
+		return nil
+	}
+
+	dialects := auditSummaryDialects
+	if config.Dialect != "" {
+		dialects = []string{config.Dialect}
+	}
+
+	return writeAuditSummary(config.AuditSummaryDir, config.SchemaName, getAuditSummaryTables(config, schemas), dialects)
+}
+
+// getAuditSummaryTables returns the history tables of the tracked schemas aggregated by the audit summary, the
+// updated_by and tenant_id fields are either added to the history schemas or copied from the original schemas
+func getAuditSummaryTables(config *Config, schemas []*load.Schema) []AuditSummaryTable {
+	var tables []AuditSummaryTable
+
+	for _, schema := range schemas {
+		if !shouldGenerate(schema, config.OptIn) {
+			continue
+		}
+
+		hasField := func(name string) bool {
+			return slices.ContainsFunc(schema.Fields, func(f *load.Field) bool {
+				return f.Name == name
+			})
+		}
+
+		tables = append(tables, AuditSummaryTable{
+			Entity: schema.Name,
+			Table:  getHistoryTableName(schema),
+			Actor:  (config.UpdatedBy != nil && config.UpdatedBy.key != "") || hasField("updated_by"),
+			Tenant: config.TenantKey != "" || hasField(tenantFieldName),
+		})
+	}
+
+	return tables
+}
+
+// validateIDTypes returns an error listing the tracked schemas, and their id types, with ids that cannot be recorded
+// as the ref of their history rows, only int and string ids are supported; edge schemas with a composite id and
+// schemas with the RefFields history annotation record a string ref, so their ids are not validated
+func validateIDTypes(schemas []*load.Schema, nodes map[string]*gen.Type, optIn bool) error {
+	var invalid []string
+
+	for _, schema := range schemas {
+		if !shouldGenerate(schema, optIn) {
+			continue
+		}
+
+		n, ok := nodes[schema.Name]
+		if !ok || !n.HasOneFieldID() {
+			continue
+		}
+
+		if annotations, err := jsonUnmarshalAnnotations(schema.Annotations[annotationName]); err == nil &&
+			len(annotations.RefFields) > 0 {
+			continue
+		}
+
+		if t := n.ID.Type.String(); t != "int" && t != "string" {
+			invalid = append(invalid, fmt.Sprintf("%s (%s)", schema.Name, t))
+		}
+	}
+
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnsupportedIDType, strings.Join(invalid, ", "))
+}
+
+// shouldGenerate checks if the history schema should be generated for the given schema, when opting in
+// only the schemas marked for tracking by the history annotation, or Mixin, are generated
+func shouldGenerate(schema *load.Schema, optIn bool) bool {
 	// check if schema has history annotation
 	// history annotation is used to exclude schemas from history tracking
 	historyAnnotation, ok := schema.Annotations[annotationName]
 	if !ok {
-		return true
+		return !optIn
 	}
 
 	// unmarshal the history annotation
 	annotations, err := jsonUnmarshalAnnotations(historyAnnotation)
 	if err != nil {
-		return true
+		return !optIn
 	}
 
 	// check if schema should be excluded from history tracking
@@ -119,6 +606,9 @@ func shouldGenerate(schema *load.Schema) bool {
 	case annotations.IsHistory:
 		// if schema is a history schema, do not generate history schema
 		return false
+	case optIn:
+		// if opting in, only generate history schema for tracked schemas
+		return annotations.Track
 	default:
 		return true
 	}
@@ -132,29 +622,38 @@ func getTemplateInfo(schema *load.Schema, config *Config, idType string) (*templ
 	}
 
 	info := &templateInfo{
-		TableName:         fmt.Sprintf("%v%s", getSchemaTableName(schema), historyTableSuffix),
+		TableName:         getHistoryTableName(schema),
 		OriginalTableName: schema.Name,
 		SchemaPkg:         pkg,
-		SchemaName:        config.SchemaName,
+		SchemaName:        getHistorySchemaName(schema.Annotations, config),
 		Query:             config.Query,
+		Federation:        config.Query && config.GQLFederation,
 		AuthzPolicy: authzPolicyInfo{
 			Enabled:         config.Auth.Enabled,
 			AllowedRelation: config.Auth.AllowedRelation,
 		},
-		AddPolicy: !config.Auth.FirstRun,
+		AddPolicy:                !config.Auth.FirstRun,
+		AllowedFieldAnnotations:  config.AllowedFieldAnnotations,
+		StrippedFieldAnnotations: config.StrippedFieldAnnotations,
+		KeepFieldDefaults:        config.FieldDefaults,
+		KeepFieldValidators:      config.FieldValidators,
+	}
+
+	// the allowed relation of the authz policy can be overridden per schema using the history annotation
+	annotations, err := jsonUnmarshalAnnotations(schema.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	if annotations.AllowedRelation != "" {
+		info.AuthzPolicy.AllowedRelation = annotations.AllowedRelation
 	}
 
 	// setup history time and updated by based on config settings
 	// add updated_by fields
 	if config.UpdatedBy != nil {
-		valueType := config.UpdatedBy.valueType
-
-		switch valueType {
-		case ValueTypeInt:
-			info.UpdatedByValueType = "Int"
-		case ValueTypeString:
-			info.UpdatedByValueType = "String"
-		}
+		info.UpdatedByValueType = valueTypeField(config.UpdatedBy.valueType)
+		info.UpdatedByGoType = valueTypeGoType(config.UpdatedBy.valueType)
 
 		// if updated_by is enabled, add the updated_by fields
 		// do not include if the key is not set, this should then
@@ -164,34 +663,145 @@ func getTemplateInfo(schema *load.Schema, config *Config, idType string) (*templ
 		}
 	}
 
+	// add deleted_by field
+	if config.DeletedBy != nil && config.DeletedBy.key != "" {
+		info.WithDeletedBy = true
+		info.DeletedByValueType = valueTypeField(config.DeletedBy.valueType)
+		info.DeletedByGoType = valueTypeGoType(config.DeletedBy.valueType)
+	}
+
+	info.WithCorrelationID = config.CorrelationID
+	info.WithAuthzDecision = config.AuthzDecision
+
+	if config.OwnerReadRules {
+		info.OwnerField, info.OwnerObjectType = getOwnerField(schema, config.Auth.OwnerFields)
+	}
+
+	if config.Comments {
+		info.Comment = historyTableComment(getSchemaTableName(schema))
+	}
+	info.WithHistoryPolicy = config.HistoryPolicy
+	info.WithSink = config.Sink
+	info.WithCallbacks = config.Callbacks
+	info.WithInheritedPolicy = config.InheritedPolicy && len(schema.Policy) > 0
+	info.WithRestoredFrom = config.RestoredFrom
+	info.WithSynthetic = config.HistoryRepair
+
+	// the tenant_id field is copied from the original schema when it already exists,
+	// the history queries are constrained to the tenant in both cases
+	info.WithTenantField = config.TenantKey != "" && !slices.ContainsFunc(schema.Fields, func(f *load.Field) bool {
+		return f.Name == tenantFieldName
+	})
+
+	info.AdditionalFields = getAdditionalFields(config.AdditionalFields, schema.Fields)
+	info.ValueTypeImports = configValueTypeImports(config)
+
+	if config.PolicyTemplate != nil {
+		policyTemplate, err := fs.ReadFile(config.PolicyTemplate.fsys, config.PolicyTemplate.path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read policy template: %v", ErrFailedToGenerateTemplate, err)
+		}
+
+		info.PolicyTemplate = string(policyTemplate)
+	}
+
+	if ops := customOpTypes(config.Operations); len(ops) > 0 {
+		info.Operations = ops
+	}
+
+	info.HistoryTimeSchemaType, err = getHistoryTimeSchemaType(config)
+	if err != nil {
+		return nil, err
+	}
+
 	info.WithHistoryTimeIndex = config.HistoryTimeIndex
+	info.HistoryTimeIndexType = config.HistoryTimeIndexConfig.Type
+
+	if name := config.HistoryTimeIndexConfig.Name; name != "" {
+		info.HistoryTimeIndexName = fmt.Sprintf(name, info.TableName)
+	}
+	info.WithRefHistoryTimeIndex = config.RefHistoryTimeIndex
+
+	// only index updated_by when the field is going to exist on the history schema
+	info.WithUpdatedByIndex = config.UpdatedByIndex && config.IncludeUpdatedBy
+
+	// add any indexes from the original schema that should be mirrored
+	info.Indexes, err = getMirroredIndexes(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	info.FieldLimits, err = getFieldLimits(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	info.RedactedFields, err = getRedactedFields(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	info.RestrictedFields = getRestrictedFields(schema)
+
+	info.CompressedFields, err = getCompressedFields(schema)
+	if err != nil {
+		return nil, err
+	}
 
 	// determine id type used in schema
 	info.IDType = getIDType(idType)
 
+	// edge schemas are identified by their composite id instead of an id field
+	info.CompositeID = getCompositeID(schema)
+
+	info.RefFields, err = getRefFields(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	info.SkipOps, err = getSkipOps(schema)
+	if err != nil {
+		return nil, err
+	}
+
 	return info, nil
 }
 
-// generateHistorySchema creates the history schema based on the original schema
-func generateHistorySchema(schema *load.Schema, config *Config, idType string, wg *sync.WaitGroup) {
-	defer wg.Done()
-
+// generateHistorySchema creates the history schema based on the original schema, and the history
+// schema generated by a previous run, if any, and records it using record
+func generateHistorySchema(schema, previous *load.Schema, config *Config, idType string, record func(SchemaInfo)) error {
 	info, err := getTemplateInfo(schema, config, idType)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	if config.SchemaVersion {
+		version, err := getSchemaVersion(schema, previous, time.Now())
+		if err != nil {
+			return err
+		}
+
+		info.SchemaVersion = version.Version
+		info.SchemaHash = version.Hash
+		info.SchemaGeneratedAt = version.GeneratedAt
+		info.SchemaFields = version.Fields
+
+		if err := applySchemaChanges(info, version, config); err != nil {
+			return err
+		}
 	}
 
 	// Load new base history schema
 	historySchema, err := loadHistorySchema(info.IDType)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	// if authz policy is enabled, add the object type and id field to the history schema
 	if info.AuthzPolicy.Enabled {
-		err := info.getAuthzPolicyInfo(schema)
+		err := info.getAuthzPolicyInfo(schema, config.Auth.OwnerFields)
 		if err != nil {
-			panic(err)
+			return err
 		}
 	}
 
@@ -203,13 +813,320 @@ func generateHistorySchema(schema *load.Schema, config *Config, idType string, w
 	// Get path to write new history schema file
 	path, err := getHistorySchemaPath(schema, config)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	// the authz policy uses the code generated for the history schema, so it is only added once
+	// the history schema exists from a previous run
+	if !historySchemaExists(path) {
+		info.AddPolicy = false
 	}
 
 	// execute schemaTemplate at the history schema path
 	if err = parseSchemaTemplate(*info, path); err != nil {
-		panic(err)
+		return err
+	}
+
+	record(SchemaInfo{
+		Name:   historySchema.Name,
+		Table:  info.TableName,
+		Source: schema.Name,
+		Path:   path,
+	})
+
+	if config.LatestHistoryViews {
+		if err := generateLatestHistoryView(info, config, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateLatestHistoryView creates the latest history view schema of the history schema next to the history schema
+// path (e.g. todo_history_latest.go), and writes the DDL of the view when using a directory
+func generateLatestHistoryView(info *templateInfo, config *Config, historyPath string) error {
+	view := latestViewTemplateInfo{
+		SchemaPkg:   info.SchemaPkg,
+		Name:        info.Schema.Name + "Latest",
+		HistoryName: info.Schema.Name,
+		ViewName:    info.TableName + latestViewSuffix,
+		SchemaName:  info.SchemaName,
+		Query:       latestHistoryQuery(info.TableName),
+		IDType:      info.IDType,
+	}
+
+	path := strings.TrimSuffix(historyPath, ".go") + latestViewSuffix + ".go"
+
+	if err := parseLatestViewSchemaTemplate(view, path); err != nil {
+		return err
+	}
+
+	if config.LatestHistoryViewsDir == "" {
+		return nil
+	}
+
+	return writeLatestHistoryView(config.LatestHistoryViewsDir, info.TableName, info.SchemaName)
+}
+
+// schemaVersion is the version of the fields of the original schema recorded on the history schema
+type schemaVersion struct {
+	// Version of the fields, starting at 1
+	Version int
+	// Hash of the fields at the version
+	Hash string
+	// GeneratedAt is the unix time the version was generated
+	GeneratedAt int64
+	// Fields are the ent field types of the fields at the version
+	Fields map[string]string
+	// Changes are the changes made to the fields since the previous version, when the previous fields are known
+	Changes []FieldChange
+	// Legacy are the legacy fields of the previous version
+	Legacy map[string]string
+}
+
+// getSchemaVersion returns the version and hash of the fields of the original schema, the version of the previous
+// history schema is kept when the fields have not changed, and bumped when they have, with the given time as the
+// generation time of new versions
+func getSchemaVersion(schema, previous *load.Schema, now time.Time) (*schemaVersion, error) {
+	hash, err := schemaFieldsHash(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &schemaVersion{
+		Version:     1,
+		Hash:        hash,
+		GeneratedAt: now.Unix(),
+		Fields:      schemaFields(schema),
 	}
+
+	if previous == nil {
+		return version, nil
+	}
+
+	annotations, err := jsonUnmarshalAnnotations(previous.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	version.Legacy = annotations.LegacyFields
+
+	switch {
+	case annotations.SchemaVersion == 0:
+	case annotations.SchemaHash != hash:
+		version.Version = annotations.SchemaVersion + 1
+
+		// the changes are only known when the previous history schema recorded its fields
+		if annotations.SchemaFields != nil {
+			version.Changes = diffSchemaFields(annotations.SchemaFields, version.Fields)
+		}
+	default:
+		version.Version = annotations.SchemaVersion
+
+		// the generation time is kept when the version has not changed, so the history schema is unchanged
+		if annotations.SchemaGeneratedAt != 0 {
+			version.GeneratedAt = annotations.SchemaGeneratedAt
+		}
+	}
+
+	return version, nil
+}
+
+// schemaFieldsHash returns the hash of the shape (name, type, optional, nillable, and enum values) of the fields
+// of the schema, including the fields of its mixins
+func schemaFieldsHash(schema *load.Schema) (string, error) {
+	type fieldShape struct {
+		Name     string
+		Type     string
+		Optional bool
+		Nillable bool
+		Enums    []string
+	}
+
+	shapes := make([]fieldShape, 0, len(schema.Fields))
+
+	for _, f := range schema.Fields {
+		shape := fieldShape{
+			Name:     f.Name,
+			Optional: f.Optional,
+			Nillable: f.Nillable,
+		}
+
+		if f.Info != nil {
+			shape.Type = f.Info.String()
+		}
+
+		for _, e := range f.Enums {
+			shape.Enums = append(shape.Enums, e.V)
+		}
+
+		shapes = append(shapes, shape)
+	}
+
+	out, err := json.Marshal(shapes)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(out)
+
+	return hex.EncodeToString(sum[:])[:schemaHashLength], nil
+}
+
+// edgeHistoryEdges returns the many-to-many edges owned by the type that are stored in a join table
+// without an edge schema, the history of these edges is recorded in their own edge history schema
+func edgeHistoryEdges(n *gen.Type) []*gen.Edge {
+	if n == nil {
+		return nil
+	}
+
+	var edges []*gen.Edge
+
+	for _, e := range n.Edges {
+		if e.M2M() && e.Through == nil && !e.IsInverse() && len(e.Rel.Columns) == 2 {
+			edges = append(edges, e)
+		}
+	}
+
+	return edges
+}
+
+// getEdgeTemplateInfo returns the template info for the history schema of the many-to-many edge based on the config
+func getEdgeTemplateInfo(e *gen.Edge, config *Config) (*edgeTemplateInfo, error) {
+	pkg, err := getPkgFromSchemaPath(config.SchemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &edgeTemplateInfo{
+		Name:       edgeHistoryName(e.Rel.Table),
+		Owner:      e.Owner.Name,
+		Edge:       e.Name,
+		SchemaPkg:  pkg,
+		TableName:  fmt.Sprintf("%s%s", e.Rel.Table, historyTableSuffix),
+		SchemaName: getHistorySchemaName(e.Owner.Annotations, config),
+		Columns: []edgeColumn{
+			{Name: e.Rel.Columns[0], IDType: getIDType(e.Owner.ID.Type.String())},
+			{Name: e.Rel.Columns[1], IDType: getIDType(e.Type.ID.Type.String())},
+		},
+	}
+
+	if config.UpdatedBy != nil && config.UpdatedBy.key != "" {
+		info.WithUpdatedBy = true
+		info.UpdatedByValueType = valueTypeField(config.UpdatedBy.valueType)
+		info.UpdatedByGoType = valueTypeGoType(config.UpdatedBy.valueType)
+	}
+
+	if config.Comments {
+		info.Comment = historyTableComment(e.Rel.Table)
+	}
+
+	info.WithTenantField = config.TenantKey != ""
+	info.WithHistoryPolicy = config.HistoryPolicy
+	info.AdditionalFields = getAdditionalFields(config.AdditionalFields, nil)
+	info.ValueTypeImports = configValueTypeImports(config)
+
+	info.HistoryTimeSchemaType, err = getHistoryTimeSchemaType(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// historyTableComment returns the database comment of the history table of the table
+func historyTableComment(table string) string {
+	return fmt.Sprintf("History of %s table, generated by enthistory", table)
+}
+
+// getHistoryTimeSchemaType returns the column type of the history_time field on postgres and mysql, by the name of
+// the dialect constant, based on the precision and time zone settings and the dialect, if set; nil is returned when
+// neither is set, or for sqlite, so the default column type of the database is used
+func getHistoryTimeSchemaType(config *Config) (map[string]string, error) {
+	precision := config.HistoryTimePrecision
+	if precision < 0 || precision > 6 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidHistoryTimePrecision, precision)
+	}
+
+	var schemaType map[string]string
+
+	if precision > 0 || config.HistoryTimeWithoutTimeZone {
+		postgres, mysql := "timestamptz", "timestamp"
+		if config.HistoryTimeWithoutTimeZone {
+			postgres, mysql = "timestamp", "datetime"
+		}
+
+		if precision > 0 {
+			postgres = fmt.Sprintf("%s(%d)", postgres, precision)
+			mysql = fmt.Sprintf("%s(%d)", mysql, precision)
+		}
+
+		schemaType = map[string]string{
+			"Postgres": postgres,
+			"MySQL":    mysql,
+		}
+
+		// only the type of the dialect is kept when generating for a dialect, sqlite uses the default type
+		switch config.Dialect {
+		case dialect.Postgres:
+			delete(schemaType, "MySQL")
+		case dialect.MySQL:
+			delete(schemaType, "Postgres")
+		case dialect.SQLite:
+			schemaType = nil
+		}
+	}
+
+	return schemaType, nil
+}
+
+// getAdditionalFields returns the additional fields added to the history schema, leaving out the fields
+// that already exist on the original schema, which are copied instead
+func getAdditionalFields(additionalFields []AdditionalField, fields []*load.Field) []additionalFieldInfo {
+	var infos []additionalFieldInfo
+
+	for _, af := range additionalFields {
+		if slices.ContainsFunc(fields, func(f *load.Field) bool { return f.Name == af.Name }) {
+			continue
+		}
+
+		infos = append(infos, additionalFieldInfo{
+			Name:      af.Name,
+			ValueType: valueTypeField(af.ValueType),
+			GoType:    valueTypeGoType(af.ValueType),
+		})
+	}
+
+	return infos
+}
+
+// generateEdgeHistorySchema creates the history schema of the many-to-many edge, and records it using record
+func generateEdgeHistorySchema(e *gen.Edge, config *Config, record func(SchemaInfo)) error {
+	info, err := getEdgeTemplateInfo(e, config)
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(config.SchemaPath)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("%s/%s.go", abs, info.TableName)
+
+	if err = parseEdgeSchemaTemplate(*info, path); err != nil {
+		return err
+	}
+
+	record(SchemaInfo{
+		Name:   info.Name,
+		Table:  info.TableName,
+		Source: info.Owner,
+		Path:   path,
+	})
+
+	return nil
 }
 
 // getHistorySchemaPath returns the path of the history schemas
@@ -224,9 +1141,17 @@ func getHistorySchemaPath(schema *load.Schema, config *Config) (string, error) {
 	return path, nil
 }
 
+// historySchemaExists checks if the history schema file exists at the path, which is the case when
+// the history schema was generated by a previous run
+func historySchemaExists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
+
 // getAuthzPolicyInfo sets the object type and id field for the authz policy
 // based on the original schema annotations
-func (t *templateInfo) getAuthzPolicyInfo(schema *load.Schema) error {
+func (t *templateInfo) getAuthzPolicyInfo(schema *load.Schema, ownerFields map[string]string) error {
 	// get entfga annotation, if its not found the history schema should not have an authz policy
 	annotations, err := getAuthzAnnotation(schema)
 	if err != nil {
@@ -255,43 +1180,49 @@ func (t *templateInfo) getAuthzPolicyInfo(schema *load.Schema) error {
 		t.AuthzPolicy.IDField = annotations.IDField
 	}
 
-	t.AuthzPolicy.OrgOwned = isOrgOwned(schema)
+	ownerType := getOwnerObjectType(schema, ownerFields)
+
+	t.AuthzPolicy.OrgOwned = ownerType == OwnerObjectTypeOrganization
 
-	t.AuthzPolicy.UserOwned = isUserOwned(schema)
+	t.AuthzPolicy.UserOwned = ownerType == OwnerObjectTypeUser
 
 	return nil
 }
 
-// isOrgOwned checks if the schema is org owned and returns true if it is
-func isOrgOwned(schema *load.Schema) bool {
-	for _, f := range schema.Fields {
-		// all org owned objects are mixed in
-		if !f.Position.MixedIn {
-			continue
-		}
+// getOwnerObjectType returns the object type of the owner of the schema (organization or user) based on the
+// configured owner fields, or the comment of the mixed in owner_id field when none of the owner fields exist
+func getOwnerObjectType(schema *load.Schema, ownerFields map[string]string) string {
+	_, objectType := getOwnerField(schema, ownerFields)
 
-		if f.Name == "owner_id" {
-			return strings.Contains(f.Comment, "organization")
+	return objectType
+}
+
+// getOwnerField returns the field holding the owner of the schema and the object type of the owner (organization or
+// user), see getOwnerObjectType, both are empty when the schema is not owned
+func getOwnerField(schema *load.Schema, ownerFields map[string]string) (string, string) {
+	for _, f := range schema.Fields {
+		if objectType, ok := ownerFields[f.Name]; ok {
+			return f.Name, objectType
 		}
 	}
 
-	return false
-}
-
-// isUserOwned checks if the schema is user owned and returns true if it is
-func isUserOwned(schema *load.Schema) bool {
 	for _, f := range schema.Fields {
-		// all org owned objects are mixed in
-		if !f.Position.MixedIn {
+		// all owned objects are mixed in
+		if f.Position == nil || !f.Position.MixedIn || f.Name != defaultOwnerFieldName {
 			continue
 		}
 
-		if f.Name == "owner_id" {
-			return strings.Contains(f.Comment, "user")
+		switch {
+		case strings.Contains(f.Comment, OwnerObjectTypeOrganization):
+			return f.Name, OwnerObjectTypeOrganization
+		case strings.Contains(f.Comment, OwnerObjectTypeUser):
+			return f.Name, OwnerObjectTypeUser
+		default:
+			return "", ""
 		}
 	}
 
-	return false
+	return "", ""
 }
 
 // getAuthzAnnotation looks for the entfga Authz annotation in the schema
@@ -1,11 +1,19 @@
 package enthistory
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"entgo.io/ent/dialect"
 	"entgo.io/ent/entc"
 	"entgo.io/ent/entc/gen"
 	"entgo.io/ent/entc/load"
+	"entgo.io/ent/schema/field"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -55,13 +63,47 @@ func TestShouldGenerate(t *testing.T) {
 				t.Fatalf("schema %s not found", tt.schemaName)
 			}
 
-			got := shouldGenerate(schema)
+			got := shouldGenerate(schema, false)
 
 			assert.Equal(t, tt.expectedValue, got)
 		})
 	}
 }
 
+func TestShouldGenerateOptIn(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]any
+		want        bool
+	}{
+		{
+			name: "no annotations",
+			want: false,
+		},
+		{
+			name:        "tracked",
+			annotations: map[string]any{annotationName: map[string]any{"track": true}},
+			want:        true,
+		},
+		{
+			name:        "tracked but excluded",
+			annotations: map[string]any{annotationName: map[string]any{"track": true, "exclude": true}},
+			want:        false,
+		},
+		{
+			name:        "not tracked",
+			annotations: map[string]any{annotationName: map[string]any{"allowedRelation": "admin"}},
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shouldGenerate(&load.Schema{Name: "Todo", Annotations: tt.annotations}, true)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestGetAuthzPolicyInfo(t *testing.T) {
 	graph, err := entc.LoadGraph("./testdata/schema", &gen.Config{})
 	require.NoError(t, err)
@@ -118,10 +160,745 @@ func TestGetAuthzPolicyInfo(t *testing.T) {
 				AuthzPolicy: authzPolicyInfo{},
 			}
 
-			err := info.getAuthzPolicyInfo(schema)
+			err := info.getAuthzPolicyInfo(schema, nil)
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.expectedValue, info.AuthzPolicy)
 		})
 	}
 }
+
+func TestGetOwnerObjectType(t *testing.T) {
+	mixedIn := &load.Position{MixedIn: true}
+
+	tests := []struct {
+		name        string
+		fields      []*load.Field
+		ownerFields map[string]string
+		want        string
+		wantField   string
+	}{
+		{
+			name:      "org owned",
+			fields:    []*load.Field{{Name: "owner_id", Comment: "the organization id that owns the object", Position: mixedIn}},
+			want:      OwnerObjectTypeOrganization,
+			wantField: "owner_id",
+		},
+		{
+			name:      "user owned",
+			fields:    []*load.Field{{Name: "owner_id", Comment: "the user id that owns the object", Position: mixedIn}},
+			want:      OwnerObjectTypeUser,
+			wantField: "owner_id",
+		},
+		{
+			name:   "owner field not mixed in",
+			fields: []*load.Field{{Name: "owner_id", Comment: "the organization id that owns the object", Position: &load.Position{}}},
+			want:   "",
+		},
+		{
+			name:        "configured owner field",
+			fields:      []*load.Field{{Name: "account_id", Position: &load.Position{}}},
+			ownerFields: map[string]string{"account_id": OwnerObjectTypeOrganization},
+			want:        OwnerObjectTypeOrganization,
+			wantField:   "account_id",
+		},
+		{
+			name:        "configured owner field not on schema",
+			fields:      []*load.Field{{Name: "name", Position: &load.Position{}}},
+			ownerFields: map[string]string{"account_id": OwnerObjectTypeOrganization},
+			want:        "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getOwnerObjectType(&load.Schema{Name: "Todo", Fields: tt.fields}, tt.ownerFields)
+			assert.Equal(t, tt.want, got)
+
+			field, objectType := getOwnerField(&load.Schema{Name: "Todo", Fields: tt.fields}, tt.ownerFields)
+			assert.Equal(t, tt.wantField, field)
+			assert.Equal(t, tt.want, objectType)
+		})
+	}
+}
+
+func TestGetTemplateInfo(t *testing.T) {
+	schema := &load.Schema{
+		Name: "Todo",
+	}
+
+	tests := []struct {
+		name   string
+		config *Config
+		want   *templateInfo
+	}{
+		{
+			name: "defaults",
+			config: &Config{
+				SchemaPath: "./schema",
+			},
+			want: &templateInfo{
+				TableName:         "todo_history",
+				OriginalTableName: "Todo",
+				SchemaPkg:         "schema",
+				IDType:            "string",
+				AddPolicy:         true,
+			},
+		},
+		{
+			name: "indexes",
+			config: &Config{
+				SchemaPath:          "./schema",
+				HistoryTimeIndex:    true,
+				RefHistoryTimeIndex: true,
+				UpdatedByIndex:      true,
+				IncludeUpdatedBy:    true,
+				UpdatedBy: &UpdatedBy{
+					key:       "userID",
+					valueType: ValueTypeString,
+				},
+			},
+			want: &templateInfo{
+				TableName:               "todo_history",
+				OriginalTableName:       "Todo",
+				SchemaPkg:               "schema",
+				IDType:                  "string",
+				AddPolicy:               true,
+				WithUpdatedBy:           true,
+				UpdatedByValueType:      "String",
+				WithHistoryTimeIndex:    true,
+				WithRefHistoryTimeIndex: true,
+				WithUpdatedByIndex:      true,
+			},
+		},
+		{
+			name: "history time index type and name",
+			config: &Config{
+				SchemaPath:       "./schema",
+				HistoryTimeIndex: true,
+				HistoryTimeIndexConfig: IndexConfig{
+					Type: "BRIN",
+					Name: "%s_time_brin",
+				},
+			},
+			want: &templateInfo{
+				TableName:            "todo_history",
+				OriginalTableName:    "Todo",
+				SchemaPkg:            "schema",
+				IDType:               "string",
+				AddPolicy:            true,
+				WithHistoryTimeIndex: true,
+				HistoryTimeIndexType: "BRIN",
+				HistoryTimeIndexName: "todo_history_time_brin",
+			},
+		},
+		{
+			name: "deleted by",
+			config: &Config{
+				SchemaPath: "./schema",
+				DeletedBy: &DeletedBy{
+					key:       "userID",
+					valueType: ValueTypeInt,
+				},
+			},
+			want: &templateInfo{
+				TableName:          "todo_history",
+				OriginalTableName:  "Todo",
+				SchemaPkg:          "schema",
+				IDType:             "string",
+				AddPolicy:          true,
+				WithDeletedBy:      true,
+				DeletedByValueType: "Int",
+			},
+		},
+		{
+			name: "custom operations",
+			config: &Config{
+				SchemaPath: "./schema",
+				Operations: []OpType{"IMPORT", OpTypeRestore},
+			},
+			want: &templateInfo{
+				TableName:         "todo_history",
+				OriginalTableName: "Todo",
+				SchemaPkg:         "schema",
+				IDType:            "string",
+				AddPolicy:         true,
+				Operations:        []string{"IMPORT"},
+			},
+		},
+		{
+			name: "comments",
+			config: &Config{
+				SchemaPath: "./schema",
+				Comments:   true,
+			},
+			want: &templateInfo{
+				TableName:         "todo_history",
+				OriginalTableName: "Todo",
+				SchemaPkg:         "schema",
+				IDType:            "string",
+				AddPolicy:         true,
+				Comment:           "History of todo table, generated by enthistory",
+			},
+		},
+		{
+			name: "updated by index without updated by",
+			config: &Config{
+				SchemaPath:     "./schema",
+				UpdatedByIndex: true,
+			},
+			want: &templateInfo{
+				TableName:         "todo_history",
+				OriginalTableName: "Todo",
+				SchemaPkg:         "schema",
+				IDType:            "string",
+				AddPolicy:         true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getTemplateInfo(schema, tt.config, "string")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetTemplateInfoTenantField(t *testing.T) {
+	config := &Config{
+		SchemaPath: "./schema",
+		TenantKey:  "organizationID",
+	}
+
+	tests := []struct {
+		name   string
+		schema *load.Schema
+		want   bool
+	}{
+		{
+			name:   "tenant field added",
+			schema: &load.Schema{Name: "Todo"},
+			want:   true,
+		},
+		{
+			name: "tenant field copied from the original",
+			schema: &load.Schema{
+				Name:   "Todo",
+				Fields: []*load.Field{{Name: "tenant_id"}},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getTemplateInfo(tt.schema, config, "string")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, got.WithTenantField)
+		})
+	}
+}
+
+func TestGetAuditSummaryTables(t *testing.T) {
+	schemas := []*load.Schema{
+		{Name: "Todo"},
+		{Name: "Beat", Fields: []*load.Field{{Name: "tenant_id"}}},
+	}
+
+	got := getAuditSummaryTables(&Config{}, schemas)
+	assert.Equal(t, []AuditSummaryTable{
+		{Entity: "Todo", Table: "todo_history"},
+		{Entity: "Beat", Table: "beat_history", Tenant: true},
+	}, got)
+
+	got = getAuditSummaryTables(&Config{TenantKey: "organizationID", UpdatedBy: &UpdatedBy{key: "userID"}}, schemas)
+	assert.Equal(t, []AuditSummaryTable{
+		{Entity: "Todo", Table: "todo_history", Actor: true, Tenant: true},
+		{Entity: "Beat", Table: "beat_history", Actor: true, Tenant: true},
+	}, got)
+}
+
+func TestGetAdditionalFields(t *testing.T) {
+	additionalFields := []AdditionalField{
+		{Name: "region", ValueType: ValueTypeString, Key: "region"},
+		{Name: "source_system", ValueType: ValueTypeInt, Key: "sourceSystem"},
+	}
+
+	tests := []struct {
+		name   string
+		fields []*load.Field
+		want   []additionalFieldInfo
+	}{
+		{
+			name: "fields added",
+			want: []additionalFieldInfo{
+				{Name: "region", ValueType: "String"},
+				{Name: "source_system", ValueType: "Int"},
+			},
+		},
+		{
+			name:   "field copied from the original",
+			fields: []*load.Field{{Name: "region"}},
+			want: []additionalFieldInfo{
+				{Name: "source_system", ValueType: "Int"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getAdditionalFields(additionalFields, tt.fields))
+		})
+	}
+
+	assert.Empty(t, getAdditionalFields(nil, nil))
+}
+
+func TestGetHistoryTimeSchemaType(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			name:   "database default",
+			config: &Config{},
+			want:   nil,
+		},
+		{
+			name:   "microseconds",
+			config: &Config{HistoryTimePrecision: 6},
+			want: map[string]string{
+				"Postgres": "timestamptz(6)",
+				"MySQL":    "timestamp(6)",
+			},
+		},
+		{
+			name:   "without time zone",
+			config: &Config{HistoryTimeWithoutTimeZone: true},
+			want: map[string]string{
+				"Postgres": "timestamp",
+				"MySQL":    "datetime",
+			},
+		},
+		{
+			name:   "milliseconds without time zone",
+			config: &Config{HistoryTimePrecision: 3, HistoryTimeWithoutTimeZone: true},
+			want: map[string]string{
+				"Postgres": "timestamp(3)",
+				"MySQL":    "datetime(3)",
+			},
+		},
+		{
+			name:   "postgres",
+			config: &Config{HistoryTimePrecision: 6, Dialect: dialect.Postgres},
+			want: map[string]string{
+				"Postgres": "timestamptz(6)",
+			},
+		},
+		{
+			name:   "mysql",
+			config: &Config{HistoryTimePrecision: 6, Dialect: dialect.MySQL},
+			want: map[string]string{
+				"MySQL": "timestamp(6)",
+			},
+		},
+		{
+			name:   "sqlite",
+			config: &Config{HistoryTimePrecision: 6, Dialect: dialect.SQLite},
+			want:   nil,
+		},
+		{
+			name:    "invalid precision",
+			config:  &Config{HistoryTimePrecision: 9},
+			wantErr: ErrInvalidHistoryTimePrecision,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getHistoryTimeSchemaType(tt.config)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetSchemaVersion(t *testing.T) {
+	todo := &load.Schema{
+		Name: "Todo",
+		Fields: []*load.Field{
+			{Name: "name", Info: &field.TypeInfo{Type: field.TypeString}},
+		},
+	}
+
+	changed := &load.Schema{
+		Name: "Todo",
+		Fields: []*load.Field{
+			{Name: "name", Info: &field.TypeInfo{Type: field.TypeString}, Optional: true},
+		},
+	}
+
+	hash, err := schemaFieldsHash(todo)
+	require.NoError(t, err)
+	assert.Len(t, hash, schemaHashLength)
+
+	changedHash, err := schemaFieldsHash(changed)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changedHash)
+
+	previous := func(a Annotations) *load.Schema {
+		return &load.Schema{
+			Name:        "TodoHistory",
+			Annotations: map[string]any{annotationName: a},
+		}
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	generatedAt := now.Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name            string
+		schema          *load.Schema
+		previous        *load.Schema
+		want            int
+		wantGeneratedAt int64
+	}{
+		{
+			name:            "new history schema",
+			schema:          todo,
+			want:            1,
+			wantGeneratedAt: now.Unix(),
+		},
+		{
+			name:            "history schema without a version",
+			schema:          todo,
+			previous:        previous(Annotations{IsHistory: true}),
+			want:            1,
+			wantGeneratedAt: now.Unix(),
+		},
+		{
+			name:            "fields not changed",
+			schema:          todo,
+			previous:        previous(Annotations{IsHistory: true, SchemaVersion: 2, SchemaHash: hash, SchemaGeneratedAt: generatedAt}),
+			want:            2,
+			wantGeneratedAt: generatedAt,
+		},
+		{
+			name:            "fields changed",
+			schema:          changed,
+			previous:        previous(Annotations{IsHistory: true, SchemaVersion: 2, SchemaHash: hash, SchemaGeneratedAt: generatedAt}),
+			want:            3,
+			wantGeneratedAt: now.Unix(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getSchemaVersion(tt.schema, tt.previous, now)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, got.Version)
+			assert.Equal(t, tt.wantGeneratedAt, got.GeneratedAt)
+
+			wantHash, err := schemaFieldsHash(tt.schema)
+			require.NoError(t, err)
+			assert.Equal(t, wantHash, got.Hash)
+			assert.Equal(t, schemaFields(tt.schema), got.Fields)
+		})
+	}
+
+	t.Run("changes since the previous fields", func(t *testing.T) {
+		retyped := &load.Schema{
+			Name: "Todo",
+			Fields: []*load.Field{
+				{Name: "name", Info: &field.TypeInfo{Type: field.TypeInt}},
+			},
+		}
+
+		got, err := getSchemaVersion(retyped, previous(Annotations{
+			IsHistory:     true,
+			SchemaVersion: 2,
+			SchemaHash:    hash,
+			SchemaFields:  map[string]string{"name": "TypeString", "due": "TypeTime"},
+			LegacyFields:  map[string]string{"archived": "TypeBool"},
+		}), now)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, got.Version)
+		assert.Equal(t, []FieldChange{
+			{Field: "due", Kind: FieldRemoved, OldType: "TypeTime"},
+			{Field: "name", Kind: FieldRetyped, OldType: "TypeString", NewType: "TypeInt"},
+		}, got.Changes)
+		assert.Equal(t, map[string]string{"archived": "TypeBool"}, got.Legacy)
+	})
+}
+
+func TestGetTemplateInfoAllowedRelation(t *testing.T) {
+	config := &Config{
+		SchemaPath: "./schema",
+		Auth: AuthzSettings{
+			Enabled:         true,
+			AllowedRelation: "audit_log_viewer",
+		},
+	}
+
+	tests := []struct {
+		name   string
+		schema *load.Schema
+		want   string
+	}{
+		{
+			name:   "global relation",
+			schema: &load.Schema{Name: "Todo"},
+			want:   "audit_log_viewer",
+		},
+		{
+			name: "relation overridden by annotation",
+			schema: &load.Schema{
+				Name: "Todo",
+				Annotations: map[string]any{
+					annotationName: map[string]any{"allowedRelation": "admin"},
+				},
+			},
+			want: "admin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getTemplateInfo(tt.schema, config, "string")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, got.AuthzPolicy.AllowedRelation)
+		})
+	}
+}
+
+func TestGetEdgeTemplateInfo(t *testing.T) {
+	user := &gen.Type{Name: "User", ID: &gen.Field{Name: "id", Type: &field.TypeInfo{Type: field.TypeString}}}
+	group := &gen.Type{Name: "Group", ID: &gen.Field{Name: "id", Type: &field.TypeInfo{Type: field.TypeInt}}}
+
+	e := &gen.Edge{
+		Name:  "groups",
+		Type:  group,
+		Owner: user,
+		Rel:   gen.Relation{Type: gen.M2M, Table: "user_groups", Columns: []string{"user_id", "group_id"}},
+	}
+
+	got, err := getEdgeTemplateInfo(e, &Config{
+		SchemaPath: "./schema",
+		SchemaName: "history",
+		TenantKey:  "organizationID",
+		UpdatedBy: &UpdatedBy{
+			key:       "userID",
+			valueType: ValueTypeInt,
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, &edgeTemplateInfo{
+		Name:       "UserGroupsHistory",
+		Owner:      "User",
+		Edge:       "groups",
+		SchemaPkg:  "schema",
+		TableName:  "user_groups_history",
+		SchemaName: "history",
+		Columns: []edgeColumn{
+			{Name: "user_id", IDType: "string"},
+			{Name: "group_id", IDType: "int"},
+		},
+		WithUpdatedBy:      true,
+		UpdatedByValueType: "Int",
+		WithTenantField:    true,
+	}, got)
+
+	user.Annotations = gen.Annotations{annotationName: map[string]any{"schemaName": "audit"}}
+
+	got, err = getEdgeTemplateInfo(e, &Config{SchemaPath: "./schema", SchemaName: "history"})
+	require.NoError(t, err)
+	assert.Equal(t, "audit", got.SchemaName)
+	assert.Empty(t, got.Comment)
+
+	got, err = getEdgeTemplateInfo(e, &Config{SchemaPath: "./schema", Comments: true})
+	require.NoError(t, err)
+	assert.Equal(t, "History of user_groups table, generated by enthistory", got.Comment)
+}
+
+func TestHistorySchemaExists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo_history.go")
+	assert.False(t, historySchemaExists(path))
+
+	err := os.WriteFile(path, []byte("package schema"), 0600)
+	require.NoError(t, err)
+
+	assert.True(t, historySchemaExists(path))
+}
+
+// copyTestSchemas copies the test schemas, without their history schemas, into a directory in the module which is
+// removed once the test is done, and returns its absolute path
+func copyTestSchemas(t *testing.T) string {
+	t.Helper()
+
+	tmp, err := os.MkdirTemp("testdata", ".generated-")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(tmp) })
+
+	// the package name of the history schemas is the name of the schema directory
+	dir, err := filepath.Abs(filepath.Join(tmp, "schema"))
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	for _, name := range []string{"list.go", "todo.go", "user.go"} {
+		content, err := os.ReadFile(filepath.Join("testdata", "schema", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+	}
+
+	return dir
+}
+
+func TestGeneratedSchemas(t *testing.T) {
+	dir := copyTestSchemas(t)
+
+	h := New(WithSchemaPath(dir))
+	assert.Empty(t, h.GeneratedSchemas())
+
+	require.NoError(t, h.GenerateSchemas())
+	assert.Equal(t, []SchemaInfo{
+		{Name: "ListHistory", Table: "list_history", Source: "List", Path: filepath.Join(dir, "list_history.go")},
+		{Name: "UserHistory", Table: "user_history", Source: "User", Path: filepath.Join(dir, "user_history.go")},
+	}, h.GeneratedSchemas())
+
+	for _, info := range h.GeneratedSchemas() {
+		assert.FileExists(t, info.Path)
+	}
+}
+
+func TestGenerateSchemasUnsupportedIDTypes(t *testing.T) {
+	dir := copyTestSchemas(t)
+
+	for name, idField := range map[string]string{"Device": `field.Int64("id")`, "Sensor": `field.Uint("id")`} {
+		content := fmt.Sprintf(`package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+)
+
+type %s struct {
+	ent.Schema
+}
+
+func (%s) Fields() []ent.Field {
+	return []ent.Field{
+		%s,
+		field.String("name"),
+	}
+}
+`, name, name, idField)
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, strings.ToLower(name)+".go"), []byte(content), 0o600))
+	}
+
+	err := New(WithSchemaPath(dir)).GenerateSchemas()
+	require.ErrorIs(t, err, ErrUnsupportedIDType)
+	assert.ErrorContains(t, err, "Device (int64), Sensor (uint)")
+
+	// no history schema is generated when an id type is not supported
+	assert.NoFileExists(t, filepath.Join(dir, "list_history.go"))
+
+	// the schemas that are not tracked are not validated
+	require.NoError(t, New(WithSchemaPath(dir), WithOptIn()).GenerateSchemas())
+}
+
+func TestGenerateSchemasInvalidAnnotation(t *testing.T) {
+	dir := copyTestSchemas(t)
+
+	content := `package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+
+	"github.com/datumforge/enthistory"
+)
+
+type Note struct {
+	ent.Schema
+}
+
+func (Note) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("body"),
+	}
+}
+
+func (Note) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		enthistory.Annotations{
+			FieldLimits: []enthistory.FieldLimit{{Field: "body", MaxSize: -1}},
+		},
+	}
+}
+`
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "note.go"), []byte(content), 0o600))
+
+	// the annotation errors are returned instead of panicking in the goroutines generating the history schemas
+	err := New(WithSchemaPath(dir)).GenerateSchemas()
+	require.ErrorIs(t, err, ErrInvalidFieldLimit)
+	assert.ErrorContains(t, err, "Note")
+}
+
+func TestGenerateSchemasUnsupportedDialect(t *testing.T) {
+	dir := copyTestSchemas(t)
+
+	err := New(WithSchemaPath(dir), WithDialect("oracle")).GenerateSchemas()
+	require.ErrorIs(t, err, ErrUnsupportedDialect)
+	assert.NoFileExists(t, filepath.Join(dir, "list_history.go"))
+
+	require.NoError(t, New(WithSchemaPath(dir), WithDialect(dialect.SQLite)).GenerateSchemas())
+	assert.FileExists(t, filepath.Join(dir, "list_history.go"))
+}
+
+func TestGetTemplateInfoPolicyTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/policy.tmpl": &fstest.MapFile{Data: []byte("// Policy of the {{ .Schema.Name }}")},
+	}
+
+	got, err := getTemplateInfo(&load.Schema{Name: "Todo"}, &Config{
+		SchemaPath:     "./schema",
+		PolicyTemplate: &PolicyTemplate{fsys: fsys, path: "templates/policy.tmpl"},
+	}, "string")
+	require.NoError(t, err)
+
+	assert.Equal(t, "// Policy of the {{ .Schema.Name }}", got.PolicyTemplate)
+
+	_, err = getTemplateInfo(&load.Schema{Name: "Todo"}, &Config{
+		SchemaPath:     "./schema",
+		PolicyTemplate: &PolicyTemplate{fsys: fsys, path: "templates/missing.tmpl"},
+	}, "string")
+	assert.ErrorIs(t, err, ErrFailedToGenerateTemplate)
+}
+
+func TestGenerateSchemasInvalidIndexName(t *testing.T) {
+	dir := copyTestSchemas(t)
+
+	err := New(WithSchemaPath(dir), WithHistoryTimeIndex(WithIndexName("history_time_brin"))).GenerateSchemas()
+	assert.ErrorIs(t, err, ErrInvalidIndexName)
+
+	require.NoError(t, New(WithSchemaPath(dir), WithHistoryTimeIndex(WithIndexType("BRIN"),
+		WithIndexName("%s_time_brin"))).GenerateSchemas())
+
+	schema, err := os.ReadFile(filepath.Join(dir, "list_history.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(schema), `StorageKey("list_history_time_brin")`)
+	assert.Contains(t, string(schema), `entsql.IndexTypes(map[string]string{dialect.Postgres: "BRIN"})`)
+}
@@ -3,29 +3,30 @@ module github.com/datumforge/enthistory
 go 1.22.5
 
 require (
+	ariga.io/atlas v0.24.1
 	entgo.io/ent v0.14.0
 	github.com/datumforge/fgax v0.5.2
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/stoewer/go-strcase v1.3.0
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/sync v0.8.0
 	golang.org/x/tools v0.24.0
 )
 
 require (
-	ariga.io/atlas v0.24.1 // indirect
 	github.com/agext/levenshtein v1.2.3 // indirect
 	github.com/apparentlymart/go-textseg/v15 v15.0.0 // indirect
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/go-openapi/inflect v0.21.0 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/hashicorp/hcl/v2 v2.21.0 // indirect
 	github.com/kr/pretty v0.1.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.22 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/zclconf/go-cty v1.14.4 // indirect
 	golang.org/x/mod v0.20.0 // indirect
-	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
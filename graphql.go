@@ -0,0 +1,63 @@
+package enthistory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"entgo.io/ent/entc/gen"
+)
+
+const (
+	// restoreMutationsName is the name of the file the GraphQL schema of the restore mutations is written to
+	restoreMutationsName = "history.graphql"
+)
+
+// restoreMutationsGraphQL returns the GraphQL schema extending the Mutation type with a restore mutation of each of
+// the tracked schemas, e.g. restoreTodoHistory(historyID: ID!): Todo!
+func restoreMutationsGraphQL(names []string) string {
+	var b strings.Builder
+
+	b.WriteString("# Code generated by enthistory, DO NOT EDIT.\n\nextend type Mutation {\n")
+
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "  \"\"\"\n  Restores the %s to the values of the %sHistory row with the history id\n  \"\"\"\n", name, name)
+		fmt.Fprintf(&b, "  restore%sHistory(historyID: ID!): %s!\n", name, name)
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// restoreMutationsHook returns a hook writing the GraphQL schema of the restore mutations of the tracked schemas with
+// a history schema to the target directory of the generated code, once the code is generated; the mutations are
+// resolved by the generated HistoryResolver
+func restoreMutationsHook(next gen.Generator) gen.Generator {
+	return gen.GenerateFunc(func(g *gen.Graph) error {
+		if err := next.Generate(g); err != nil {
+			return err
+		}
+
+		var names []string
+
+		for _, n := range g.Nodes {
+			if h := historyType(g.Nodes, n); h != nil && !h.IsView() {
+				names = append(names, n.Name)
+			}
+		}
+
+		if len(names) == 0 {
+			return nil
+		}
+
+		path := filepath.Join(g.Config.Target, restoreMutationsName)
+
+		return os.WriteFile(path, []byte(restoreMutationsGraphQL(names)), 0o600) //nolint:mnd
+	})
+}
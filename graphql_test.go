@@ -0,0 +1,58 @@
+package enthistory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"entgo.io/ent/entc"
+	"entgo.io/ent/entc/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreMutationsGraphQL(t *testing.T) {
+	assert.Equal(t, `# Code generated by enthistory, DO NOT EDIT.
+
+extend type Mutation {
+  """
+  Restores the Todo to the values of the TodoHistory row with the history id
+  """
+  restoreTodoHistory(historyID: ID!): Todo!
+
+  """
+  Restores the List to the values of the ListHistory row with the history id
+  """
+  restoreListHistory(historyID: ID!): List!
+}
+`, restoreMutationsGraphQL([]string{"Todo", "List"}))
+}
+
+func TestRestoreMutationsHook(t *testing.T) {
+	graph, err := entc.LoadGraph("./testdata/schema", &gen.Config{})
+	require.NoError(t, err)
+
+	graph.Config.Target = t.TempDir()
+
+	generated := false
+	next := gen.GenerateFunc(func(*gen.Graph) error {
+		generated = true
+
+		return nil
+	})
+
+	require.NoError(t, restoreMutationsHook(next).Generate(graph))
+	assert.True(t, generated)
+
+	schema, err := os.ReadFile(filepath.Join(graph.Config.Target, restoreMutationsName))
+	require.NoError(t, err)
+	assert.Contains(t, string(schema), "restoreUserHistory(historyID: ID!): User!")
+	assert.NotContains(t, string(schema), "restoreUserHistoryHistory")
+}
+
+func TestHooks(t *testing.T) {
+	assert.Empty(t, New().Hooks())
+	assert.Empty(t, New(WithGQLQuery()).Hooks())
+	assert.Len(t, New(WithGQLQuery(), WithAuditing()).Hooks(), 1)
+	assert.Len(t, New(WithGQLQuery(), WithAuditing(), WithLatestHistoryViews("")).Hooks(), 2)
+}
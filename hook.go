@@ -33,13 +33,63 @@ func On(hk ent.Hook, op ent.Op) ent.Hook {
 	}
 }
 
+// CaptureMode is when the history of updates is captured by the history hooks
+type CaptureMode int
+
+const (
+	// CapturePreMutation captures the update history before the mutation, merging the values of the mutation
+	// with the stored records, this is the default
+	CapturePreMutation CaptureMode = iota
+	// CapturePostMutation captures the update history after the mutation, and the hooks that run after the history
+	// hooks, so the changes made to the mutation by other hooks are recorded; this is applied to update one
+	// mutations, updates of many records are captured before the mutation as their predicates may no longer
+	// match the updated records
+	CapturePostMutation
+)
+
+// hookConfig is the configuration of the history hooks
+type hookConfig struct {
+	// updateCapture is when the update history is captured
+	updateCapture CaptureMode
+	// recordAttempt records the mutations that fail, set using WithAttempts
+	recordAttempt AttemptRecorder
+}
+
+// HookOption configures the history hooks
+type HookOption func(*hookConfig)
+
+// WithUpdateCapture sets when the update history is captured, before (the default) or after the mutation
+func WithUpdateCapture(mode CaptureMode) HookOption {
+	return func(c *hookConfig) {
+		c.updateCapture = mode
+	}
+}
+
 // HistoryHooks returns a list of hooks that can be used to create history entries
-func HistoryHooks[T Mutation]() []ent.Hook {
-	return []ent.Hook{
+func HistoryHooks[T Mutation](opts ...HookOption) []ent.Hook {
+	config := &hookConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	hooks := []ent.Hook{
 		On(historyHookCreate[T](), ent.OpCreate),
-		On(historyHookUpdate[T](), ent.OpUpdate|ent.OpUpdateOne),
+		On(historyHookUpdate[T](config), ent.OpUpdate|ent.OpUpdateOne),
 		On(historyHookDelete[T](), ent.OpDelete|ent.OpDeleteOne),
 	}
+
+	if config.recordAttempt != nil {
+		hooks = append([]ent.Hook{historyHookAttempt(config.recordAttempt)}, hooks...)
+	}
+
+	return hooks
+}
+
+// newHistoryContext returns the context used by the history hooks to create the history rows, bypassing query caches
+// using the context set using SetQueryContext; the privacy tokens allowing the history rows are minted by the generated
+// methods creating them
+func newHistoryContext(ctx context.Context) context.Context {
+	return newQueryContext(ctx)
 }
 
 // getTypedMutation is a helper function that allows you to get a typed mutation from an ent.Mutation
@@ -56,6 +106,10 @@ func getTypedMutation[T Mutation](m ent.Mutation) (T, error) {
 func historyHookCreate[T Mutation]() ent.Hook {
 	return func(next ent.Mutator) ent.Mutator {
 		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if shouldSkip(ctx, m) {
+				return next.Mutate(ctx, m)
+			}
+
 			mutation, err := getTypedMutation[T](m)
 			if err != nil {
 				return nil, err
@@ -66,8 +120,10 @@ func historyHookCreate[T Mutation]() ent.Hook {
 				return nil, err
 			}
 
-			err = mutation.CreateHistoryFromCreate(ctx)
+			err = mutation.CreateHistoryFromCreate(newHistoryContext(ctx))
 			if err != nil {
+				historyErrors.Add(1)
+
 				return nil, err
 			}
 
@@ -77,15 +133,36 @@ func historyHookCreate[T Mutation]() ent.Hook {
 }
 
 // historyHookUpdate is a hook that creates a history entry when an update operation is performed
-func historyHookUpdate[T Mutation]() ent.Hook {
+func historyHookUpdate[T Mutation](config *hookConfig) ent.Hook {
 	return func(next ent.Mutator) ent.Mutator {
 		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if shouldSkip(ctx, m) {
+				return next.Mutate(ctx, m)
+			}
+
 			mutation, err := getTypedMutation[T](m)
 			if err != nil {
 				return nil, err
 			}
 
-			if err = mutation.CreateHistoryFromUpdate(ctx); err != nil {
+			if config.updateCapture == CapturePostMutation && m.Op().Is(ent.OpUpdateOne) {
+				value, err := next.Mutate(ctx, m)
+				if err != nil {
+					return nil, err
+				}
+
+				if err = mutation.CreateHistoryFromUpdate(newHistoryContext(ctx)); err != nil {
+					historyErrors.Add(1)
+
+					return nil, err
+				}
+
+				return value, nil
+			}
+
+			if err = mutation.CreateHistoryFromUpdate(newHistoryContext(ctx)); err != nil {
+				historyErrors.Add(1)
+
 				return nil, err
 			}
 
@@ -98,12 +175,18 @@ func historyHookUpdate[T Mutation]() ent.Hook {
 func historyHookDelete[T Mutation]() ent.Hook {
 	return func(next ent.Mutator) ent.Mutator {
 		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if shouldSkip(ctx, m) {
+				return next.Mutate(ctx, m)
+			}
+
 			mutation, err := getTypedMutation[T](m)
 			if err != nil {
 				return nil, err
 			}
 
-			if err = mutation.CreateHistoryFromDelete(ctx); err != nil {
+			if err = mutation.CreateHistoryFromDelete(newHistoryContext(ctx)); err != nil {
+				historyErrors.Add(1)
+
 				return nil, err
 			}
 
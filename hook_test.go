@@ -0,0 +1,100 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testOrderMutation records the order of the history and the mutation
+type testOrderMutation struct {
+	ent.Mutation
+	op    ent.Op
+	calls *[]string
+}
+
+func (m *testOrderMutation) Op() ent.Op {
+	return m.op
+}
+
+func (m *testOrderMutation) CreateHistoryFromCreate(context.Context) error {
+	*m.calls = append(*m.calls, "history")
+
+	return nil
+}
+
+func (m *testOrderMutation) CreateHistoryFromUpdate(context.Context) error {
+	*m.calls = append(*m.calls, "history")
+
+	return nil
+}
+
+func (m *testOrderMutation) CreateHistoryFromDelete(context.Context) error {
+	*m.calls = append(*m.calls, "history")
+
+	return nil
+}
+
+func TestHistoryHooksUpdateCapture(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []HookOption
+		op   ent.Op
+		want []string
+	}{
+		{
+			name: "pre mutation by default",
+			op:   ent.OpUpdateOne,
+			want: []string{"history", "mutate"},
+		},
+		{
+			name: "post mutation",
+			opts: []HookOption{WithUpdateCapture(CapturePostMutation)},
+			op:   ent.OpUpdateOne,
+			want: []string{"mutate", "history"},
+		},
+		{
+			name: "post mutation on update many",
+			opts: []HookOption{WithUpdateCapture(CapturePostMutation)},
+			op:   ent.OpUpdate,
+			want: []string{"history", "mutate"},
+		},
+		{
+			name: "create",
+			opts: []HookOption{WithUpdateCapture(CapturePostMutation)},
+			op:   ent.OpCreate,
+			want: []string{"mutate", "history"},
+		},
+		{
+			name: "delete",
+			opts: []HookOption{WithUpdateCapture(CapturePostMutation)},
+			op:   ent.OpDeleteOne,
+			want: []string{"history", "mutate"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := []string{}
+			m := &testOrderMutation{op: tt.op, calls: &calls}
+
+			var mutator ent.Mutator = ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+				calls = append(calls, "mutate")
+
+				return nil, nil
+			})
+
+			hooks := HistoryHooks[*testOrderMutation](tt.opts...)
+			for i := len(hooks) - 1; i >= 0; i-- {
+				mutator = hooks[i](mutator)
+			}
+
+			_, err := mutator.Mutate(context.Background(), m)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, calls)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package enthistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// inspectTableName matches the names of the history tables read by ShowHistory and DiffHistory, optionally qualified
+// by the schema, the names are not quoted so anything else is rejected
+var inspectTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ShowHistory writes the history rows of the ref in the history table to w as a table, ordered by history time; this
+// is used by the show command of the enthistory CLI, the placeholders of the query are supported by Postgres and SQLite
+func ShowHistory(ctx context.Context, db *sql.DB, w io.Writer, table, ref string) error {
+	if !inspectTableName.MatchString(table) {
+		return fmt.Errorf("%w: %q", ErrInvalidTableName, table)
+	}
+
+	columns, rows, err := inspectRows(ctx, db,
+		fmt.Sprintf("SELECT * FROM %s WHERE ref = $1 ORDER BY history_time, id", table), ref)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+
+	for _, row := range rows {
+		values := make([]string, len(row))
+		for i, value := range row {
+			values[i] = inspectValue(value)
+		}
+
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// DiffHistory writes the columns that changed between the history rows from and to of the ref in the history table
+// to w, with their values in both rows; from and to are the ids of the history rows, as listed by ShowHistory, and
+// ErrHistoryRowNotFound is returned when either is not a history row of the ref
+func DiffHistory(ctx context.Context, db *sql.DB, w io.Writer, table, ref, from, to string) error {
+	if !inspectTableName.MatchString(table) {
+		return fmt.Errorf("%w: %q", ErrInvalidTableName, table)
+	}
+
+	columns, rows, err := inspectRows(ctx, db,
+		fmt.Sprintf("SELECT * FROM %s WHERE ref = $1 AND id IN ($2, $3)", table), ref, from, to)
+	if err != nil {
+		return err
+	}
+
+	id := inspectColumn(columns, "id")
+
+	var fromRow, toRow []any
+
+	for _, row := range rows {
+		switch inspectValue(row[id]) {
+		case from:
+			fromRow = row
+		case to:
+			toRow = row
+		}
+	}
+
+	if fromRow == nil {
+		return fmt.Errorf("%w: %s", ErrHistoryRowNotFound, from)
+	}
+
+	if toRow == nil {
+		return fmt.Errorf("%w: %s", ErrHistoryRowNotFound, to)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "COLUMN\t%s\t%s\n", from, to)
+
+	for i, column := range columns {
+		if i == id {
+			continue
+		}
+
+		if before, after := inspectValue(fromRow[i]), inspectValue(toRow[i]); before != after {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", column, before, after)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// inspectRows returns the columns and the rows of the query
+func inspectRows(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, [][]any, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if inspectColumn(columns, "id") < 0 {
+		return nil, nil, fmt.Errorf("%w: id", ErrFieldNotFound)
+	}
+
+	var values [][]any
+
+	for rows.Next() {
+		row := make([]any, len(columns))
+		dest := make([]any, len(columns))
+
+		for i := range row {
+			dest[i] = &row[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+
+		values = append(values, row)
+	}
+
+	return columns, values, rows.Err()
+}
+
+// inspectColumn returns the index of the column, -1 when there is none
+func inspectColumn(columns []string, name string) int {
+	for i, column := range columns {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// inspectValue formats a value scanned from a history row for the terminal
+func inspectValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,71 @@
+package enthistory
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInspectDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file:inspect?mode=memory&cache=shared")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE todo_history (
+		id integer PRIMARY KEY,
+		history_time text NOT NULL,
+		ref text,
+		operation text NOT NULL,
+		name text,
+		done boolean
+	);
+	INSERT INTO todo_history VALUES
+		(1, '2024-01-01T00:00:00Z', '7', 'INSERT', 'write tests', false),
+		(2, '2024-01-02T00:00:00Z', '7', 'UPDATE', 'write tests', true),
+		(3, '2024-01-02T00:00:00Z', '8', 'INSERT', 'other', NULL)`)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestShowHistory(t *testing.T) {
+	db := newInspectDB(t)
+
+	var b bytes.Buffer
+
+	require.NoError(t, ShowHistory(context.Background(), db, &b, "todo_history", "7"))
+	assert.Equal(t, "ID  HISTORY_TIME          REF  OPERATION  NAME         DONE\n"+
+		"1   2024-01-01T00:00:00Z  7    INSERT     write tests  false\n"+
+		"2   2024-01-02T00:00:00Z  7    UPDATE     write tests  true\n", b.String())
+
+	assert.ErrorIs(t, ShowHistory(context.Background(), db, &b, "todo_history; DROP TABLE todo_history", "7"), ErrInvalidTableName)
+}
+
+func TestDiffHistory(t *testing.T) {
+	db := newInspectDB(t)
+
+	var b bytes.Buffer
+
+	require.NoError(t, DiffHistory(context.Background(), db, &b, "todo_history", "7", "1", "2"))
+	assert.Equal(t, "COLUMN        1                     2\n"+
+		"history_time  2024-01-01T00:00:00Z  2024-01-02T00:00:00Z\n"+
+		"operation     INSERT                UPDATE\n"+
+		"done          false                 true\n", b.String())
+
+	assert.ErrorIs(t, DiffHistory(context.Background(), db, &b, "todo_history", "7", "1", "3"), ErrHistoryRowNotFound)
+	assert.ErrorIs(t, DiffHistory(context.Background(), db, &b, "public.todo-history", "7", "1", "2"), ErrInvalidTableName)
+}
+
+func TestInspectValue(t *testing.T) {
+	assert.Equal(t, "NULL", inspectValue(nil))
+	assert.Equal(t, `{"a":1}`, inspectValue([]byte(`{"a":1}`)))
+	assert.Equal(t, "42", inspectValue(int64(42)))
+}
@@ -0,0 +1,166 @@
+package enthistory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// LatestCache caches the latest history row of each ref, read through by the generated LatestByRef of the history
+// clients under the system context to avoid querying the latest history row of hot records repeatedly; the cached rows
+// are invalidated once the history rows written for the ref are committed, and when history rows of the table are purged
+type LatestCache interface {
+	// Get returns the latest history row of the ref in the history table, if it is cached
+	Get(ctx context.Context, table string, ref any) (any, bool)
+	// Set caches the latest history row of the ref in the history table
+	Set(ctx context.Context, table string, ref any, row any)
+	// Invalidate removes the cached latest history row of the ref in the history table
+	Invalidate(ctx context.Context, table string, ref any)
+	// InvalidateTable removes the cached latest history rows of the history table
+	InvalidateTable(ctx context.Context, table string)
+}
+
+var (
+	// latestCache is the cache of the latest history rows, set using SetLatestCache
+	latestCache LatestCache
+	// latestCacheMu guards the latest cache
+	latestCacheMu sync.RWMutex
+)
+
+// SetLatestCache sets the cache of the latest history row of each ref used by the history clients of all schemas,
+// the latest history rows are not cached when it is nil
+func SetLatestCache(c LatestCache) {
+	latestCacheMu.Lock()
+	defer latestCacheMu.Unlock()
+
+	latestCache = c
+}
+
+// GetLatestCache returns the cache of the latest history rows set using SetLatestCache, nil when there is none; this
+// is used by the generated LatestByRef
+func GetLatestCache() LatestCache {
+	latestCacheMu.RLock()
+	defer latestCacheMu.RUnlock()
+
+	return latestCache
+}
+
+// InvalidateLatest removes the cached latest history row of the ref in the history table, this is called by
+// InvalidateRef once history rows are committed
+func InvalidateLatest(ctx context.Context, table string, ref any) {
+	if c := GetLatestCache(); c != nil {
+		c.Invalidate(ctx, table, ref)
+	}
+}
+
+// InvalidateLatestTable removes the cached latest history rows of the history table, this is used by the generated
+// purge methods once the deletes of history rows are committed
+func InvalidateLatestTable(ctx context.Context, table string) {
+	if c := GetLatestCache(); c != nil {
+		c.InvalidateTable(ctx, table)
+	}
+}
+
+// latestKey is the key of a cached latest history row
+type latestKey struct {
+	table string
+	ref   any
+}
+
+// latestEntry is a cached latest history row
+type latestEntry struct {
+	key latestKey
+	row any
+}
+
+// MemoryLatestCache is an in-memory LatestCache holding the latest history rows of up to size refs, evicting the
+// least recently used
+type MemoryLatestCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[latestKey]*list.Element
+}
+
+// NewMemoryLatestCache returns an in-memory cache of the latest history rows of up to size refs, the cache is
+// unbounded when size is not positive
+func NewMemoryLatestCache(size int) *MemoryLatestCache {
+	return &MemoryLatestCache{
+		size:    size,
+		order:   list.New(),
+		entries: map[latestKey]*list.Element{},
+	}
+}
+
+// Get returns the latest history row of the ref in the history table, if it is cached
+func (c *MemoryLatestCache) Get(_ context.Context, table string, ref any) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[latestKey{table: table, ref: ref}]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*latestEntry).row, true
+}
+
+// Set caches the latest history row of the ref in the history table, evicting the least recently used row when the
+// cache is full
+func (c *MemoryLatestCache) Set(_ context.Context, table string, ref any, row any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := latestKey{table: table, ref: ref}
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*latestEntry).row = row
+		c.order.MoveToFront(e)
+
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&latestEntry{key: key, row: row})
+
+	if c.size > 0 && c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*latestEntry).key)
+	}
+}
+
+// Invalidate removes the cached latest history row of the ref in the history table
+func (c *MemoryLatestCache) Invalidate(_ context.Context, table string, ref any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := latestKey{table: table, ref: ref}
+
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+// InvalidateTable removes the cached latest history rows of the history table
+func (c *MemoryLatestCache) InvalidateTable(_ context.Context, table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, e := range c.entries {
+		if key.table == table {
+			c.order.Remove(e)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len returns the number of cached latest history rows
+func (c *MemoryLatestCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,78 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLatestCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryLatestCache(2)
+
+	_, ok := c.Get(ctx, "todo_history", 1)
+	assert.False(t, ok)
+
+	c.Set(ctx, "todo_history", 1, "a")
+	c.Set(ctx, "todo_history", 2, "b")
+	c.Set(ctx, "todo_history", 1, "c")
+
+	row, ok := c.Get(ctx, "todo_history", 1)
+	assert.True(t, ok)
+	assert.Equal(t, "c", row)
+
+	// the least recently used row is evicted
+	c.Set(ctx, "user_history", 1, "d")
+
+	_, ok = c.Get(ctx, "todo_history", 2)
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	c.Invalidate(ctx, "todo_history", 1)
+
+	_, ok = c.Get(ctx, "todo_history", 1)
+	assert.False(t, ok)
+
+	row, ok = c.Get(ctx, "user_history", 1)
+	assert.True(t, ok)
+	assert.Equal(t, "d", row)
+
+	c.InvalidateTable(ctx, "user_history")
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestMemoryLatestCacheUnbounded(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryLatestCache(0)
+
+	for i := range 10 {
+		c.Set(ctx, "todo_history", i, i)
+	}
+
+	assert.Equal(t, 10, c.Len())
+}
+
+func TestInvalidateLatest(t *testing.T) {
+	ctx := context.Background()
+
+	// this does nothing when no cache is set
+	InvalidateLatest(ctx, "todo_history", 1)
+	InvalidateLatestTable(ctx, "todo_history")
+
+	c := NewMemoryLatestCache(10)
+
+	SetLatestCache(c)
+	t.Cleanup(func() { SetLatestCache(nil) })
+
+	assert.Equal(t, c, GetLatestCache())
+
+	c.Set(ctx, "todo_history", 1, "a")
+	c.Set(ctx, "todo_history", 2, "b")
+
+	InvalidateLatest(ctx, "todo_history", 1)
+	assert.Equal(t, 1, c.Len())
+
+	InvalidateLatestTable(ctx, "todo_history")
+	assert.Equal(t, 0, c.Len())
+}
@@ -0,0 +1,181 @@
+package enthistory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+
+	"entgo.io/ent"
+)
+
+// LimitStrategy is the strategy used when the value of a field copied to a history row exceeds its size limit
+type LimitStrategy string
+
+const (
+	// LimitTruncate truncates the value to the size limit, ending with TruncatedMarker
+	LimitTruncate LimitStrategy = "TRUNCATE"
+	// LimitHash replaces the value with its sha256 hash, e.g. sha256:9f86d08...
+	LimitHash LimitStrategy = "HASH"
+	// LimitExternal stores the value using the BlobSink set by SetBlobSink, and replaces it with the reference
+	// returned by the sink
+	LimitExternal LimitStrategy = "EXTERNAL"
+)
+
+const (
+	// TruncatedMarker ends the values truncated using LimitTruncate
+	TruncatedMarker = "...[truncated]"
+	// hashPrefix is the prefix of the values replaced using LimitHash
+	hashPrefix = "sha256:"
+)
+
+// FieldLimit is the size limit of the values of a string or bytes field copied to the history rows, so large values
+// (e.g. documents) do not bloat the history tables
+type FieldLimit struct {
+	// Field is the name of the field
+	Field string `json:"field"`
+	// MaxSize is the maximum size of the values in bytes
+	MaxSize int `json:"maxSize"`
+	// Strategy used for values exceeding MaxSize, defaults to LimitTruncate
+	Strategy LimitStrategy `json:"strategy,omitempty"`
+}
+
+// BlobSink stores the large values of the history rows using LimitExternal, e.g. in object storage
+type BlobSink interface {
+	// Put stores the value using the key, and returns the reference stored on the history row in place of the value
+	Put(ctx context.Context, key string, value []byte) (string, error)
+}
+
+// BlobSinkFunc is a function implementing BlobSink
+type BlobSinkFunc func(ctx context.Context, key string, value []byte) (string, error)
+
+// Put stores the value using the function
+func (f BlobSinkFunc) Put(ctx context.Context, key string, value []byte) (string, error) {
+	return f(ctx, key, value)
+}
+
+var (
+	// blobSink is the sink used by LimitExternal, set using SetBlobSink
+	blobSink BlobSink
+	// blobSinkMu guards the blob sink
+	blobSinkMu sync.RWMutex
+)
+
+// SetBlobSink sets the sink used to store the large values of the history rows of all schemas using LimitExternal
+func SetBlobSink(sink BlobSink) {
+	blobSinkMu.Lock()
+	defer blobSinkMu.Unlock()
+
+	blobSink = sink
+}
+
+// FieldLimitHook returns a hook applying the size limits to the fields of the history rows of the schema as they
+// are created; this is added to the generated history schemas of schemas using the FieldLimits annotation
+func FieldLimitHook(schema string, limits ...FieldLimit) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if !m.Op().Is(ent.OpCreate) {
+				return next.Mutate(ctx, m)
+			}
+
+			ref, _ := m.Field("ref")
+
+			for _, limit := range limits {
+				value, ok := m.Field(limit.Field)
+				if !ok {
+					continue
+				}
+
+				limited, err := limitValue(ctx, schema, ref, limit, value)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := m.SetField(limit.Field, limited); err != nil {
+					return nil, err
+				}
+			}
+
+			return next.Mutate(ctx, m)
+		})
+	}
+}
+
+// limitValue applies the size limit to the string or bytes value, values of other types are returned as is
+func limitValue(ctx context.Context, schema string, ref any, limit FieldLimit, value ent.Value) (ent.Value, error) {
+	v := reflect.ValueOf(value)
+
+	var b []byte
+
+	switch {
+	case v.Kind() == reflect.String:
+		b = []byte(v.String())
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		b = v.Bytes()
+	default:
+		return value, nil
+	}
+
+	if len(b) <= limit.MaxSize {
+		return value, nil
+	}
+
+	var limited string
+
+	switch limit.Strategy {
+	case LimitHash:
+		limited = hashValue(b)
+	case LimitExternal:
+		blobSinkMu.RLock()
+		sink := blobSink
+		blobSinkMu.RUnlock()
+
+		if sink == nil {
+			return nil, fmt.Errorf("%w: %s.%s", ErrBlobSinkNotSet, schema, limit.Field)
+		}
+
+		key := fmt.Sprintf("%s/%v/%s/%s", schema, ref, limit.Field, hex.EncodeToString(sha256Sum(b)))
+
+		ref, err := sink.Put(ctx, key, b)
+		if err != nil {
+			return nil, err
+		}
+
+		limited = ref
+	default:
+		limited = truncateValue(b, limit.MaxSize)
+	}
+
+	// the limited value is converted back to the type of the field, e.g. a custom string type
+	if v.Kind() == reflect.String {
+		return reflect.ValueOf(limited).Convert(v.Type()).Interface(), nil
+	}
+
+	return reflect.ValueOf([]byte(limited)).Convert(v.Type()).Interface(), nil
+}
+
+// truncateValue truncates the value to the size, ending with TruncatedMarker, without splitting a utf-8 character
+func truncateValue(b []byte, size int) string {
+	end := max(size-len(TruncatedMarker), 0)
+
+	for end > 0 && !utf8.RuneStart(b[end]) {
+		end--
+	}
+
+	return string(b[:end]) + TruncatedMarker
+}
+
+// hashValue returns the sha256 hash of the value, e.g. sha256:9f86d08...
+func hashValue(b []byte) string {
+	return hashPrefix + hex.EncodeToString(sha256Sum(b))
+}
+
+// sha256Sum returns the sha256 sum of the value
+func sha256Sum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+
+	return sum[:]
+}
@@ -0,0 +1,108 @@
+package enthistory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLimitMutation records the fields set on the mutation
+type testLimitMutation struct {
+	ent.Mutation
+	op     ent.Op
+	fields map[string]ent.Value
+}
+
+func (m *testLimitMutation) Op() ent.Op {
+	return m.op
+}
+
+func (m *testLimitMutation) Field(name string) (ent.Value, bool) {
+	value, ok := m.fields[name]
+
+	return value, ok
+}
+
+func (m *testLimitMutation) SetField(name string, value ent.Value) error {
+	m.fields[name] = value
+
+	return nil
+}
+
+type testText string
+
+func TestFieldLimitHook(t *testing.T) {
+	large := strings.Repeat("é", 50)
+
+	var keys []string
+
+	SetBlobSink(BlobSinkFunc(func(_ context.Context, key string, _ []byte) (string, error) {
+		keys = append(keys, key)
+
+		return "blob://" + key, nil
+	}))
+	t.Cleanup(func() { SetBlobSink(nil) })
+
+	hook := FieldLimitHook("Document",
+		FieldLimit{Field: "body", MaxSize: 32},
+		FieldLimit{Field: "summary", MaxSize: 32, Strategy: LimitHash},
+		FieldLimit{Field: "attachment", MaxSize: 32, Strategy: LimitExternal},
+		FieldLimit{Field: "title", MaxSize: 32},
+	)
+
+	mutator := hook(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return nil, nil
+	}))
+
+	m := &testLimitMutation{
+		op: ent.OpCreate,
+		fields: map[string]ent.Value{
+			"ref":        1,
+			"body":       testText(large),
+			"summary":    large,
+			"attachment": []byte(large),
+			"title":      "short",
+		},
+	}
+
+	_, err := mutator.Mutate(context.Background(), m)
+	require.NoError(t, err)
+
+	body, ok := m.fields["body"].(testText)
+	require.True(t, ok)
+	assert.LessOrEqual(t, len(body), 32)
+	assert.True(t, strings.HasSuffix(string(body), TruncatedMarker))
+	assert.True(t, utf8.ValidString(string(body)))
+
+	assert.Equal(t, hashValue([]byte(large)), m.fields["summary"])
+	assert.Equal(t, "short", m.fields["title"])
+
+	require.Len(t, keys, 1)
+	assert.True(t, strings.HasPrefix(keys[0], "Document/1/attachment/"))
+	assert.Equal(t, []byte("blob://"+keys[0]), m.fields["attachment"])
+
+	// updates of the history rows are not limited
+	update := &testLimitMutation{op: ent.OpUpdateOne, fields: map[string]ent.Value{"body": large}}
+
+	_, err = mutator.Mutate(context.Background(), update)
+	require.NoError(t, err)
+	assert.Equal(t, large, update.fields["body"])
+}
+
+func TestFieldLimitHookNoBlobSink(t *testing.T) {
+	hook := FieldLimitHook("Document", FieldLimit{Field: "body", MaxSize: 4, Strategy: LimitExternal})
+
+	mutator := hook(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return nil, nil
+	}))
+
+	m := &testLimitMutation{op: ent.OpCreate, fields: map[string]ent.Value{"body": "too large"}}
+
+	_, err := mutator.Mutate(context.Background(), m)
+	assert.ErrorIs(t, err, ErrBlobSinkNotSet)
+}
@@ -0,0 +1,30 @@
+package enthistory
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/mixin"
+)
+
+// Mixin tracks the history of the schema it is added to, it adds the history hooks using the mutation type of the
+// schema (e.g. enthistory.Mixin[*ent.TodoMutation]{}) and marks the schema for history tracking when using
+// WithOptIn; this is an alternative to the history annotations and registering the history hooks on the client
+type Mixin[T Mutation] struct {
+	mixin.Schema
+	// Options configure the history hooks added to the schema
+	Options []HookOption
+}
+
+// Hooks of the Mixin
+func (m Mixin[T]) Hooks() []ent.Hook {
+	return HistoryHooks[T](m.Options...)
+}
+
+// Annotations of the Mixin
+func (Mixin[T]) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		Annotations{
+			Track: true,
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHistoryMutation is the mutation of a tracked schema
+type testHistoryMutation struct {
+	ent.Mutation
+}
+
+func (testHistoryMutation) CreateHistoryFromCreate(context.Context) error { return nil }
+
+func (testHistoryMutation) CreateHistoryFromUpdate(context.Context) error { return nil }
+
+func (testHistoryMutation) CreateHistoryFromDelete(context.Context) error { return nil }
+
+func TestMixin(t *testing.T) {
+	m := Mixin[*testHistoryMutation]{}
+
+	assert.Len(t, m.Hooks(), 3)
+
+	annotations := m.Annotations()
+	require.Len(t, annotations, 1)
+	assert.Equal(t, Annotations{Track: true}, annotations[0])
+}
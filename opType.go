@@ -1,6 +1,7 @@
 package enthistory
 
 import (
+	"context"
 	"database/sql/driver"
 	"io"
 	"strconv"
@@ -16,6 +17,13 @@ const (
 	OpTypeUpdate OpType = "UPDATE"
 	// OpTypeDelete is the delete operation
 	OpTypeDelete OpType = "DELETE"
+	// OpTypeSoftDelete is the soft delete operation, an update that marks the record as deleted
+	OpTypeSoftDelete OpType = "SOFT_DELETE"
+	// OpTypeRestore is the restore operation, an update that restores a soft deleted record
+	OpTypeRestore OpType = "RESTORE"
+	// OpTypeExternal is the operation of the history rows written by the triggers capturing the out-of-band writes,
+	// which is registered by WithTriggers
+	OpTypeExternal OpType = "EXTERNAL"
 )
 
 // opTypes are the possible values that can be used
@@ -23,6 +31,40 @@ var opTypes = []string{
 	OpTypeInsert.String(),
 	OpTypeUpdate.String(),
 	OpTypeDelete.String(),
+	OpTypeSoftDelete.String(),
+	OpTypeRestore.String(),
+}
+
+// customOpTypes returns the operations that are not built-in, without duplicates or empty values
+func customOpTypes(ops []OpType) []string {
+	custom := []string{}
+
+	for _, op := range ops {
+		if op == "" || in(op.String(), opTypes) || in(op.String(), custom) {
+			continue
+		}
+
+		custom = append(custom, op.String())
+	}
+
+	return custom
+}
+
+// operationKey is the context key for the operation recorded on history rows
+type operationKey struct{}
+
+// NewOperationContext returns a copy of the context with the operation, history rows created with this
+// context are stamped with the operation instead of the ent operation (e.g. IMPORT instead of INSERT);
+// operations other than the built-in ones must be registered using WithOperations
+func NewOperationContext(ctx context.Context, op OpType) context.Context {
+	return context.WithValue(ctx, operationKey{}, op)
+}
+
+// OperationFromContext returns the operation from the context, if it was set
+func OperationFromContext(ctx context.Context) (OpType, bool) {
+	op, ok := ctx.Value(operationKey{}).(OpType)
+
+	return op, ok && op != ""
 }
 
 // Values provides list valid values for Enum.
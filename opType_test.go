@@ -0,0 +1,77 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomOpTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []OpType
+		want []string
+	}{
+		{
+			name: "custom operations",
+			ops:  []OpType{"IMPORT", "MERGE"},
+			want: []string{"IMPORT", "MERGE"},
+		},
+		{
+			name: "built-in operations are skipped",
+			ops:  []OpType{OpTypeInsert, "IMPORT", OpTypeRestore},
+			want: []string{"IMPORT"},
+		},
+		{
+			name: "duplicates and empty values are skipped",
+			ops:  []OpType{"IMPORT", "", "IMPORT"},
+			want: []string{"IMPORT"},
+		},
+		{
+			name: "no operations",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := customOpTypes(tt.ops)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOperationFromContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		want   OpType
+		wantOk bool
+	}{
+		{
+			name:   "happy path",
+			ctx:    NewOperationContext(context.Background(), "IMPORT"),
+			want:   "IMPORT",
+			wantOk: true,
+		},
+		{
+			name:   "empty operation",
+			ctx:    NewOperationContext(context.Background(), ""),
+			want:   "",
+			wantOk: false,
+		},
+		{
+			name:   "not set",
+			ctx:    context.Background(),
+			want:   "",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := OperationFromContext(tt.ctx)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package enthistory
+
+import (
+	"context"
+
+	"entgo.io/ent"
+)
+
+// orderedQueryOps are the query operations returning rows, which are ordered by the default order interceptors
+var orderedQueryOps = []string{
+	ent.OpQueryFirst,
+	ent.OpQueryFirstID,
+	ent.OpQueryAll,
+	ent.OpQueryIDs,
+}
+
+// DefaultOrdered checks if the query of the context returns rows, which are ordered by history_time by the default
+// order interceptors generated for the history types when using WithDefaultOrder
+func DefaultOrdered(ctx context.Context) bool {
+	qc := ent.QueryFromContext(ctx)
+
+	return qc != nil && in(qc.Op, orderedQueryOps)
+}
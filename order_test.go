@@ -0,0 +1,50 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOrdered(t *testing.T) {
+	tests := []struct {
+		name string
+		op   string
+		want bool
+	}{
+		{
+			name: "all",
+			op:   ent.OpQueryAll,
+			want: true,
+		},
+		{
+			name: "first",
+			op:   ent.OpQueryFirst,
+			want: true,
+		},
+		{
+			name: "ids",
+			op:   ent.OpQueryIDs,
+			want: true,
+		},
+		{
+			name: "count",
+			op:   ent.OpQueryCount,
+		},
+		{
+			name: "exist",
+			op:   ent.OpQueryExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ent.NewQueryContext(context.Background(), &ent.QueryContext{Op: tt.op})
+
+			assert.Equal(t, tt.want, DefaultOrdered(ctx))
+		})
+	}
+
+	assert.False(t, DefaultOrdered(context.Background()))
+}
@@ -0,0 +1,137 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/privacy"
+)
+
+// OwnerResolver returns the ids of the owners of the object type (organization or user) the viewer on the context can
+// read the history of, e.g. the organizations of the authenticated user, or the user itself
+type OwnerResolver func(ctx context.Context, objectType string) ([]string, error)
+
+var (
+	// ownerResolver is the owner resolver set using SetOwnerResolver
+	ownerResolver OwnerResolver
+	// ownerResolverMu guards the owner resolver
+	ownerResolverMu sync.RWMutex
+)
+
+// SetOwnerResolver sets the owner resolver used by the owner read rules of the history schemas when using
+// WithOwnerReadRules; without an owner resolver all history queries are denied, except when using the system context.
+// This is usually called when the application starts
+func SetOwnerResolver(fn OwnerResolver) {
+	ownerResolverMu.Lock()
+	defer ownerResolverMu.Unlock()
+
+	ownerResolver = fn
+}
+
+// ownerQueryRule is the privacy rule filtering history queries to the owners of the viewer
+type ownerQueryRule struct {
+	// field is the field holding the owner of the history rows
+	field string
+	// objectType is the object type of the owner (organization or user)
+	objectType string
+}
+
+// OwnerQueryRule returns a privacy rule filtering history queries to the rows whose owner field holds one of the owners
+// of the object type returned by the owner resolver, and skips to the next rules; queries are denied with
+// ErrHistoryQueryDenied when the viewer has no owners, and are not filtered when using the system context. This is
+// added to the generated policy of the history schemas of owned schemas when using WithOwnerReadRules
+func OwnerQueryRule(field, objectType string) privacy.QueryRule {
+	return ownerQueryRule{
+		field:      field,
+		objectType: objectType,
+	}
+}
+
+// EvalQuery filters the history query to the owners of the viewer
+func (r ownerQueryRule) EvalQuery(ctx context.Context, q ent.Query) error {
+	if HasToken(ctx, q, TokenSystem) {
+		return privacy.Skip
+	}
+
+	ownerResolverMu.RLock()
+	resolve := ownerResolver
+	ownerResolverMu.RUnlock()
+
+	if resolve == nil {
+		return privacy.Denyf("%w: no owner resolver", ErrHistoryQueryDenied)
+	}
+
+	owners, err := resolve(ctx, r.objectType)
+	if err != nil {
+		return privacy.Denyf("%w: %v", ErrHistoryQueryDenied, err)
+	}
+
+	if len(owners) == 0 {
+		return privacy.Denyf("%w: no %s owners", ErrHistoryQueryDenied, r.objectType)
+	}
+
+	applyQueryOption(q, "Where", sql.FieldIn(r.field, owners...))
+
+	return privacy.Skip
+}
+
+// ownedPolicy is the privacy policy of the history schemas using the owner read rules without the authz policy
+type ownedPolicy struct {
+	// rule is the owner query rule, evaluated before the policy
+	rule privacy.QueryRule
+	// policy is the policy of the history schema, if any
+	policy ent.Policy
+}
+
+// OwnedPolicy returns a privacy policy filtering history queries using the OwnerQueryRule of the owner field and object
+// type before evaluating the policy, which can be nil to allow all queries and mutations
+func OwnedPolicy(policy ent.Policy, field, objectType string) ent.Policy {
+	return ownedPolicy{
+		rule:   OwnerQueryRule(field, objectType),
+		policy: policy,
+	}
+}
+
+// EvalQuery filters the history query to the owners of the viewer, and evaluates the query using the policy
+func (p ownedPolicy) EvalQuery(ctx context.Context, q ent.Query) error {
+	if err := p.rule.EvalQuery(ctx, q); err != nil && !errors.Is(err, privacy.Skip) {
+		return err
+	}
+
+	if p.policy == nil {
+		return nil
+	}
+
+	return p.policy.EvalQuery(ctx, q)
+}
+
+// EvalMutation evaluates the history mutation using the policy
+func (p ownedPolicy) EvalMutation(ctx context.Context, m ent.Mutation) error {
+	if p.policy == nil {
+		return nil
+	}
+
+	return p.policy.EvalMutation(ctx, m)
+}
+
+// applyQueryOption adds the option to the query using the variadic method (e.g. Where or Order), the generated
+// queries each accept their own predicate and order option types, which are all functions of a sql selector
+func applyQueryOption(q ent.Query, method string, p func(*sql.Selector)) {
+	m := reflect.ValueOf(q).MethodByName(method)
+	if !m.IsValid() || !m.Type().IsVariadic() || m.Type().NumIn() != 1 {
+		return
+	}
+
+	optionType := m.Type().In(0).Elem()
+
+	option := reflect.ValueOf(p)
+	if !option.Type().ConvertibleTo(optionType) {
+		return
+	}
+
+	m.Call([]reflect.Value{option.Convert(optionType)})
+}
@@ -0,0 +1,112 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/privacy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPredicate is the predicate type of the test query, like the generated predicate types
+type testPredicate func(*sql.Selector)
+
+// testQuery records the predicates added by the rules
+type testQuery struct {
+	predicates []testPredicate
+}
+
+func (q *testQuery) Where(ps ...testPredicate) *testQuery {
+	q.predicates = append(q.predicates, ps...)
+
+	return q
+}
+
+func (*testQuery) HistoryTokens(ctx context.Context) Token {
+	return testTokens(ctx)
+}
+
+func TestOwnerQueryRule(t *testing.T) {
+	t.Cleanup(func() { SetOwnerResolver(nil) })
+
+	rule := OwnerQueryRule("owner_id", OwnerObjectTypeOrganization)
+
+	// all queries are denied without an owner resolver
+	q := &testQuery{}
+	err := rule.EvalQuery(context.Background(), q)
+	require.ErrorIs(t, err, privacy.Deny)
+	require.ErrorIs(t, err, ErrHistoryQueryDenied)
+
+	// the system context is not filtered
+	err = rule.EvalQuery(withTestTokens(context.Background(), TokenSystem), q)
+	require.ErrorIs(t, err, privacy.Skip)
+	assert.Empty(t, q.predicates)
+
+	type viewerKey struct{}
+
+	SetOwnerResolver(func(ctx context.Context, objectType string) ([]string, error) {
+		assert.Equal(t, OwnerObjectTypeOrganization, objectType)
+
+		switch viewer, _ := ctx.Value(viewerKey{}).(string); viewer {
+		case "member":
+			return []string{"org1", "org2"}, nil
+		case "broken":
+			return nil, errors.New("session expired")
+		default:
+			return nil, nil
+		}
+	})
+
+	err = rule.EvalQuery(context.WithValue(context.Background(), viewerKey{}, "member"), q)
+	require.ErrorIs(t, err, privacy.Skip)
+	require.Len(t, q.predicates, 1)
+
+	selector := sql.Select("*").From(sql.Table("todo_history"))
+	q.predicates[0](selector)
+
+	query, args := selector.Query()
+	assert.Contains(t, query, "`owner_id` IN (?, ?)")
+	assert.Equal(t, []any{"org1", "org2"}, args)
+
+	err = rule.EvalQuery(context.WithValue(context.Background(), viewerKey{}, "broken"), &testQuery{})
+	require.ErrorIs(t, err, ErrHistoryQueryDenied)
+	assert.Contains(t, err.Error(), "session expired")
+
+	err = rule.EvalQuery(context.WithValue(context.Background(), viewerKey{}, "guest"), &testQuery{})
+	require.ErrorIs(t, err, ErrHistoryQueryDenied)
+}
+
+func TestOwnedPolicy(t *testing.T) {
+	t.Cleanup(func() { SetOwnerResolver(nil) })
+
+	SetOwnerResolver(func(context.Context, string) ([]string, error) {
+		return []string{"org1"}, nil
+	})
+
+	ctx := context.Background()
+
+	// without a policy the filtered queries and all mutations are allowed
+	q := &testQuery{}
+	policy := OwnedPolicy(nil, "owner_id", OwnerObjectTypeOrganization)
+	require.NoError(t, policy.EvalQuery(ctx, q))
+	assert.Len(t, q.predicates, 1)
+	require.NoError(t, policy.EvalMutation(ctx, testMutation{op: ent.OpCreate}))
+
+	// the policy is evaluated once the query is filtered
+	q = &testQuery{}
+	policy = OwnedPolicy(HistoryPolicy(), "owner_id", OwnerObjectTypeOrganization)
+	require.NoError(t, policy.EvalQuery(ctx, q))
+	assert.Len(t, q.predicates, 1)
+	require.ErrorIs(t, policy.EvalMutation(ctx, testMutation{op: ent.OpCreate}), ErrHistoryMutationDenied)
+
+	// denied queries do not evaluate the policy
+	SetOwnerResolver(func(context.Context, string) ([]string, error) {
+		return nil, nil
+	})
+
+	require.ErrorIs(t, policy.EvalQuery(ctx, &testQuery{}), ErrHistoryQueryDenied)
+}
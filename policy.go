@@ -0,0 +1,130 @@
+package enthistory
+
+import (
+	"context"
+
+	"entgo.io/ent"
+	"entgo.io/ent/privacy"
+)
+
+// Token is a privacy token minted on the context by the generated code, the context key of the tokens is unexported in
+// the generated package so they cannot be set by application code
+type Token uint8
+
+const (
+	// TokenSystem allows the internal queries and mutations of the history hooks and the generated methods, it is
+	// minted by the history hooks and the generated NewHistorySystemContext
+	TokenSystem Token = 1 << iota
+	// TokenHistoryHook marks the mutations created by the history hooks, it is minted by the generated methods
+	// creating the history rows
+	TokenHistoryHook
+	// TokenPurge allows history rows to be deleted, it is minted by the generated Purge, DeleteHistoryByRef, and
+	// CompactHistory methods of the history clients
+	TokenPurge
+)
+
+// Tokenized is implemented by the generated queries and mutations, returning the privacy tokens minted on the context
+// by the generated code
+type Tokenized interface {
+	HistoryTokens(ctx context.Context) Token
+}
+
+// HasToken checks if the context holds the privacy token minted by the generated code of the query or mutation
+func HasToken(ctx context.Context, v any, token Token) bool {
+	tokenized, ok := v.(Tokenized)
+
+	return ok && tokenized.HistoryTokens(ctx)&token != 0
+}
+
+// systemContextRule is the privacy rule allowing the queries and mutations using the system token
+type systemContextRule struct{}
+
+// SystemContextRule is a privacy rule that allows queries and mutations using the system token, minted by the
+// history hooks and the generated NewHistorySystemContext, and skips all others; this is added to the generated authz
+// policy of the history schemas, and should be added first to deny-by-default policies of the tracked schemas
+func SystemContextRule() privacy.QueryMutationRule {
+	return systemContextRule{}
+}
+
+// EvalQuery allows the query using the system token
+func (systemContextRule) EvalQuery(ctx context.Context, q ent.Query) error {
+	if HasToken(ctx, q, TokenSystem) {
+		return privacy.Allow
+	}
+
+	return privacy.Skip
+}
+
+// EvalMutation allows the mutation using the system token
+func (systemContextRule) EvalMutation(ctx context.Context, m ent.Mutation) error {
+	if HasToken(ctx, m, TokenSystem) {
+		return privacy.Allow
+	}
+
+	return privacy.Skip
+}
+
+// HistoryMutationRule is a privacy rule that allows the mutations created by the history hooks, and deletes
+// using the purge token minted by the generated Purge, DeleteHistoryByRef, and CompactHistory methods, all other
+// mutations are denied with ErrHistoryMutationDenied
+func HistoryMutationRule() privacy.MutationRule {
+	return privacy.MutationRuleFunc(func(ctx context.Context, m ent.Mutation) error {
+		if HasToken(ctx, m, TokenHistoryHook) {
+			return privacy.Allow
+		}
+
+		if m.Op().Is(ent.OpDelete|ent.OpDeleteOne) && HasToken(ctx, m, TokenPurge) {
+			return privacy.Allow
+		}
+
+		return privacy.Denyf("%w: %s %s", ErrHistoryMutationDenied, m.Op(), m.Type())
+	})
+}
+
+// HistoryPolicy is the privacy policy of the history schemas when using WithHistoryPolicy, this makes the
+// history tables append-only from application code by only allowing mutations created by the history hooks,
+// and deletes by the generated Purge and DeleteHistoryByRef methods
+func HistoryPolicy() ent.Policy {
+	return privacy.Policy{
+		Mutation: privacy.MutationPolicy{
+			HistoryMutationRule(),
+		},
+	}
+}
+
+// inheritedPolicy is the privacy policy of the history schemas when using WithInheritedPolicy
+type inheritedPolicy struct {
+	// query is the policy of the original schema, used to evaluate the history queries
+	query ent.Policy
+	// mutation is the policy used to evaluate the history mutations, if any
+	mutation ent.Policy
+}
+
+// InheritedPolicy returns a privacy policy evaluating history queries using the policy of the original schema, and
+// history mutations using the mutation policy, e.g. HistoryPolicy, which can be nil to allow all mutations; the
+// original policy should use rules that are not typed to the original query (e.g. privacy.QueryRuleFunc), typed
+// rules (e.g. privacy.TodoQueryRuleFunc) deny the history queries
+func InheritedPolicy(original, mutation ent.Policy) ent.Policy {
+	return inheritedPolicy{
+		query:    original,
+		mutation: mutation,
+	}
+}
+
+// EvalQuery evaluates the history query using the policy of the original schema
+func (p inheritedPolicy) EvalQuery(ctx context.Context, q ent.Query) error {
+	if p.query == nil {
+		return nil
+	}
+
+	return p.query.EvalQuery(ctx, q)
+}
+
+// EvalMutation evaluates the history mutation using the mutation policy
+func (p inheritedPolicy) EvalMutation(ctx context.Context, m ent.Mutation) error {
+	if p.mutation == nil {
+		return nil
+	}
+
+	return p.mutation.EvalMutation(ctx, m)
+}
@@ -0,0 +1,160 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/privacy"
+	"github.com/stretchr/testify/assert"
+)
+
+// testTokensKey is the context key of the privacy tokens of the test queries and mutations, like the generated key
+type testTokensKey struct{}
+
+// withTestTokens returns a copy of the context holding the privacy tokens of the test queries and mutations
+func withTestTokens(ctx context.Context, tokens Token) context.Context {
+	return context.WithValue(ctx, testTokensKey{}, tokens)
+}
+
+// testTokens returns the privacy tokens of the test queries and mutations on the context
+func testTokens(ctx context.Context) Token {
+	tokens, _ := ctx.Value(testTokensKey{}).(Token)
+
+	return tokens
+}
+
+// testMutation is the mutation evaluated by the history policy
+type testMutation struct {
+	ent.Mutation
+	op ent.Op
+}
+
+func (testMutation) HistoryTokens(ctx context.Context) Token {
+	return testTokens(ctx)
+}
+
+func (m testMutation) Op() ent.Op {
+	return m.op
+}
+
+func (testMutation) Type() string {
+	return "TodoHistory"
+}
+
+func TestHistoryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		op      ent.Op
+		wantErr error
+	}{
+		{
+			name:    "history hook",
+			ctx:     withTestTokens(context.Background(), TokenSystem|TokenHistoryHook),
+			op:      ent.OpCreate,
+			wantErr: privacy.Allow,
+		},
+		{
+			name:    "direct mutation",
+			ctx:     context.Background(),
+			op:      ent.OpCreate,
+			wantErr: ErrHistoryMutationDenied,
+		},
+		{
+			name:    "system mutation",
+			ctx:     withTestTokens(context.Background(), TokenSystem),
+			op:      ent.OpCreate,
+			wantErr: ErrHistoryMutationDenied,
+		},
+		{
+			name:    "direct delete",
+			ctx:     context.Background(),
+			op:      ent.OpDelete,
+			wantErr: ErrHistoryMutationDenied,
+		},
+		{
+			name:    "purge delete",
+			ctx:     withTestTokens(context.Background(), TokenPurge),
+			op:      ent.OpDelete,
+			wantErr: privacy.Allow,
+		},
+		{
+			name:    "purge update",
+			ctx:     withTestTokens(context.Background(), TokenPurge),
+			op:      ent.OpUpdate,
+			wantErr: ErrHistoryMutationDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := HistoryPolicy().EvalMutation(tt.ctx, testMutation{op: tt.op})
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestHasToken(t *testing.T) {
+	ctx := withTestTokens(context.Background(), TokenSystem|TokenPurge)
+
+	assert.True(t, HasToken(ctx, testMutation{}, TokenSystem))
+	assert.True(t, HasToken(ctx, testMutation{}, TokenPurge))
+	assert.False(t, HasToken(ctx, testMutation{}, TokenHistoryHook))
+	assert.False(t, HasToken(context.Background(), testMutation{}, TokenSystem))
+
+	// the tokens are only read by the generated queries and mutations
+	assert.False(t, HasToken(ctx, nil, TokenSystem))
+}
+
+func TestInheritedPolicy(t *testing.T) {
+	original := privacy.Policy{
+		Query: privacy.QueryPolicy{
+			privacy.AlwaysDenyRule(),
+		},
+	}
+
+	tests := []struct {
+		name            string
+		mutation        ent.Policy
+		wantQueryErr    error
+		wantMutationErr error
+	}{
+		{
+			name:         "original policy without mutation policy",
+			wantQueryErr: privacy.Deny,
+		},
+		{
+			name:            "original policy with history policy",
+			mutation:        HistoryPolicy(),
+			wantQueryErr:    privacy.Deny,
+			wantMutationErr: ErrHistoryMutationDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := InheritedPolicy(original, tt.mutation)
+
+			err := policy.EvalQuery(context.Background(), nil)
+			assert.ErrorIs(t, err, tt.wantQueryErr)
+
+			err = policy.EvalMutation(context.Background(), testMutation{op: ent.OpCreate})
+			if tt.wantMutationErr == nil {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.wantMutationErr)
+		})
+	}
+}
+
+func TestSystemContextRule(t *testing.T) {
+	system := withTestTokens(context.Background(), TokenSystem)
+
+	assert.ErrorIs(t, SystemContextRule().EvalQuery(context.Background(), &testQuery{}), privacy.Skip)
+	assert.ErrorIs(t, SystemContextRule().EvalQuery(system, &testQuery{}), privacy.Allow)
+	assert.ErrorIs(t, SystemContextRule().EvalMutation(context.Background(), testMutation{}), privacy.Skip)
+	assert.ErrorIs(t, SystemContextRule().EvalMutation(system, testMutation{}), privacy.Allow)
+	assert.ErrorIs(t, SystemContextRule().EvalMutation(withTestTokens(context.Background(), TokenPurge), testMutation{}), privacy.Skip)
+}
@@ -0,0 +1,172 @@
+package enthistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// postgresMaxInsertRows is the number of rows inserted by a single INSERT statement of the PostgresSink, keeping the
+// number of arguments below the limit of Postgres
+const postgresMaxInsertRows = 1000
+
+// PostgresSink is a Sink writing the history events to a Postgres table, created using PostgresDDL, in batches; the
+// events are buffered and written when the batch size is reached, at the flush interval, and when the sink is closed.
+// The batches are written using multi-row INSERT statements by default, and using COPY for high volumes of history
+// when the write mode of the sink configuration is WriteCopy (e.g. using lib/pq) or WriteCopyFrom (e.g. using pgx)
+type PostgresSink struct {
+	*bufferedSink
+
+	db    *sql.DB
+	table string
+}
+
+// NewPostgresSink returns a sink writing the history events to the Postgres table in batches, the sink must be closed
+// to write the buffered events; ErrCopyFromNotSet is returned when using WriteCopyFrom without a CopyFrom function
+func NewPostgresSink(db *sql.DB, table string, opts ...SinkOption) (*PostgresSink, error) {
+	config := newSinkConfig(opts...)
+
+	if config.WriteMode == WriteCopyFrom && config.CopyFrom == nil {
+		return nil, ErrCopyFromNotSet
+	}
+
+	s := &PostgresSink{
+		db:    db,
+		table: table,
+	}
+
+	s.bufferedSink = newBufferedSink(table, config, s.write)
+
+	return s, nil
+}
+
+// write writes the history events to Postgres using the write mode of the sink, the values of the fields are stored
+// as JSON
+func (s *PostgresSink) write(ctx context.Context, events []HistoryEvent) error {
+	rows, err := sinkRows(events)
+	if err != nil {
+		return err
+	}
+
+	switch s.config.WriteMode {
+	case WriteCopyFrom:
+		return s.config.CopyFrom(ctx, s.table, sinkColumns, rows)
+	case WriteCopy:
+		return s.copy(ctx, rows)
+	default:
+		return s.insert(ctx, rows)
+	}
+}
+
+// insert writes the rows using multi-row INSERT statements in a transaction
+func (s *PostgresSink) insert(ctx context.Context, rows [][]any) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(rows); start += postgresMaxInsertRows {
+		query, args := postgresInsert(s.table, rows[start:min(start+postgresMaxInsertRows, len(rows))])
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// copy writes the rows using COPY ... FROM STDIN in a transaction, the rows are sent by executing the prepared statement
+// for each row, and the copy is completed by executing it without arguments
+func (s *PostgresSink) copy(ctx context.Context, rows [][]any) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, postgresCopy(s.table))
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			_ = stmt.Close()
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if err := stmt.Close(); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// postgresInsert returns the multi-row INSERT statement of the rows, and its arguments
+func postgresInsert(table string, rows [][]any) (string, []any) {
+	var b strings.Builder
+
+	args := make([]any, 0, len(rows)*len(sinkColumns))
+
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(sinkColumns, ", "))
+
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteByte('(')
+
+		for j, value := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+
+			args = append(args, value)
+			fmt.Fprintf(&b, "$%d", len(args))
+		}
+
+		b.WriteByte(')')
+	}
+
+	return b.String(), args
+}
+
+// postgresCopy returns the COPY ... FROM STDIN statement of the table
+func postgresCopy(table string) string {
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN", table, strings.Join(sinkColumns, ", "))
+}
+
+// PostgresDDL returns the DDL creating the Postgres table the history events are written to by the PostgresSink,
+// indexed by entity, ref, and history time, the values of the fields of the history rows are stored as JSON
+func PostgresDDL(table string) string {
+	index := strings.ReplaceAll(table, ".", "_") + "_entity_ref_history_time"
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n"+
+		"\thistory_type text NOT NULL,\n"+
+		"\tentity text NOT NULL,\n"+
+		"\tid text NOT NULL,\n"+
+		"\tref text NOT NULL,\n"+
+		"\toperation text NOT NULL,\n"+
+		"\thistory_time timestamptz NOT NULL,\n"+
+		"\tfields jsonb NOT NULL\n"+
+		");\n"+
+		"CREATE INDEX IF NOT EXISTS %s ON %s (entity, ref, history_time);\n", table, index, table)
+}
@@ -0,0 +1,217 @@
+package enthistory
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSQLDriver is a database/sql driver recording the statements executed, and their arguments
+type recordingSQLDriver struct {
+	mu    sync.Mutex
+	stmts []string
+	args  [][]driver.Value
+}
+
+func (d *recordingSQLDriver) Open(string) (driver.Conn, error) {
+	return &recordingSQLConn{driver: d}, nil
+}
+
+type recordingSQLConn struct {
+	driver *recordingSQLDriver
+}
+
+func (c *recordingSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingSQLStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *recordingSQLConn) Close() error {
+	return nil
+}
+
+func (c *recordingSQLConn) Begin() (driver.Tx, error) {
+	c.driver.record("BEGIN", nil)
+
+	return c, nil
+}
+
+func (c *recordingSQLConn) Commit() error {
+	c.driver.record("COMMIT", nil)
+
+	return nil
+}
+
+func (c *recordingSQLConn) Rollback() error {
+	c.driver.record("ROLLBACK", nil)
+
+	return nil
+}
+
+type recordingSQLStmt struct {
+	driver *recordingSQLDriver
+	query  string
+}
+
+func (s *recordingSQLStmt) Close() error {
+	return nil
+}
+
+func (s *recordingSQLStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.record(s.query, args)
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+func (d *recordingSQLDriver) record(stmt string, args []driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stmts = append(d.stmts, stmt)
+	d.args = append(d.args, args)
+}
+
+// openRecordingDB returns a database using a new recording driver
+func openRecordingDB(t *testing.T) (*sql.DB, *recordingSQLDriver) {
+	t.Helper()
+
+	drv := &recordingSQLDriver{}
+
+	db := sql.OpenDB(recordingConnector{drv})
+	t.Cleanup(func() { db.Close() })
+
+	return db, drv
+}
+
+type recordingConnector struct {
+	driver *recordingSQLDriver
+}
+
+func (c recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open("")
+}
+
+func (c recordingConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func testSinkEvents(n int) []HistoryEvent {
+	events := make([]HistoryEvent, 0, n)
+
+	for i := range n {
+		events = append(events, HistoryEvent{
+			Type:        "TodoHistory",
+			Entity:      "Todo",
+			ID:          i + 1,
+			Ref:         1,
+			Operation:   OpTypeUpdate,
+			HistoryTime: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			Values:      map[string]any{"name": "todo"},
+		})
+	}
+
+	return events
+}
+
+func TestPostgresSinkInsert(t *testing.T) {
+	db, drv := openRecordingDB(t)
+
+	s, err := NewPostgresSink(db, "history_events", WithSinkFlushInterval(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(context.Background(), testSinkEvents(2)))
+	require.NoError(t, s.Close())
+
+	require.Equal(t, []string{
+		"BEGIN",
+		"INSERT INTO history_events (history_type, entity, id, ref, operation, history_time, fields) VALUES " +
+			"($1, $2, $3, $4, $5, $6, $7), ($8, $9, $10, $11, $12, $13, $14)",
+		"COMMIT",
+	}, drv.stmts)
+
+	assert.Len(t, drv.args[1], 14)
+	assert.Equal(t, []driver.Value{"TodoHistory", "Todo", "1", "1", "UPDATE"}, drv.args[1][:5])
+	assert.Equal(t, `{"name":"todo"}`, drv.args[1][6])
+}
+
+func TestPostgresSinkCopy(t *testing.T) {
+	db, drv := openRecordingDB(t)
+
+	s, err := NewPostgresSink(db, "history_events", WithSinkWriteMode(WriteCopy), WithSinkFlushInterval(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(context.Background(), testSinkEvents(2)))
+	require.NoError(t, s.Close())
+
+	stmt := "COPY history_events (history_type, entity, id, ref, operation, history_time, fields) FROM STDIN"
+
+	require.Equal(t, []string{"BEGIN", stmt, stmt, stmt, "COMMIT"}, drv.stmts)
+	assert.Len(t, drv.args[1], 7)
+	assert.Len(t, drv.args[2], 7)
+	assert.Empty(t, drv.args[3])
+}
+
+func TestPostgresSinkCopyFrom(t *testing.T) {
+	var (
+		table   string
+		columns []string
+		rows    [][]any
+	)
+
+	copyFrom := func(_ context.Context, t string, c []string, r [][]any) error {
+		table, columns, rows = t, c, r
+
+		return nil
+	}
+
+	_, err := NewPostgresSink(nil, "history_events", WithSinkWriteMode(WriteCopyFrom))
+	require.ErrorIs(t, err, ErrCopyFromNotSet)
+
+	s, err := NewPostgresSink(nil, "history_events", WithSinkCopyFrom(copyFrom), WithSinkFlushInterval(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(context.Background(), testSinkEvents(3)))
+	require.NoError(t, s.Flush(context.Background()))
+
+	assert.Equal(t, "history_events", table)
+	assert.Equal(t, sinkColumns, columns)
+	assert.Len(t, rows, 3)
+
+	require.NoError(t, s.Close())
+}
+
+func TestPostgresInsertBatches(t *testing.T) {
+	db, drv := openRecordingDB(t)
+
+	s, err := NewPostgresSink(db, "history_events", WithSinkBatchSize(5000), WithSinkFlushInterval(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(context.Background(), testSinkEvents(postgresMaxInsertRows+1)))
+	require.NoError(t, s.Close())
+
+	require.Len(t, drv.stmts, 4)
+	assert.Len(t, drv.args[1], postgresMaxInsertRows*len(sinkColumns))
+	assert.Len(t, drv.args[2], len(sinkColumns))
+}
+
+func TestPostgresDDL(t *testing.T) {
+	ddl := PostgresDDL("history.events")
+
+	assert.True(t, strings.HasPrefix(ddl, "CREATE TABLE IF NOT EXISTS history.events ("))
+	assert.Contains(t, ddl, "fields jsonb NOT NULL")
+	assert.Contains(t, ddl, "CREATE INDEX IF NOT EXISTS history_events_entity_ref_history_time ON history.events (entity, ref, history_time);")
+}
@@ -0,0 +1,91 @@
+package enthistory
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultPurgeBatchSize is the number of history rows deleted by each batch of the generated PurgeBatched methods
+	DefaultPurgeBatchSize = 1000
+)
+
+// PurgeConfig is the configuration of the generated PurgeBatched methods of the history clients, deleting the history
+// rows in batches so purging years of history does not hold long locks, or flood replication with a single delete
+type PurgeConfig struct {
+	// BatchSize is the maximum number of history rows deleted by each batch, defaults to DefaultPurgeBatchSize
+	BatchSize int
+	// Pause is the time waited between batches, letting replicas and other writers catch up
+	Pause time.Duration
+	// MaxBatches stops the purge after the number of batches, e.g. to fit a maintenance window, the remaining rows are
+	// deleted by the next purge; there is no limit when zero
+	MaxBatches int
+	// Progress is called once each batch is deleted, if set
+	Progress PurgeProgressFunc
+}
+
+// PurgeProgress is the progress of a purge by the generated PurgeBatched methods
+type PurgeProgress struct {
+	// Batches is the number of batches deleted so far
+	Batches int
+	// Deleted is the number of history rows deleted so far
+	Deleted int
+}
+
+// PurgeProgressFunc is called by the generated PurgeBatched methods once each batch is deleted, e.g. to log the
+// progress of a retention job
+type PurgeProgressFunc func(ctx context.Context, progress PurgeProgress)
+
+// PurgeBatches deletes history rows in batches using deleteBatch, which deletes at most limit rows and returns the
+// number of rows deleted; batches are deleted until one deletes fewer rows than the batch size, or the max batches of
+// the config is reached, pausing between batches. The number of rows deleted is returned with the error of the context
+// if it is done before the purge completes. This is used by the generated PurgeBatched methods of the history clients
+func PurgeBatches(ctx context.Context, config PurgeConfig, deleteBatch func(ctx context.Context, limit int) (int, error)) (int, error) {
+	size := config.BatchSize
+	if size <= 0 {
+		size = DefaultPurgeBatchSize
+	}
+
+	var progress PurgeProgress
+
+	for config.MaxBatches <= 0 || progress.Batches < config.MaxBatches {
+		if err := ctx.Err(); err != nil {
+			return progress.Deleted, err
+		}
+
+		n, err := deleteBatch(ctx, size)
+		progress.Deleted += n
+
+		if err != nil {
+			return progress.Deleted, err
+		}
+
+		if n == 0 {
+			break
+		}
+
+		progress.Batches++
+
+		if config.Progress != nil {
+			config.Progress(ctx, progress)
+		}
+
+		if n < size || progress.Batches == config.MaxBatches {
+			break
+		}
+
+		if config.Pause > 0 {
+			timer := time.NewTimer(config.Pause)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+
+				return progress.Deleted, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+
+	return progress.Deleted, nil
+}
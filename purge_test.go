@@ -0,0 +1,132 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// purgeRows returns a delete batch func deleting at most limit of the rows, recording the limit of each batch
+func purgeRows(rows int, limits *[]int) func(ctx context.Context, limit int) (int, error) {
+	return func(_ context.Context, limit int) (int, error) {
+		*limits = append(*limits, limit)
+
+		n := min(rows, limit)
+		rows -= n
+
+		return n, nil
+	}
+}
+
+func TestPurgeBatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       int
+		config     PurgeConfig
+		want       int
+		wantLimits []int
+	}{
+		{
+			name:       "default batch size",
+			rows:       1500,
+			want:       1500,
+			wantLimits: []int{DefaultPurgeBatchSize, DefaultPurgeBatchSize},
+		},
+		{
+			name:       "batch size",
+			rows:       25,
+			config:     PurgeConfig{BatchSize: 10},
+			want:       25,
+			wantLimits: []int{10, 10, 10},
+		},
+		{
+			name:       "exact batches",
+			rows:       20,
+			config:     PurgeConfig{BatchSize: 10},
+			want:       20,
+			wantLimits: []int{10, 10, 10},
+		},
+		{
+			name:       "max batches",
+			rows:       100,
+			config:     PurgeConfig{BatchSize: 10, MaxBatches: 3},
+			want:       30,
+			wantLimits: []int{10, 10, 10},
+		},
+		{
+			name:       "no rows",
+			config:     PurgeConfig{BatchSize: 10},
+			wantLimits: []int{10},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var limits []int
+
+			n, err := PurgeBatches(context.Background(), tc.config, purgeRows(tc.rows, &limits))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, n)
+			assert.Equal(t, tc.wantLimits, limits)
+		})
+	}
+}
+
+func TestPurgeBatchesProgress(t *testing.T) {
+	var (
+		limits   []int
+		progress []PurgeProgress
+	)
+
+	config := PurgeConfig{
+		BatchSize: 10,
+		Pause:     time.Millisecond,
+		Progress: func(_ context.Context, p PurgeProgress) {
+			progress = append(progress, p)
+		},
+	}
+
+	n, err := PurgeBatches(context.Background(), config, purgeRows(25, &limits))
+	require.NoError(t, err)
+	assert.Equal(t, 25, n)
+	assert.Equal(t, []PurgeProgress{{Batches: 1, Deleted: 10}, {Batches: 2, Deleted: 20}, {Batches: 3, Deleted: 25}}, progress)
+}
+
+func TestPurgeBatchesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var limits []int
+
+	config := PurgeConfig{
+		BatchSize: 10,
+		Pause:     time.Hour,
+		Progress: func(context.Context, PurgeProgress) {
+			cancel()
+		},
+	}
+
+	n, err := PurgeBatches(ctx, config, purgeRows(100, &limits))
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, []int{10}, limits)
+}
+
+func TestPurgeBatchesError(t *testing.T) {
+	errDelete := errors.New("delete failed")
+	calls := 0
+
+	n, err := PurgeBatches(context.Background(), PurgeConfig{BatchSize: 10}, func(context.Context, int) (int, error) {
+		calls++
+		if calls == 2 {
+			return 0, errDelete
+		}
+
+		return 10, nil
+	})
+	require.ErrorIs(t, err, errDelete)
+	assert.Equal(t, 10, n)
+}
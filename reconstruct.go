@@ -0,0 +1,44 @@
+package enthistory
+
+import (
+	"context"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+)
+
+// ReconstructTempTable creates the temporary table holding the records of the history rows selected by the selector,
+// which must select the latest history row of each record at a point in time (see LatestAsOf), with the id of the
+// records (the ref of the history rows) and the columns of the original table; the table is created empty and then
+// populated, as Postgres does not allow arguments in CREATE TABLE AS. Temporary tables are only visible on the
+// connection creating them, so the driver must be a transaction; this is used by the generated
+// ReconstructTempTableAsOf methods of the history clients
+func ReconstructTempTable(ctx context.Context, drv dialect.ExecQuerier, s *sql.Selector, table string, columns []string) error {
+	create := sql.Dialect(s.Dialect()).
+		Select(append([]string{sql.As(s.C("ref"), "id")}, s.Columns(columns...)...)...).
+		From(s.Table()).
+		Where(sql.False())
+
+	b := &sql.Builder{}
+	b.SetDialect(s.Dialect())
+	b.WriteString("CREATE TEMPORARY TABLE ").Ident(table).WriteString(" AS ").Join(create)
+
+	query, args := b.Query()
+	if err := drv.Exec(ctx, query, args, nil); err != nil {
+		return err
+	}
+
+	s.Select(append([]string{s.C("ref")}, s.Columns(columns...)...)...)
+
+	b = &sql.Builder{}
+	b.SetDialect(s.Dialect())
+	b.WriteString("INSERT INTO ").Ident(table).WriteByte(' ').
+		Wrap(func(b *sql.Builder) {
+			b.IdentComma(append([]string{"id"}, columns...)...)
+		}).
+		WriteByte(' ').Join(s)
+
+	query, args = b.Query()
+
+	return drv.Exec(ctx, query, args, nil)
+}
@@ -0,0 +1,51 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconstructTempTable(t *testing.T) {
+	s := sql.Dialect(dialect.Postgres).Select().From(sql.Table("todo_history"))
+	s.Where(sql.EQ(s.C("operation"), "INSERT"))
+
+	drv := &recordingDriver{dialect: dialect.Postgres}
+	err := ReconstructTempTable(context.Background(), drv, s, "todos_as_of", []string{"name", "status"})
+	require.NoError(t, err)
+
+	require.Len(t, drv.stmts, 2)
+	assert.Equal(t, `CREATE TEMPORARY TABLE "todos_as_of" AS SELECT "todo_history"."ref" AS "id", "todo_history"."name", `+
+		`"todo_history"."status" FROM "todo_history" WHERE FALSE`, drv.stmts[0])
+	assert.Equal(t, `INSERT INTO "todos_as_of" ("id", "name", "status") SELECT "todo_history"."ref", "todo_history"."name", `+
+		`"todo_history"."status" FROM "todo_history" WHERE "todo_history"."operation" = $1`, drv.stmts[1])
+}
+
+func TestReconstructTempTableAsOf(t *testing.T) {
+	s := sql.Dialect(dialect.SQLite).Select().From(sql.Table("todo_history"))
+	LatestAsOf(time.Now(), "")(s)
+
+	drv := &recordingDriver{dialect: dialect.SQLite}
+	err := ReconstructTempTable(context.Background(), drv, s, "todos_as_of", []string{"name"})
+	require.NoError(t, err)
+
+	require.Len(t, drv.stmts, 2)
+	assert.Contains(t, drv.stmts[1], "INSERT INTO `todos_as_of` (`id`, `name`) SELECT `todo_history`.`ref`, `todo_history`.`name` "+
+		"FROM `todo_history` WHERE `todo_history`.`history_time` <= ? AND NOT EXISTS")
+
+	// the temporary table is created from the history table of the schema
+	s = sql.Dialect(dialect.Postgres).Select().From(sql.Table("todo_history").Schema("audit"))
+
+	drv = &recordingDriver{dialect: dialect.Postgres}
+	err = ReconstructTempTable(context.Background(), drv, s, "todos_as_of", []string{"name"})
+	require.NoError(t, err)
+
+	require.Len(t, drv.stmts, 2)
+	assert.Equal(t, `CREATE TEMPORARY TABLE "todos_as_of" AS SELECT "audit"."todo_history"."ref" AS "id", `+
+		`"audit"."todo_history"."name" FROM "audit"."todo_history" WHERE FALSE`, drv.stmts[0])
+}
@@ -0,0 +1,75 @@
+package enthistory
+
+import (
+	"context"
+	"reflect"
+
+	"entgo.io/ent"
+)
+
+const (
+	// RedactedValue is the value stored on the history rows in place of the values of the redacted fields
+	RedactedValue = "[REDACTED]"
+	// fieldAnnotationName is the name of the history field annotation
+	fieldAnnotationName = "HistoryField"
+)
+
+// FieldAnnotation is the history annotation of a field of the original schema, set using Redact or Restrict
+type FieldAnnotation struct {
+	// Redact stores RedactedValue on the history rows in place of the values of the field
+	Redact bool `json:"redact,omitempty"`
+	// Restrict hides the values of the field on the history rows returned to the viewers who cannot see them
+	Restrict bool `json:"restrict,omitempty"`
+}
+
+// Name of the annotation
+func (FieldAnnotation) Name() string {
+	return fieldAnnotationName
+}
+
+// Redact returns the field annotation storing RedactedValue on the history rows in place of the values of the string
+// or bytes field, e.g. field.String("ssn").Annotations(enthistory.Redact()); the column is kept on the history schema,
+// and unlike Sensitive the field is not changed on the original schema
+func Redact() FieldAnnotation {
+	return FieldAnnotation{Redact: true}
+}
+
+// RedactHook returns a hook storing RedactedValue in place of the values of the fields of the history rows as they are
+// created; this is added to the generated history schemas of schemas with fields annotated using Redact
+func RedactHook(fields ...string) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if !m.Op().Is(ent.OpCreate) {
+				return next.Mutate(ctx, m)
+			}
+
+			for _, name := range fields {
+				value, ok := m.Field(name)
+				if !ok {
+					continue
+				}
+
+				if err := m.SetField(name, redactValue(value)); err != nil {
+					return nil, err
+				}
+			}
+
+			return next.Mutate(ctx, m)
+		})
+	}
+}
+
+// redactValue returns RedactedValue converted to the type of the string or bytes value, values of other types are
+// returned as is
+func redactValue(value ent.Value) ent.Value {
+	v := reflect.ValueOf(value)
+
+	switch {
+	case v.Kind() == reflect.String:
+		return reflect.ValueOf(RedactedValue).Convert(v.Type()).Interface()
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return reflect.ValueOf([]byte(RedactedValue)).Convert(v.Type()).Interface()
+	default:
+		return value
+	}
+}
@@ -0,0 +1,45 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactHook(t *testing.T) {
+	mutator := RedactHook("ssn", "token", "notes")(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return nil, nil
+	}))
+
+	m := &testLimitMutation{
+		op: ent.OpCreate,
+		fields: map[string]ent.Value{
+			"ssn":   testText("123-45-6789"),
+			"token": []byte("secret"),
+			"name":  "Jane",
+		},
+	}
+
+	_, err := mutator.Mutate(context.Background(), m)
+	require.NoError(t, err)
+
+	assert.Equal(t, testText(RedactedValue), m.fields["ssn"])
+	assert.Equal(t, []byte(RedactedValue), m.fields["token"])
+	assert.Equal(t, "Jane", m.fields["name"])
+	assert.NotContains(t, m.fields, "notes")
+
+	// updates of the history rows are not redacted
+	update := &testLimitMutation{op: ent.OpUpdateOne, fields: map[string]ent.Value{"ssn": "123-45-6789"}}
+
+	_, err = mutator.Mutate(context.Background(), update)
+	require.NoError(t, err)
+	assert.Equal(t, "123-45-6789", update.fields["ssn"])
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, FieldAnnotation{Redact: true}, Redact())
+	assert.Equal(t, "HistoryField", Redact().Name())
+}
@@ -0,0 +1,27 @@
+package enthistory
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CompositeRef returns the ref recorded on the history rows of records with a composite id (e.g. edge schemas),
+// which is the JSON encoded array of the id field values in the order they are defined on the schema
+func CompositeRef(values ...any) string {
+	out, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Sprint(values...)
+	}
+
+	return string(out)
+}
+
+// NaturalRef returns the ref recorded on the history rows of schemas with the RefFields history annotation, which is
+// the value of the field for a single field, or the CompositeRef of the values for multiple fields
+func NaturalRef(values ...any) string {
+	if len(values) == 1 {
+		return fmt.Sprint(values[0])
+	}
+
+	return CompositeRef(values...)
+}
@@ -0,0 +1,42 @@
+package enthistory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompositeRef(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []any
+		want   string
+	}{
+		{
+			name:   "strings",
+			values: []any{"user-1", "group-1"},
+			want:   `["user-1","group-1"]`,
+		},
+		{
+			name:   "ints",
+			values: []any{1, 2},
+			want:   `[1,2]`,
+		},
+		{
+			name:   "separators are escaped",
+			values: []any{`a","b`, "c"},
+			want:   `["a\",\"b","c"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CompositeRef(tt.values...))
+		})
+	}
+}
+
+func TestNaturalRef(t *testing.T) {
+	assert.Equal(t, "user@example.com", NaturalRef("user@example.com"))
+	assert.Equal(t, "42", NaturalRef(42))
+	assert.Equal(t, `["tenant-1","user@example.com"]`, NaturalRef("tenant-1", "user@example.com"))
+}
@@ -0,0 +1,63 @@
+package enthistory
+
+import (
+	"slices"
+)
+
+// repairableIssues are the kinds of issues repaired by inserting synthetic history rows, history rows
+// recorded out of order cannot be repaired by inserting rows
+var repairableIssues = []ConsistencyIssueKind{
+	IssueMissingCreate,
+	IssueUpdateAfterDelete,
+}
+
+// RepairConfig is the configuration used by the generated RepairHistory
+type RepairConfig struct {
+	// Kinds are the kinds of issues repaired, defaults to all repairable kinds
+	Kinds []ConsistencyIssueKind
+	// DryRun reports the issues that would be repaired without inserting any history rows
+	DryRun bool
+}
+
+// RepairOption is a functional option for the generated RepairHistory
+type RepairOption = func(*RepairConfig)
+
+// NewRepairConfig creates a new repair config with the defaults and the given options applied
+func NewRepairConfig(opts ...RepairOption) *RepairConfig {
+	config := &RepairConfig{
+		Kinds: repairableIssues,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return config
+}
+
+// Repairs checks if the issue is repaired using the config
+func (c *RepairConfig) Repairs(issue ConsistencyIssue) bool {
+	return slices.Contains(repairableIssues, issue.Kind) && slices.Contains(c.Kinds, issue.Kind)
+}
+
+// WithRepairKinds only repairs the given kinds of issues, e.g. IssueMissingCreate
+func WithRepairKinds(kinds ...ConsistencyIssueKind) RepairOption {
+	return func(c *RepairConfig) {
+		c.Kinds = kinds
+	}
+}
+
+// WithRepairDryRun reports the issues that would be repaired without inserting any history rows
+func WithRepairDryRun() RepairOption {
+	return func(c *RepairConfig) {
+		c.DryRun = true
+	}
+}
+
+// RepairReport is the report returned by the generated RepairHistory
+type RepairReport struct {
+	// Repaired are the issues repaired by inserting a synthetic history row, or that would be using WithRepairDryRun
+	Repaired []ConsistencyIssue
+	// Skipped are the issues that were not repaired, e.g. history rows recorded out of order
+	Skipped []ConsistencyIssue
+}
@@ -0,0 +1,48 @@
+package enthistory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairConfig(t *testing.T) {
+	missingCreate := ConsistencyIssue{Schema: "Todo", Ref: 1, Kind: IssueMissingCreate}
+	updateAfterDelete := ConsistencyIssue{Schema: "Todo", Ref: 1, HistoryID: 3, Kind: IssueUpdateAfterDelete}
+	outOfOrder := ConsistencyIssue{Schema: "Todo", Ref: 1, HistoryID: 4, Kind: IssueHistoryTimeOutOfOrder}
+
+	tests := []struct {
+		name       string
+		opts       []RepairOption
+		wantDryRun bool
+		want       []bool
+	}{
+		{
+			name: "defaults",
+			want: []bool{true, true, false},
+		},
+		{
+			name: "repair kinds",
+			opts: []RepairOption{WithRepairKinds(IssueMissingCreate, IssueHistoryTimeOutOfOrder)},
+			want: []bool{true, false, false},
+		},
+		{
+			name:       "dry run",
+			opts:       []RepairOption{WithRepairDryRun()},
+			wantDryRun: true,
+			want:       []bool{true, true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewRepairConfig(tt.opts...)
+
+			assert.Equal(t, tt.wantDryRun, config.DryRun)
+			assert.Equal(t, tt.want, []bool{
+				config.Repairs(missingCreate),
+				config.Repairs(updateAfterDelete),
+				config.Repairs(outOfOrder),
+			})
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package enthistory
+
+import (
+	"context"
+)
+
+// RestoreStrategy is the strategy used when a restored record collides with the unique fields of a live record
+type RestoreStrategy int
+
+const (
+	// RestoreStrategyFail returns ErrRestoreConflict when the restored record collides with a live record
+	RestoreStrategyFail RestoreStrategy = iota
+	// RestoreStrategyOverwrite deletes the live record that collides with the restored record
+	RestoreStrategyOverwrite
+	// RestoreStrategySuffix appends a suffix to the colliding unique string fields of the restored record,
+	// unique fields of other types still return ErrRestoreConflict
+	RestoreStrategySuffix
+)
+
+const (
+	// defaultRestoreSuffix is the default suffix used with RestoreStrategySuffix
+	defaultRestoreSuffix = "-restored"
+)
+
+// RestoreConfig is the configuration used by the generated Restore methods
+type RestoreConfig struct {
+	// Strategy is used when the restored record collides with a live record, defaults to RestoreStrategyFail
+	Strategy RestoreStrategy
+	// Suffix is appended to colliding unique string fields when using RestoreStrategySuffix
+	Suffix string
+	// NewID allows deleted records with ids generated by the database to be recreated under a new id
+	NewID bool
+}
+
+// RestoreOption is a functional option for the generated Restore methods
+type RestoreOption = func(*RestoreConfig)
+
+// NewRestoreConfig creates a new restore config with the defaults and the given options applied
+func NewRestoreConfig(opts ...RestoreOption) *RestoreConfig {
+	config := &RestoreConfig{
+		Strategy: RestoreStrategyFail,
+		Suffix:   defaultRestoreSuffix,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return config
+}
+
+// WithRestoreStrategy sets the strategy used when the restored record collides with a live record
+func WithRestoreStrategy(strategy RestoreStrategy) RestoreOption {
+	return func(c *RestoreConfig) {
+		c.Strategy = strategy
+	}
+}
+
+// WithRestoreSuffix restores colliding records using RestoreStrategySuffix with the given suffix
+// instead of the default "-restored"
+func WithRestoreSuffix(suffix string) RestoreOption {
+	return func(c *RestoreConfig) {
+		c.Strategy = RestoreStrategySuffix
+		c.Suffix = suffix
+	}
+}
+
+// WithNewID allows the generated Restore methods to recreate deleted records whose ids are generated by the database
+// (e.g. auto-increment ids) under a new id, as the original id cannot be reused; the id of the returned record then
+// differs from the Ref of the history row, and its new history rows are recorded under the new id. Without this
+// option, restoring these records returns ErrRestoreNewID
+func WithNewID() RestoreOption {
+	return func(c *RestoreConfig) {
+		c.NewID = true
+	}
+}
+
+// restoredFromKey is the context key for the id of the history row being restored
+type restoredFromKey struct{}
+
+// NewRestoredFromContext returns a copy of the context with the id of the history row being restored,
+// this is set by the generated Restore methods and recorded in the restored_from field when using WithRestoredFrom
+func NewRestoredFromContext(ctx context.Context, id any) context.Context {
+	return context.WithValue(ctx, restoredFromKey{}, id)
+}
+
+// RestoredFromContext returns the id of the history row being restored from the context, if it was set
+func RestoredFromContext[T comparable](ctx context.Context) (T, bool) {
+	id, ok := ctx.Value(restoredFromKey{}).(T)
+
+	var zero T
+
+	return id, ok && id != zero
+}
@@ -0,0 +1,74 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRestoreConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []RestoreOption
+		want *RestoreConfig
+	}{
+		{
+			name: "defaults",
+			want: &RestoreConfig{
+				Strategy: RestoreStrategyFail,
+				Suffix:   "-restored",
+			},
+		},
+		{
+			name: "overwrite",
+			opts: []RestoreOption{WithRestoreStrategy(RestoreStrategyOverwrite)},
+			want: &RestoreConfig{
+				Strategy: RestoreStrategyOverwrite,
+				Suffix:   "-restored",
+			},
+		},
+		{
+			name: "suffix",
+			opts: []RestoreOption{WithRestoreSuffix("-copy")},
+			want: &RestoreConfig{
+				Strategy: RestoreStrategySuffix,
+				Suffix:   "-copy",
+			},
+		},
+		{
+			name: "new id",
+			opts: []RestoreOption{WithNewID()},
+			want: &RestoreConfig{
+				Strategy: RestoreStrategyFail,
+				Suffix:   "-restored",
+				NewID:    true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewRestoreConfig(tt.opts...)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRestoredFromContext(t *testing.T) {
+	ctx := NewRestoredFromContext(context.Background(), "01HXYZ")
+
+	id, ok := RestoredFromContext[string](ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "01HXYZ", id)
+
+	// mismatched types are ignored
+	_, ok = RestoredFromContext[int](ctx)
+	assert.False(t, ok)
+
+	// zero values are ignored
+	_, ok = RestoredFromContext[int](NewRestoredFromContext(context.Background(), 0))
+	assert.False(t, ok)
+
+	_, ok = RestoredFromContext[string](context.Background())
+	assert.False(t, ok)
+}
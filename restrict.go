@@ -0,0 +1,206 @@
+package enthistory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"entgo.io/ent"
+)
+
+const (
+	// fieldRelationPrefix is the prefix of the FGA relations of the restricted fields, e.g. can_view_salary
+	fieldRelationPrefix = "can_view_"
+)
+
+// FieldVisibility reports whether the viewer on the context can see the values of the restricted field of the history
+// rows of the schema (e.g. the ssn field of Todo), e.g. based on the role of the viewer or an FGA check
+type FieldVisibility func(ctx context.Context, schema, field string) bool
+
+// FieldAccessCheck reports whether the viewer on the context has the relation to the object, e.g. the
+// can_view_salary relation to the organization owning the history row, usually using the CheckAccess of the FGA client
+type FieldAccessCheck func(ctx context.Context, objectType, objectID, relation string) (bool, error)
+
+var (
+	// fieldVisibility is the field visibility set using SetFieldVisibility
+	fieldVisibility FieldVisibility
+	// fieldVisibilityMu guards the field visibility
+	fieldVisibilityMu sync.RWMutex
+	// fieldAccessCheck is the field access check set using SetFieldAccessCheck
+	fieldAccessCheck FieldAccessCheck
+	// fieldAccessCheckMu guards the field access check
+	fieldAccessCheckMu sync.RWMutex
+)
+
+// Restrict returns the field annotation hiding the values of the field on the history rows returned to the viewers
+// who cannot see them, based on the field visibility set using SetFieldVisibility, or the field access check set using
+// SetFieldAccessCheck when using WithGQLQuery and WithAuthzPolicy, e.g.
+// field.Int("salary").Annotations(enthistory.Restrict()); unlike Redact the values are still stored on the history
+// rows, so the viewers allowed to see them still can
+func Restrict() FieldAnnotation {
+	return FieldAnnotation{Restrict: true}
+}
+
+// SetFieldVisibility sets the field visibility deciding which viewers can see the values of the restricted fields of
+// the history rows; without a field visibility the restricted fields are hidden from all viewers, except when using
+// the system context. This is usually called when the application starts
+func SetFieldVisibility(fn FieldVisibility) {
+	fieldVisibilityMu.Lock()
+	defer fieldVisibilityMu.Unlock()
+
+	fieldVisibility = fn
+}
+
+// RestrictInterceptor returns an interceptor setting the restricted fields of the history rows of the schema returned
+// by history queries to their zero value (nil for nillable fields) when the viewer on the context cannot see them, so
+// the viewer can see that a change happened without seeing the values; this is added to the generated history schemas
+// of schemas with fields annotated using Restrict
+func RestrictInterceptor(schema string, fields ...string) ent.Interceptor {
+	return ent.InterceptFunc(func(next ent.Querier) ent.Querier {
+		return ent.QuerierFunc(func(ctx context.Context, q ent.Query) (ent.Value, error) {
+			v, err := next.Query(ctx, q)
+			if err != nil || HasToken(ctx, q, TokenSystem) {
+				return v, err
+			}
+
+			fieldVisibilityMu.RLock()
+			visible := fieldVisibility
+			fieldVisibilityMu.RUnlock()
+
+			hidden := make([]string, 0, len(fields))
+
+			for _, f := range fields {
+				if visible == nil || !visible(ctx, schema, f) {
+					hidden = append(hidden, f)
+				}
+			}
+
+			if len(hidden) > 0 {
+				hideFields(reflect.ValueOf(v), hidden)
+			}
+
+			return v, nil
+		})
+	})
+}
+
+// hideFields sets the fields of the history rows, a single row or a slice of rows, to their zero value; the fields of
+// the generated entities are matched using the name of their json tag, which is the name of the ent field
+func hideFields(v reflect.Value, fields []string) {
+	forEachRow(v, func(row reflect.Value) {
+		for i := range row.NumField() {
+			name, _, _ := strings.Cut(row.Type().Field(i).Tag.Get("json"), ",")
+			if f := row.Field(i); slices.Contains(fields, name) && f.CanSet() {
+				f.Set(reflect.Zero(f.Type()))
+			}
+		}
+	})
+}
+
+// FieldRelation returns the FGA relation the viewer must have to the object of a history row to see the values of the
+// restricted field, e.g. can_view_salary for the salary field
+func FieldRelation(field string) string {
+	return fieldRelationPrefix + field
+}
+
+// SetFieldAccessCheck sets the field access check deciding which viewers can see the values of the restricted fields
+// of the history rows when using WithGQLQuery and WithAuthzPolicy; without a field access check the restricted fields
+// are hidden from all viewers, except when using the system context. This is usually called when the application
+// starts
+func SetFieldAccessCheck(fn FieldAccessCheck) {
+	fieldAccessCheckMu.Lock()
+	defer fieldAccessCheckMu.Unlock()
+
+	fieldAccessCheck = fn
+}
+
+// FieldAccessInterceptor returns an interceptor setting the restricted fields of each history row returned by history
+// queries, including the queries of the GraphQL resolvers, to their zero value unless the viewer on the context has the
+// FieldRelation of the field to the object of the row; the object is of the object type, identified by the value of
+// the id field of the row (e.g. Ref or OwnerID), and the fields are hidden when the check fails. This is added to the
+// generated history schemas of schemas with fields annotated using Restrict when using WithGQLQuery and WithAuthzPolicy
+func FieldAccessInterceptor(objectType, idField string, fields ...string) ent.Interceptor {
+	return ent.InterceptFunc(func(next ent.Querier) ent.Querier {
+		return ent.QuerierFunc(func(ctx context.Context, q ent.Query) (ent.Value, error) {
+			v, err := next.Query(ctx, q)
+			if err != nil || HasToken(ctx, q, TokenSystem) {
+				return v, err
+			}
+
+			fieldAccessCheckMu.RLock()
+			check := fieldAccessCheck
+			fieldAccessCheckMu.RUnlock()
+
+			// the checks are shared by the rows of the same object
+			checked := make(map[string]bool)
+
+			forEachRow(reflect.ValueOf(v), func(row reflect.Value) {
+				objectID, ok := rowObjectID(row, idField)
+
+				hidden := make([]string, 0, len(fields))
+
+				for _, f := range fields {
+					key := objectID + "#" + f
+
+					allowed, done := checked[key]
+					if !done {
+						if ok && check != nil {
+							granted, err := check(ctx, objectType, objectID, FieldRelation(f))
+							allowed = granted && err == nil
+						}
+
+						checked[key] = allowed
+					}
+
+					if !allowed {
+						hidden = append(hidden, f)
+					}
+				}
+
+				if len(hidden) > 0 {
+					hideFields(row, hidden)
+				}
+			})
+
+			return v, nil
+		})
+	})
+}
+
+// forEachRow calls the function with each history row, of a single row or a slice of rows
+func forEachRow(v reflect.Value, fn func(row reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Slice:
+		for i := range v.Len() {
+			forEachRow(v.Index(i), fn)
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			forEachRow(v.Elem(), fn)
+		}
+	case reflect.Struct:
+		fn(v)
+	}
+}
+
+// rowObjectID returns the value of the id field of the history row as the id of its object, rows without a value are
+// not associated with an object
+func rowObjectID(row reflect.Value, idField string) (string, bool) {
+	f := row.FieldByName(idField)
+	if !f.IsValid() {
+		return "", false
+	}
+
+	if f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			return "", false
+		}
+
+		f = f.Elem()
+	}
+
+	return fmt.Sprint(f.Interface()), !f.IsZero()
+}
@@ -0,0 +1,158 @@
+package enthistory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEmployeeHistory is a history row with the json tags of the generated entities
+type testEmployeeHistory struct {
+	ID      int     `json:"id,omitempty"`
+	Ref     int     `json:"ref,omitempty"`
+	OwnerID *string `json:"owner_id,omitempty"`
+	Name    string  `json:"name,omitempty"`
+	Salary  int     `json:"salary,omitempty"`
+	SSN     *string `json:"ssn,omitempty"`
+}
+
+func TestRestrictInterceptor(t *testing.T) {
+	t.Cleanup(func() { SetFieldVisibility(nil) })
+
+	ssn := "123-45-6789"
+
+	querier := RestrictInterceptor("Employee", "salary", "ssn").Intercept(
+		ent.QuerierFunc(func(context.Context, ent.Query) (ent.Value, error) {
+			return []*testEmployeeHistory{
+				{ID: 1, Name: "Jane", Salary: 100, SSN: &ssn},
+				{ID: 2, Name: "John", Salary: 200},
+			}, nil
+		}),
+	)
+
+	query := func(ctx context.Context) []*testEmployeeHistory {
+		t.Helper()
+
+		v, err := querier.Query(ctx, &testQuery{})
+		require.NoError(t, err)
+
+		return v.([]*testEmployeeHistory)
+	}
+
+	// the restricted fields are hidden from all viewers without a field visibility
+	assert.Equal(t, []*testEmployeeHistory{{ID: 1, Name: "Jane"}, {ID: 2, Name: "John"}}, query(context.Background()))
+
+	// the system context sees all fields
+	assert.Equal(t, &ssn, query(withTestTokens(context.Background(), TokenSystem))[0].SSN)
+
+	type viewerKey struct{}
+
+	SetFieldVisibility(func(ctx context.Context, schema, field string) bool {
+		assert.Equal(t, "Employee", schema)
+
+		return ctx.Value(viewerKey{}) == "admin" || field == "salary"
+	})
+
+	rows := query(context.WithValue(context.Background(), viewerKey{}, "member"))
+	assert.Equal(t, 100, rows[0].Salary)
+	assert.Nil(t, rows[0].SSN)
+
+	rows = query(context.WithValue(context.Background(), viewerKey{}, "admin"))
+	assert.Equal(t, 100, rows[0].Salary)
+	assert.Equal(t, &ssn, rows[0].SSN)
+}
+
+func TestHideFields(t *testing.T) {
+	row := &testEmployeeHistory{ID: 1, Name: "Jane", Salary: 100}
+	hideFields(reflect.ValueOf(row), []string{"salary"})
+	assert.Equal(t, &testEmployeeHistory{ID: 1, Name: "Jane"}, row)
+
+	rows := []testEmployeeHistory{{ID: 1, Name: "Jane"}}
+	hideFields(reflect.ValueOf(rows), []string{"name"})
+	assert.Equal(t, []testEmployeeHistory{{ID: 1}}, rows)
+
+	// values other than history rows, e.g. counts, are returned as is
+	assert.NotPanics(t, func() { hideFields(reflect.ValueOf(3), []string{"name"}) })
+	assert.NotPanics(t, func() { hideFields(reflect.ValueOf((*testEmployeeHistory)(nil)), []string{"name"}) })
+}
+
+func TestFieldAccessInterceptor(t *testing.T) {
+	t.Cleanup(func() { SetFieldAccessCheck(nil) })
+
+	org1, org2 := "org1", "org2"
+
+	querier := FieldAccessInterceptor("organization", "OwnerID", "salary").Intercept(
+		ent.QuerierFunc(func(context.Context, ent.Query) (ent.Value, error) {
+			return []*testEmployeeHistory{
+				{ID: 1, OwnerID: &org1, Name: "Jane", Salary: 100},
+				{ID: 2, OwnerID: &org1, Name: "Jane", Salary: 110},
+				{ID: 3, OwnerID: &org2, Name: "John", Salary: 200},
+				{ID: 4, Name: "Jim", Salary: 300},
+			}, nil
+		}),
+	)
+
+	salaries := func(ctx context.Context) []int {
+		t.Helper()
+
+		v, err := querier.Query(ctx, &testQuery{})
+		require.NoError(t, err)
+
+		var salaries []int
+		for _, row := range v.([]*testEmployeeHistory) {
+			salaries = append(salaries, row.Salary)
+		}
+
+		return salaries
+	}
+
+	// the restricted fields are hidden from all viewers without a field access check
+	assert.Equal(t, []int{0, 0, 0, 0}, salaries(context.Background()))
+	assert.Equal(t, []int{100, 110, 200, 300}, salaries(withTestTokens(context.Background(), TokenSystem)))
+
+	var checks []string
+
+	SetFieldAccessCheck(func(_ context.Context, objectType, objectID, relation string) (bool, error) {
+		checks = append(checks, objectType+":"+objectID+"#"+relation)
+
+		if objectID == "org2" {
+			return false, assert.AnError
+		}
+
+		return true, nil
+	})
+
+	// rows without an object, and rows whose check fails, are hidden
+	assert.Equal(t, []int{100, 110, 0, 0}, salaries(context.Background()))
+	// the rows of the same object are checked once
+	assert.Equal(t, []string{"organization:org1#can_view_salary", "organization:org2#can_view_salary"}, checks)
+}
+
+func TestRowObjectID(t *testing.T) {
+	owner := "org1"
+
+	id, ok := rowObjectID(reflect.ValueOf(testEmployeeHistory{Ref: 7}), "Ref")
+	assert.True(t, ok)
+	assert.Equal(t, "7", id)
+
+	id, ok = rowObjectID(reflect.ValueOf(testEmployeeHistory{OwnerID: &owner}), "OwnerID")
+	assert.True(t, ok)
+	assert.Equal(t, "org1", id)
+
+	_, ok = rowObjectID(reflect.ValueOf(testEmployeeHistory{}), "OwnerID")
+	assert.False(t, ok)
+
+	_, ok = rowObjectID(reflect.ValueOf(testEmployeeHistory{}), "Ref")
+	assert.False(t, ok)
+
+	_, ok = rowObjectID(reflect.ValueOf(testEmployeeHistory{}), "Missing")
+	assert.False(t, ok)
+}
+
+func TestFieldRelation(t *testing.T) {
+	assert.Equal(t, "can_view_salary", FieldRelation("salary"))
+}
@@ -0,0 +1,388 @@
+package enthistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"entgo.io/ent"
+)
+
+// HistoryEvent is a history row written to the history tables, sent to the Sink set using SetSink
+type HistoryEvent struct {
+	// Type is the name of the history schema (e.g. TodoHistory)
+	Type string `json:"type"`
+	// Entity is the name of the tracked schema (e.g. Todo)
+	Entity string `json:"entity"`
+	// ID is the id of the history row
+	ID any `json:"id"`
+	// Ref is the id of the tracked record
+	Ref any `json:"ref"`
+	// Operation is the operation of the history row
+	Operation OpType `json:"operation"`
+	// HistoryTime is the time the history row was recorded
+	HistoryTime time.Time `json:"historyTime"`
+	// Values are the values of the fields of the history row by name, including ref, operation, and history_time
+	Values map[string]any `json:"values"`
+}
+
+// Sink is a secondary store the history rows are written to, e.g. an analytical store keeping the long-term history
+// while the history tables are purged; sinks are called once the transaction of the history rows is committed, or
+// right away outside of transactions, so they should buffer the events and write them in batches, errors returned by
+// the sink are returned from the commit, or fail the mutation outside of transactions
+type Sink interface {
+	// Write writes the history events to the sink
+	Write(ctx context.Context, events []HistoryEvent) error
+}
+
+// SinkFunc is a function implementing Sink
+type SinkFunc func(ctx context.Context, events []HistoryEvent) error
+
+// Write writes the history events using the function
+func (f SinkFunc) Write(ctx context.Context, events []HistoryEvent) error {
+	return f(ctx, events)
+}
+
+var (
+	// sink is the secondary sink of the history rows, set using SetSink
+	sink Sink
+	// sinkMu guards the sink
+	sinkMu sync.RWMutex
+)
+
+// SetSink sets the secondary sink the history rows of all schemas are written to, when using WithSink; the history
+// rows are not written to a sink when it is nil
+func SetSink(s Sink) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+
+	sink = s
+}
+
+// AfterCommitter is implemented by the generated mutations, running fn once the transaction of the mutation is
+// committed, or right away outside of transactions
+type AfterCommitter interface {
+	AfterCommit(fn func() error) error
+}
+
+// afterCommit runs fn once the transaction of the mutation is committed, the mutations of clients generated without
+// AfterCommit run it right away
+func afterCommit(m ent.Mutation, fn func() error) error {
+	if c, ok := m.(AfterCommitter); ok {
+		return c.AfterCommit(fn)
+	}
+
+	return fn()
+}
+
+// SinkHook returns a hook writing the history rows to the sink set using SetSink once they are committed, so the
+// history rows of rolled back transactions are not sent; this is added to the generated history schemas when using
+// WithSink
+func SinkHook() ent.Hook {
+	return On(func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			v, err := next.Mutate(ctx, m)
+			if err != nil {
+				return v, err
+			}
+
+			sinkMu.RLock()
+			s := sink
+			sinkMu.RUnlock()
+
+			if s == nil {
+				return v, nil
+			}
+
+			event := newHistoryEvent(m, v)
+
+			return v, afterCommit(m, func() error {
+				return s.Write(ctx, []HistoryEvent{event})
+			})
+		})
+	}, ent.OpCreate)
+}
+
+// newHistoryEvent returns the history event of the created history row, the values are those of the mutation and the
+// id is read from the created row
+func newHistoryEvent(m ent.Mutation, v ent.Value) HistoryEvent {
+	event := HistoryEvent{
+		Type:   m.Type(),
+		Entity: strings.TrimSuffix(m.Type(), "History"),
+		Values: map[string]any{},
+	}
+
+	for _, name := range m.Fields() {
+		value, _ := m.Field(name)
+		event.Values[name] = value
+	}
+
+	event.Ref = event.Values["ref"]
+	event.Operation, _ = event.Values["operation"].(OpType)
+	event.HistoryTime, _ = event.Values["history_time"].(time.Time)
+
+	if row := reflect.Indirect(reflect.ValueOf(v)); row.Kind() == reflect.Struct {
+		if id := row.FieldByName("ID"); id.IsValid() {
+			event.ID = id.Interface()
+		}
+	}
+
+	return event
+}
+
+const (
+	// DefaultSinkBatchSize is the number of history events written by the sinks in a batch
+	DefaultSinkBatchSize = 1000
+	// DefaultSinkFlushInterval is the interval the buffered history events are written by the sinks at
+	DefaultSinkFlushInterval = 5 * time.Second
+)
+
+// sinkColumns are the columns of the tables the history events are written to by the ClickHouseSink and PostgresSink
+var sinkColumns = []string{"history_type", "entity", "id", "ref", "operation", "history_time", "fields"}
+
+// WriteMode is how the batches of history events are written by the sinks supporting several
+type WriteMode int
+
+const (
+	// WriteInsert writes the batches using INSERT statements, this is the default
+	WriteInsert WriteMode = iota
+	// WriteCopy writes the batches using COPY ... FROM STDIN through a prepared statement, which is supported by
+	// database/sql drivers implementing the COPY protocol that way (e.g. lib/pq)
+	WriteCopy
+	// WriteCopyFrom writes the batches using the CopyFrom function of the sink configuration, e.g. to use the bulk
+	// copy API of the driver (e.g. pgx.Conn.CopyFrom)
+	WriteCopyFrom
+)
+
+// CopyFromFunc writes the rows to the columns of the table using the bulk copy API of a driver
+type CopyFromFunc func(ctx context.Context, table string, columns []string, rows [][]any) error
+
+// SinkConfig is the configuration of the sinks writing the history events to an analytical store in batches
+type SinkConfig struct {
+	// BatchSize is the number of buffered history events triggering a write
+	BatchSize int
+	// FlushInterval is the interval the buffered history events are written at
+	FlushInterval time.Duration
+	// OnError handles the errors writing the history events in the background
+	OnError func(err error, events []HistoryEvent)
+	// WriteMode is how the batches are written by the sinks supporting several (e.g. the PostgresSink)
+	WriteMode WriteMode
+	// CopyFrom writes the batches when using WriteCopyFrom
+	CopyFrom CopyFromFunc
+}
+
+// SinkOption is a functional option for the sinks writing the history events to an analytical store
+type SinkOption func(*SinkConfig)
+
+// WithSinkBatchSize sets the number of buffered history events triggering a write
+func WithSinkBatchSize(size int) SinkOption {
+	return func(c *SinkConfig) {
+		c.BatchSize = size
+	}
+}
+
+// WithSinkFlushInterval sets the interval the buffered history events are written at
+func WithSinkFlushInterval(interval time.Duration) SinkOption {
+	return func(c *SinkConfig) {
+		c.FlushInterval = interval
+	}
+}
+
+// WithSinkErrorHandler sets the handler of the errors writing the history events in the background, the events that
+// could not be written are passed to the handler, e.g. to retry or log them; they are dropped otherwise
+func WithSinkErrorHandler(handler func(err error, events []HistoryEvent)) SinkOption {
+	return func(c *SinkConfig) {
+		c.OnError = handler
+	}
+}
+
+// WithSinkWriteMode sets how the batches are written by the sinks supporting several write modes
+func WithSinkWriteMode(mode WriteMode) SinkOption {
+	return func(c *SinkConfig) {
+		c.WriteMode = mode
+	}
+}
+
+// WithSinkCopyFrom writes the batches using the bulk copy API of the driver, e.g. pgx.Conn.CopyFrom for the highest
+// throughput on Postgres, this sets the write mode to WriteCopyFrom
+func WithSinkCopyFrom(fn CopyFromFunc) SinkOption {
+	return func(c *SinkConfig) {
+		c.WriteMode = WriteCopyFrom
+		c.CopyFrom = fn
+	}
+}
+
+// newSinkConfig returns the sink configuration with the defaults and the options applied
+func newSinkConfig(opts ...SinkOption) SinkConfig {
+	c := SinkConfig{
+		BatchSize:     DefaultSinkBatchSize,
+		FlushInterval: DefaultSinkFlushInterval,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// bufferedSink buffers the history events, and writes them in batches in the background using the write function,
+// when the batch size is reached, at the flush interval, and when the sink is closed
+type bufferedSink struct {
+	config SinkConfig
+	name   string
+	write  func(ctx context.Context, events []HistoryEvent) error
+
+	mu     sync.Mutex
+	events []HistoryEvent
+	closed bool
+
+	flush chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// newBufferedSink returns a buffered sink writing the history events to the named destination using the write
+// function, and starts writing the batches in the background
+func newBufferedSink(name string, config SinkConfig, write func(context.Context, []HistoryEvent) error) *bufferedSink {
+	s := &bufferedSink{
+		config: config,
+		name:   name,
+		write:  write,
+		flush:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+
+	go s.run()
+
+	return s
+}
+
+// Write buffers the history events, which are written in the background
+func (s *bufferedSink) Write(_ context.Context, events []HistoryEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSinkClosed
+	}
+
+	s.events = append(s.events, events...)
+	sinkQueueDepth.Add(int64(len(events)))
+
+	if len(s.events) >= s.config.BatchSize {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the buffered history events, the events are dropped when they cannot be written
+func (s *bufferedSink) Flush(ctx context.Context) error {
+	events := s.take()
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := s.writeEvents(ctx, events); err != nil {
+		return fmt.Errorf("writing %d history events to %s: %w", len(events), s.name, err)
+	}
+
+	return nil
+}
+
+// Close stops the background writes, and writes the buffered history events
+func (s *bufferedSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	s.wg.Wait()
+
+	return s.Flush(context.Background())
+}
+
+// take returns the buffered history events, and empties the buffer
+func (s *bufferedSink) take() []HistoryEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := s.events
+	s.events = nil
+
+	sinkQueueDepth.Add(-int64(len(events)))
+
+	return events
+}
+
+// writeEvents writes the history events using the write function, counting the events written and the failed writes
+func (s *bufferedSink) writeEvents(ctx context.Context, events []HistoryEvent) error {
+	if err := s.write(ctx, events); err != nil {
+		sinkErrors.Add(1)
+
+		return err
+	}
+
+	sinkEventsWritten.Add(int64(len(events)))
+
+	return nil
+}
+
+// run writes the buffered history events when the batch size is reached and at the flush interval, until closed
+func (s *bufferedSink) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.flush:
+		}
+
+		events := s.take()
+		if len(events) == 0 {
+			continue
+		}
+
+		if err := s.writeEvents(context.Background(), events); err != nil && s.config.OnError != nil {
+			s.config.OnError(err, events)
+		}
+	}
+}
+
+// sinkRows returns the values of the sinkColumns of the history events, the values of the fields are encoded as JSON
+func sinkRows(events []HistoryEvent) ([][]any, error) {
+	rows := make([][]any, 0, len(events))
+
+	for _, e := range events {
+		values, err := json.Marshal(e.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, []any{e.Type, e.Entity, fmt.Sprint(e.ID), fmt.Sprint(e.Ref), string(e.Operation),
+			e.HistoryTime, string(values)})
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,192 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSinkMutation is a history mutation with the fields set on it
+type testSinkMutation struct {
+	ent.Mutation
+	fields map[string]ent.Value
+}
+
+func (m *testSinkMutation) Op() ent.Op {
+	return ent.OpCreate
+}
+
+func (m *testSinkMutation) Type() string {
+	return "TodoHistory"
+}
+
+func (m *testSinkMutation) Fields() []string {
+	fields := make([]string, 0, len(m.fields))
+	for name := range m.fields {
+		fields = append(fields, name)
+	}
+
+	return fields
+}
+
+func (m *testSinkMutation) Field(name string) (ent.Value, bool) {
+	value, ok := m.fields[name]
+
+	return value, ok
+}
+
+type testSinkRow struct {
+	ID int
+}
+
+// testTxMutation is a history mutation of a transaction, deferring the functions until commit is called
+type testTxMutation struct {
+	testSinkMutation
+	committed []func() error
+}
+
+func (m *testTxMutation) AfterCommit(fn func() error) error {
+	m.committed = append(m.committed, fn)
+
+	return nil
+}
+
+// commit runs the deferred functions, returning the first error
+func (m *testTxMutation) commit() error {
+	for _, fn := range m.committed {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestSinkHook(t *testing.T) {
+	now := time.Now()
+
+	m := &testSinkMutation{
+		fields: map[string]ent.Value{
+			"ref":          7,
+			"operation":    OpTypeUpdate,
+			"history_time": now,
+			"name":         "todo",
+		},
+	}
+
+	mutator := SinkHook()(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return &testSinkRow{ID: 3}, nil
+	}))
+
+	_, err := mutator.Mutate(context.Background(), m)
+	require.NoError(t, err)
+
+	var events []HistoryEvent
+
+	SetSink(SinkFunc(func(_ context.Context, e []HistoryEvent) error {
+		events = append(events, e...)
+
+		return nil
+	}))
+	t.Cleanup(func() { SetSink(nil) })
+
+	_, err = mutator.Mutate(context.Background(), m)
+	require.NoError(t, err)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, HistoryEvent{
+		Type:        "TodoHistory",
+		Entity:      "Todo",
+		ID:          3,
+		Ref:         7,
+		Operation:   OpTypeUpdate,
+		HistoryTime: now,
+		Values: map[string]any{
+			"ref":          7,
+			"operation":    OpTypeUpdate,
+			"history_time": now,
+			"name":         "todo",
+		},
+	}, events[0])
+
+	errSink := errors.New("sink unavailable")
+
+	SetSink(SinkFunc(func(context.Context, []HistoryEvent) error {
+		return errSink
+	}))
+
+	_, err = mutator.Mutate(context.Background(), m)
+	assert.ErrorIs(t, err, errSink)
+
+	// the events of transactions are written once committed, and the errors of the sink are returned by the commit
+	tx := &testTxMutation{testSinkMutation: *m}
+
+	_, err = mutator.Mutate(context.Background(), tx)
+	require.NoError(t, err)
+	assert.ErrorIs(t, tx.commit(), errSink)
+
+	events = nil
+
+	SetSink(SinkFunc(func(_ context.Context, e []HistoryEvent) error {
+		events = append(events, e...)
+
+		return nil
+	}))
+
+	tx = &testTxMutation{testSinkMutation: *m}
+
+	_, err = mutator.Mutate(context.Background(), tx)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	require.NoError(t, tx.commit())
+	assert.Len(t, events, 1)
+}
+
+func TestClickHouseSinkClosed(t *testing.T) {
+	s := NewClickHouseSink(nil, "history_events", WithSinkBatchSize(10), WithSinkFlushInterval(time.Hour))
+
+	assert.Equal(t, 10, s.config.BatchSize)
+	assert.Equal(t, time.Hour, s.config.FlushInterval)
+
+	require.NoError(t, s.Close())
+	require.NoError(t, s.Close())
+
+	err := s.Write(context.Background(), []HistoryEvent{{Type: "TodoHistory"}})
+	assert.ErrorIs(t, err, ErrSinkClosed)
+}
+
+func TestClickHouseSinkInsert(t *testing.T) {
+	db, drv := openRecordingDB(t)
+
+	var failed []HistoryEvent
+
+	s := NewClickHouseSink(db, "history_events", WithSinkBatchSize(2), WithSinkFlushInterval(time.Hour),
+		WithSinkErrorHandler(func(_ error, events []HistoryEvent) { failed = append(failed, events...) }))
+
+	require.NoError(t, s.Write(context.Background(), testSinkEvents(1)))
+	require.NoError(t, s.Close())
+
+	require.Equal(t, []string{
+		"BEGIN",
+		"INSERT INTO history_events (history_type, entity, id, ref, operation, history_time, fields)",
+		"COMMIT",
+	}, drv.stmts)
+	assert.Len(t, drv.args[1], 7)
+	assert.Empty(t, failed)
+}
+
+func TestClickHouseDDL(t *testing.T) {
+	ddl := ClickHouseDDL("history_events")
+
+	assert.True(t, strings.HasPrefix(ddl, "CREATE TABLE IF NOT EXISTS history_events ("))
+	assert.Contains(t, ddl, "history_time DateTime64(6, 'UTC')")
+	assert.Contains(t, ddl, "ENGINE = MergeTree")
+	assert.Contains(t, ddl, "ORDER BY (entity, ref, history_time, id)")
+}
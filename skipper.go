@@ -0,0 +1,80 @@
+package enthistory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"entgo.io/ent"
+)
+
+// Skipper decides if the history of the mutation is skipped, the history rows are not created
+// when it returns true
+type Skipper func(ctx context.Context, m ent.Mutation) bool
+
+var (
+	// skippers are the skippers registered using RegisterSkipper
+	skippers []Skipper
+	// skippersMu guards the registered skippers
+	skippersMu sync.RWMutex
+)
+
+// RegisterSkipper registers skippers used by the history hooks of all schemas, the history of a mutation is
+// skipped when any of the skippers return true; this is usually called when the application starts
+func RegisterSkipper(s ...Skipper) {
+	skippersMu.Lock()
+	defer skippersMu.Unlock()
+
+	skippers = append(skippers, s...)
+}
+
+// skipKey is the context key marking mutations that skip history
+type skipKey struct{}
+
+// NewSkipContext returns a copy of the context that skips the history of the mutations using it,
+// e.g. for backfills or data migrations that should not be recorded
+func NewSkipContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// IsSkipContext checks if the context skips the history of the mutations using it
+func IsSkipContext(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipKey{}).(bool)
+
+	return skip
+}
+
+// shouldSkip checks if the history of the mutation is skipped by the context or any of the registered skippers
+func shouldSkip(ctx context.Context, m ent.Mutation) bool {
+	if IsSkipContext(ctx) {
+		return true
+	}
+
+	skippersMu.RLock()
+	defer skippersMu.RUnlock()
+
+	for _, skip := range skippers {
+		if skip(ctx, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OnlyFieldsChanged checks if the mutation only set or cleared the given fields, this is used by the generated
+// code to skip the update history of the fields set in the IgnoredUpdateFields history annotation
+func OnlyFieldsChanged(m ent.Mutation, fields ...string) bool {
+	changed := append(m.Fields(), m.ClearedFields()...)
+	if len(changed) == 0 {
+		return false
+	}
+
+	for _, f := range changed {
+		if !slices.Contains(fields, f) {
+			return false
+		}
+	}
+
+	return true
+}
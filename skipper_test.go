@@ -0,0 +1,79 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldSkip(t *testing.T) {
+	t.Cleanup(func() {
+		skippers = nil
+	})
+
+	assert.False(t, shouldSkip(context.Background(), testMutation{op: ent.OpCreate}))
+	assert.True(t, shouldSkip(NewSkipContext(context.Background()), testMutation{op: ent.OpCreate}))
+
+	RegisterSkipper(func(_ context.Context, m ent.Mutation) bool {
+		return m.Op().Is(ent.OpDelete)
+	})
+
+	assert.False(t, shouldSkip(context.Background(), testMutation{op: ent.OpCreate}))
+	assert.True(t, shouldSkip(context.Background(), testMutation{op: ent.OpDelete}))
+}
+
+func TestIsSkipContext(t *testing.T) {
+	assert.False(t, IsSkipContext(context.Background()))
+	assert.True(t, IsSkipContext(NewSkipContext(context.Background())))
+}
+
+// testFieldsMutation is a mutation setting and clearing fields
+type testFieldsMutation struct {
+	ent.Mutation
+	fields  []string
+	cleared []string
+}
+
+func (m testFieldsMutation) Fields() []string {
+	return m.fields
+}
+
+func (m testFieldsMutation) ClearedFields() []string {
+	return m.cleared
+}
+
+func TestOnlyFieldsChanged(t *testing.T) {
+	tests := []struct {
+		name string
+		m    testFieldsMutation
+		want bool
+	}{
+		{
+			name: "only ignored field set",
+			m:    testFieldsMutation{fields: []string{"last_seen_at"}},
+			want: true,
+		},
+		{
+			name: "only ignored field cleared",
+			m:    testFieldsMutation{cleared: []string{"last_seen_at"}},
+			want: true,
+		},
+		{
+			name: "other field set",
+			m:    testFieldsMutation{fields: []string{"last_seen_at", "name"}},
+			want: false,
+		},
+		{
+			name: "nothing changed",
+			m:    testFieldsMutation{},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, OnlyFieldsChanged(tt.m, "last_seen_at"))
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package enthistory
+
+import (
+	"expvar"
+)
+
+// StatsName is the name of the expvar map the counters of the history subsystem are published as, served by the
+// expvar handler on /debug/vars
+const StatsName = "enthistory"
+
+var (
+	// rowsWritten is the number of history rows written by the history hooks
+	rowsWritten = new(expvar.Int)
+	// historyErrors is the number of mutations failed by the history hooks, e.g. when writing a history row fails
+	historyErrors = new(expvar.Int)
+	// sinkEventsWritten is the number of history events written by the buffered sinks
+	sinkEventsWritten = new(expvar.Int)
+	// sinkErrors is the number of batches of history events the buffered sinks failed to write
+	sinkErrors = new(expvar.Int)
+	// sinkQueueDepth is the number of history events buffered by the sinks, waiting to be written
+	sinkQueueDepth = new(expvar.Int)
+
+	// stats is the expvar map publishing the counters
+	stats = publishStats()
+)
+
+// publishStats publishes the counters of the history subsystem as the StatsName expvar map
+func publishStats() *expvar.Map {
+	m := expvar.NewMap(StatsName)
+
+	m.Set("rows_written", rowsWritten)
+	m.Set("errors", historyErrors)
+	m.Set("sink_events_written", sinkEventsWritten)
+	m.Set("sink_errors", sinkErrors)
+	m.Set("sink_queue_depth", sinkQueueDepth)
+
+	return m
+}
+
+// AddRowsWritten adds the history rows written to the rows_written counter, this is used by the generated history
+// hooks once the history rows are saved
+func AddRowsWritten(n int) {
+	rowsWritten.Add(int64(n))
+}
@@ -0,0 +1,93 @@
+package enthistory
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFailingMutation is a mutation failing to create its history
+type testFailingMutation struct {
+	testOrderMutation
+}
+
+func (m *testFailingMutation) CreateHistoryFromDelete(context.Context) error {
+	return errTestMutation
+}
+
+// statValue returns the value of the counter published in the enthistory expvar map
+func statValue(t *testing.T, name string) int64 {
+	t.Helper()
+
+	stats, ok := expvar.Get(StatsName).(*expvar.Map)
+	require.True(t, ok)
+
+	counter, ok := stats.Get(name).(*expvar.Int)
+	require.True(t, ok)
+
+	return counter.Value()
+}
+
+func TestAddRowsWritten(t *testing.T) {
+	before := statValue(t, "rows_written")
+
+	AddRowsWritten(3)
+
+	assert.Equal(t, before+3, statValue(t, "rows_written"))
+}
+
+func TestHistoryErrorsStat(t *testing.T) {
+	before := statValue(t, "errors")
+
+	m := &testFailingMutation{testOrderMutation: testOrderMutation{op: ent.OpDeleteOne, calls: &[]string{}}}
+
+	hooks := HistoryHooks[*testFailingMutation]()
+
+	var mutator ent.Mutator = ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return nil, nil
+	})
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		mutator = hooks[i](mutator)
+	}
+
+	_, err := mutator.Mutate(context.Background(), m)
+	require.ErrorIs(t, err, errTestMutation)
+
+	assert.Equal(t, before+1, statValue(t, "errors"))
+}
+
+func TestSinkStats(t *testing.T) {
+	errWrite := errors.New("sink unavailable")
+	fail := true
+
+	s := newBufferedSink("test", newSinkConfig(WithSinkFlushInterval(time.Hour)), func(context.Context, []HistoryEvent) error {
+		if fail {
+			return errWrite
+		}
+
+		return nil
+	})
+
+	depth, written, failed := statValue(t, "sink_queue_depth"), statValue(t, "sink_events_written"), statValue(t, "sink_errors")
+
+	require.NoError(t, s.Write(context.Background(), testSinkEvents(2)))
+	assert.Equal(t, depth+2, statValue(t, "sink_queue_depth"))
+
+	require.ErrorIs(t, s.Flush(context.Background()), errWrite)
+	assert.Equal(t, depth, statValue(t, "sink_queue_depth"))
+	assert.Equal(t, failed+1, statValue(t, "sink_errors"))
+
+	fail = false
+
+	require.NoError(t, s.Write(context.Background(), testSinkEvents(2)))
+	require.NoError(t, s.Close())
+	assert.Equal(t, depth, statValue(t, "sink_queue_depth"))
+	assert.Equal(t, written+2, statValue(t, "sink_events_written"))
+}
@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"entgo.io/ent/entc/gen"
 	"github.com/stoewer/go-strcase"
@@ -34,13 +39,79 @@ func extractUpdatedByValueType(val any) string {
 		return ""
 	}
 
-	switch updatedBy.valueType {
+	return valueTypeName(updatedBy.valueType)
+}
+
+// extractDeletedByKey gets the key that is used for the deleted_by field
+func extractDeletedByKey(val any) string {
+	deletedBy, ok := val.(*DeletedBy)
+	if !ok || deletedBy == nil {
+		return ""
+	}
+
+	return deletedBy.key
+}
+
+// extractDeletedByValueType gets the type (int or string) that the deleted_by
+// field uses
+func extractDeletedByValueType(val any) string {
+	deletedBy, ok := val.(*DeletedBy)
+	if !ok || deletedBy == nil {
+		return ""
+	}
+
+	return valueTypeName(deletedBy.valueType)
+}
+
+// valueTypeName returns the go type name of the value type
+func valueTypeName(valueType ValueType) string {
+	switch valueType {
 	case ValueTypeInt:
 		return "int"
 	case ValueTypeString:
 		return "string"
+	case ValueTypeInt64:
+		return "int64"
+	case ValueTypeUUID:
+		return "uuid.UUID"
 	default:
-		return ""
+		return valueTypeGoType(valueType)
+	}
+}
+
+// valueTypeField returns the name of the ent field builder of the value type (e.g. Int, String)
+func valueTypeField(valueType ValueType) string {
+	switch valueType {
+	case ValueTypeInt:
+		return "Int"
+	case ValueTypeString:
+		return "String"
+	case ValueTypeInt64:
+		return "Int64"
+	case ValueTypeUUID:
+		return "UUID"
+	}
+
+	// the go types of ValueTypeOf are stored using the field builder of their builtin value type
+	if c, ok := lookupValueType(valueType); ok && c.builder < valueTypeCustom {
+		return valueTypeField(c.builder)
+	}
+
+	return ""
+}
+
+// valueTypeZero returns the zero value expression of the go type name of the value type, values
+// from the context equal to the zero value are not recorded
+func valueTypeZero(typeName string) string {
+	switch typeName {
+	case "string":
+		return `""`
+	case "uuid.UUID":
+		return "uuid.Nil"
+	case "int", "int64", "":
+		return "0"
+	default:
+		return fmt.Sprintf("*new(%s)", typeName)
 	}
 }
 
@@ -65,15 +136,639 @@ func in(str string, list []string) bool {
 	return false
 }
 
+// quoteJoin quotes each string in the list and joins them with a comma
+// so they can be used as a string slice literal in the templates
+func quoteJoin(list []string) string {
+	quoted := make([]string, len(list))
+
+	for i, item := range list {
+		quoted[i] = strconv.Quote(item)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// convertEnum converts the expression to the enum type of the target node when the field
+// is an enum without a GoType; ent generates a separate enum type for each node (e.g. user.Status
+// and userhistory.Status) so the value cannot be set directly, otherwise the expression is returned as is
+func convertEnum(f *gen.Field, target *gen.Type, expr string, pointer bool) string {
+	if !f.IsEnum() || f.HasGoType() {
+		return expr
+	}
+
+	pascal := gen.Funcs["pascal"].(func(string) string)
+	enumType := fmt.Sprintf("%s.%s", target.PackageDir(), pascal(f.Name))
+
+	if pointer {
+		return fmt.Sprintf("(*%s)(%s)", enumType, expr)
+	}
+
+	return fmt.Sprintf("%s(%s)", enumType, expr)
+}
+
+// isOptionalEnum checks if the field is an optional enum that is not nillable, the zero value
+// of these fields is not a valid enum value so it should not be set when copying the field
+func isOptionalEnum(f *gen.Field) bool {
+	return f.IsEnum() && f.Optional && !f.Nillable
+}
+
+// isOptionalReference checks if the field is an optional edge field (e.g. owner_id) that is not nillable,
+// the zero value of these fields does not reference a record so it is cleared instead when restoring
+func isOptionalReference(f *gen.Field) bool {
+	return f.IsEdgeField() && f.Optional && !f.Nillable
+}
+
+// zeroValue returns the zero value expression of the field type
+func zeroValue(f *gen.Field) string {
+	switch {
+	case f.Type == nil:
+		return "nil"
+	case f.IsString(), f.IsEnum():
+		return `""`
+	case f.Type.Numeric():
+		return "0"
+	case f.IsBool():
+		return "false"
+	default:
+		return f.Type.String() + "{}"
+	}
+}
+
+// sampleValue returns the expression of a sample value of the field used by the generated history tests, or of a
+// different sample value when updated; this is empty for fields without a sample value (e.g. edge fields, fields
+// with a custom Go type, and the updated value of JSON fields), which must be set by the tests
+func sampleValue(f *gen.Field, n *gen.Type, updated bool) string {
+	switch {
+	case f.Type == nil, f.IsEdgeField():
+		return ""
+	case f.IsEnum() && !f.HasGoType() && len(f.Enums) > 0:
+		e := f.Enums[0]
+		if updated {
+			e = f.Enums[len(f.Enums)-1]
+		}
+
+		return fmt.Sprintf("%s.%s", n.PackageDir(), e.Name)
+	case f.HasGoType():
+		return ""
+	case f.IsString():
+		if updated {
+			return `"sample updated"`
+		}
+
+		return `"sample"`
+	case f.Type.Numeric():
+		if updated {
+			return "2"
+		}
+
+		return "1"
+	case f.IsBool():
+		return fmt.Sprint(!updated)
+	case f.IsTime():
+		if updated {
+			return "time.Now().Add(time.Hour)"
+		}
+
+		return "time.Now()"
+	case f.IsBytes():
+		if updated {
+			return `[]byte("sample updated")`
+		}
+
+		return `[]byte("sample")`
+	case f.IsJSON() && !updated:
+		return "*new(" + f.Type.String() + ")"
+	default:
+		return ""
+	}
+}
+
+// sampleCreate reports if the generated history tests can create the node using sample values, which is not the case
+// when the node has required edges, or required fields without a sample value
+func sampleCreate(n *gen.Type) bool {
+	for _, e := range n.Edges {
+		if !e.Optional {
+			return false
+		}
+	}
+
+	for _, f := range n.Fields {
+		if !f.Optional && !f.Default && sampleValue(f, n, false) == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sampleUpdateField returns the field updated by the generated history tests, the first field with a sample value that
+// can be updated and is not ignored by the history, nil when there is none
+func sampleUpdateField(n *gen.Type, softDelete string) (*gen.Field, error) {
+	ignored, err := ignoredUpdateFields(n)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range n.Fields {
+		if f.Immutable || f.Name == softDelete || slices.Contains(ignored, f) || sampleValue(f, n, true) == "" {
+			continue
+		}
+
+		return f, nil
+	}
+
+	return nil, nil
+}
+
+// sensitiveFields returns the quoted names of the sensitive fields of the node, and of the fields annotated using
+// Redact, which are left out of the values of the attempted changes
+func sensitiveFields(n *gen.Type) []string {
+	names := []string{}
+
+	for _, f := range n.Fields {
+		if f.Sensitive() || redactedField(f) {
+			names = append(names, strconv.Quote(f.Name))
+		}
+	}
+
+	return names
+}
+
+// maskedField checks if the field of the node is sensitive or annotated using Redact, the values of these fields are
+// masked in the audit log when using WithAuditMasking
+func maskedField(n *gen.Type, name string) bool {
+	i := slices.IndexFunc(n.Fields, func(f *gen.Field) bool {
+		return f.Name == name
+	})
+
+	return i >= 0 && (n.Fields[i].Sensitive() || redactedField(n.Fields[i]))
+}
+
+// redactedField checks if the field is annotated using Redact
+func redactedField(f *gen.Field) bool {
+	ant, ok := f.Annotations[fieldAnnotationName].(map[string]any)
+	if !ok {
+		return false
+	}
+
+	redact, _ := ant["redact"].(bool)
+
+	return redact
+}
+
+// softDeleteField returns the soft delete field of the node, if it exists, only time and bool
+// fields are supported as these are used to determine if the record is deleted or restored
+func softDeleteField(n *gen.Type, name string) *gen.Field {
+	if name == "" {
+		return nil
+	}
+
+	for _, f := range n.Fields {
+		if f.Name != name || f.Type == nil {
+			continue
+		}
+
+		if f.IsTime() || f.IsBool() {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// historyType returns the history node of the node, if it exists
+func historyType(nodes []*gen.Type, n *gen.Type) *gen.Type {
+	for _, h := range nodes {
+		if h.Name == n.Name+"History" {
+			return h
+		}
+	}
+
+	return nil
+}
+
+// cascadeEdges returns the edges of the node to its dependent children, these are the one-to-many and one-to-one
+// edges owned by the node; the children of many-to-many edges are shared with other records and the edges are not
+// recorded on the history rows, so they cannot be told apart from the children deleted with other records
+func cascadeEdges(n *gen.Type) []*gen.Edge {
+	edges := []*gen.Edge{}
+
+	for _, e := range n.Edges {
+		if e.IsInverse() || e.Through != nil || e.Type == nil || e.M2O() || e.M2M() {
+			continue
+		}
+
+		edges = append(edges, e)
+	}
+
+	return edges
+}
+
+// edgeRefField returns the edge field (e.g. owner_id) on the other side of the edge, if it was defined in the schema
+func edgeRefField(e *gen.Edge) *gen.Field {
+	if e.Ref == nil {
+		return nil
+	}
+
+	return e.Ref.Field()
+}
+
+// goImport is an import path and the alias used for the import, if any
+type goImport struct {
+	Alias string
+	Path  string
+}
+
+// historyRef returns the expression of the ref recorded on the history rows of the receiver, this is the id
+// of the receiver, the natural ref of the RefFields of the history annotation, or the composite ref of the id
+// fields for edge schemas with a composite id
+func historyRef(n *gen.Type, receiver string) (string, error) {
+	if !n.HasCompositeID() {
+		fields, err := refFields(n)
+		if err != nil {
+			return "", err
+		}
+
+		if len(fields) == 0 {
+			return receiver + ".ID", nil
+		}
+
+		values := make([]string, 0, len(fields))
+		for _, f := range fields {
+			values = append(values, fmt.Sprintf("%s.%s", receiver, f.StructField()))
+		}
+
+		return fmt.Sprintf("enthistory.NaturalRef(%s)", strings.Join(values, ", ")), nil
+	}
+
+	values := make([]string, 0, len(n.EdgeSchema.ID))
+	for _, f := range n.EdgeSchema.ID {
+		values = append(values, fmt.Sprintf("%s.%s", receiver, f.StructField()))
+	}
+
+	return fmt.Sprintf("enthistory.CompositeRef(%s)", strings.Join(values, ", ")), nil
+}
+
+// refFields returns the fields of the RefFields of the history annotation of the node, which are recorded as the
+// ref of the history rows instead of the id, nil when the node uses its id
+func refFields(n *gen.Type) ([]*gen.Field, error) {
+	annotations, err := jsonUnmarshalAnnotations(n.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(annotations.RefFields) == 0 {
+		return nil, nil
+	}
+
+	fields := make([]*gen.Field, 0, len(annotations.RefFields))
+
+	for _, name := range annotations.RefFields {
+		i := slices.IndexFunc(n.Fields, func(f *gen.Field) bool {
+			return f.Name == name
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s on %s", ErrFieldNotFound, name, n.Name)
+		}
+
+		fields = append(fields, n.Fields[i])
+	}
+
+	return fields, nil
+}
+
+// idRef checks if the id of the node is recorded as the ref of its history rows, this is false for edge schemas
+// with a composite id and for nodes with the RefFields history annotation
+func idRef(n *gen.Type) bool {
+	if !n.HasOneFieldID() {
+		return false
+	}
+
+	annotations, err := jsonUnmarshalAnnotations(n.Annotations[annotationName])
+
+	return err != nil || len(annotations.RefFields) == 0
+}
+
+// isCompositeIDField checks if the field is part of the composite id of an edge schema, these fields
+// identify the record and cannot be updated
+func isCompositeIDField(n *gen.Type, f *gen.Field) bool {
+	if !n.HasCompositeID() {
+		return false
+	}
+
+	return slices.ContainsFunc(n.EdgeSchema.ID, func(id *gen.Field) bool {
+		return id.Name == f.Name
+	})
+}
+
+// hasField checks if the type has a field with the given name
+func hasField(n *gen.Type, name string) bool {
+	return slices.ContainsFunc(n.Fields, func(f *gen.Field) bool {
+		return f.Name == name
+	})
+}
+
+// historyAnnotations returns the history annotations of the node
+func historyAnnotations(n *gen.Type) (Annotations, error) {
+	return jsonUnmarshalAnnotations(n.Annotations[annotationName])
+}
+
+// ignoredUpdateFields returns the fields of the node set in the IgnoredUpdateFields history annotation, which do not
+// create update history when they are the only fields changed
+func ignoredUpdateFields(n *gen.Type) ([]*gen.Field, error) {
+	annotations, err := jsonUnmarshalAnnotations(n.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]*gen.Field, 0, len(annotations.IgnoredUpdateFields))
+
+	for _, name := range annotations.IgnoredUpdateFields {
+		i := slices.IndexFunc(n.Fields, func(f *gen.Field) bool {
+			return f.Name == name
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s on %s", ErrFieldNotFound, name, n.Name)
+		}
+
+		fields = append(fields, n.Fields[i])
+	}
+
+	return fields, nil
+}
+
+// sampleInterval returns the SampleInterval of the history annotation of the node, zero when the node is not sampled
+func sampleInterval(n *gen.Type) (time.Duration, error) {
+	annotations, err := jsonUnmarshalAnnotations(n.Annotations[annotationName])
+	if err != nil {
+		return 0, err
+	}
+
+	return annotations.SampleInterval, nil
+}
+
+// skipOp checks if the operation (CREATE, UPDATE, or DELETE) of the node does not create history, set using the
+// SkipOps of the history annotation
+func skipOp(n *gen.Type, op string) (bool, error) {
+	annotations, err := jsonUnmarshalAnnotations(n.Annotations[annotationName])
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(annotations.SkipOps, op), nil
+}
+
+// edgeHistoryName returns the name of the history schema of the join table of a many-to-many edge
+func edgeHistoryName(table string) string {
+	pascal := gen.Funcs["pascal"].(func(string) string)
+
+	return pascal(table) + "History"
+}
+
+// edgeHistoryType returns the history type recording the changes of the many-to-many edge, this is
+// nil for other edges, edges with an edge schema, or when the edge history is not generated
+func edgeHistoryType(nodes []*gen.Type, e *gen.Edge) *gen.Type {
+	if !e.M2M() || e.Through != nil || len(e.Rel.Columns) != 2 {
+		return nil
+	}
+
+	name := edgeHistoryName(e.Rel.Table)
+
+	for _, n := range nodes {
+		if n.Name == name {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// isEdgeHistory checks if the type is the history type of a many-to-many edge, which are not
+// paired with an original type like the history types of schemas
+func isEdgeHistory(nodes []*gen.Type, n *gen.Type) bool {
+	for _, node := range nodes {
+		for _, e := range node.Edges {
+			if edgeHistoryType(nodes, e) == n {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// edgeHistoryRef is an edge history type holding the id of a record in its columns
+type edgeHistoryRef struct {
+	// Type is the edge history type
+	Type *gen.Type
+	// Columns are the columns of the edge history type holding the id of the record
+	Columns []string
+}
+
+// edgeHistoryRefs returns the edge history types of the many-to-many edges of the original type of the history type,
+// with the columns holding the id of the original record, so they are erased with the history of the record
+func edgeHistoryRefs(nodes []*gen.Type, h *gen.Type) []edgeHistoryRef {
+	refs := []edgeHistoryRef{}
+
+	for _, n := range nodes {
+		if historyType(nodes, n) != h {
+			continue
+		}
+
+		for _, e := range n.Edges {
+			eh := edgeHistoryType(nodes, e)
+			if eh == nil {
+				continue
+			}
+
+			// the first column holds the id of the owner of the edge, and both hold ids of the record on self-references
+			columns := []string{e.Rel.Columns[0]}
+
+			switch {
+			case e.Type == n:
+				columns = e.Rel.Columns
+			case e.IsInverse():
+				columns = e.Rel.Columns[1:]
+			}
+
+			i := slices.IndexFunc(refs, func(r edgeHistoryRef) bool { return r.Type == eh })
+			if i < 0 {
+				refs = append(refs, edgeHistoryRef{Type: eh})
+				i = len(refs) - 1
+			}
+
+			for _, c := range columns {
+				if !slices.Contains(refs[i].Columns, c) {
+					refs[i].Columns = append(refs[i].Columns, c)
+				}
+			}
+		}
+	}
+
+	return refs
+}
+
+// tenantTypes returns the history types, and the latest history views and audit summary view built on them, holding
+// the tenant_id field, these are the types constrained to the tenant on the context when using WithTenantField
+func tenantTypes(nodes []*gen.Type) []*gen.Type {
+	names := map[string]bool{
+		"AuditSummary": true,
+	}
+
+	for _, n := range nodes {
+		if h := historyType(nodes, n); h != nil {
+			names[h.Name] = true
+			names[h.Name+"Latest"] = true
+		}
+
+		if isEdgeHistory(nodes, n) {
+			names[n.Name] = true
+		}
+	}
+
+	types := []*gen.Type{}
+
+	for _, n := range nodes {
+		if names[n.Name] && hasField(n, tenantFieldName) {
+			types = append(types, n)
+		}
+	}
+
+	return types
+}
+
+// orderTypes returns the history types, the latest history views built on them, and the edge history types, these are
+// the types ordered by history_time by default when using WithDefaultOrder
+func orderTypes(nodes []*gen.Type) []*gen.Type {
+	names := map[string]bool{}
+
+	for _, n := range nodes {
+		if h := historyType(nodes, n); h != nil {
+			names[h.Name] = true
+			names[h.Name+"Latest"] = true
+		}
+
+		if isEdgeHistory(nodes, n) {
+			names[n.Name] = true
+		}
+	}
+
+	types := []*gen.Type{}
+
+	for _, n := range nodes {
+		if names[n.Name] && hasField(n, historyTimeFieldName) {
+			types = append(types, n)
+		}
+	}
+
+	return types
+}
+
+// edgeHistoryColumns returns the fields of the edge history type holding the ids of both ends of the edge
+func edgeHistoryColumns(n *gen.Type) []*gen.Field {
+	managed := []string{"history_time", "operation", "updated_by", tenantFieldName}
+
+	var columns []*gen.Field
+
+	for _, f := range n.Fields {
+		if !slices.Contains(managed, f.Name) {
+			columns = append(columns, f)
+		}
+	}
+
+	return columns
+}
+
+// goTypeImports returns the imports needed for the custom go types (e.g. GoType or JSON fields)
+// used by the id and fields of the nodes, skipping any import paths that are excluded
+// because they are already imported by the template
+func goTypeImports(nodes []*gen.Type, exclude ...string) []goImport {
+	seen := map[string]bool{}
+	goImports := []goImport{}
+
+	for _, n := range nodes {
+		fields := n.Fields
+		if n.HasOneFieldID() {
+			fields = append([]*gen.Field{n.ID}, fields...)
+		}
+
+		for _, f := range fields {
+			if f.Type == nil || f.Type.PkgPath == "" {
+				continue
+			}
+
+			pkgPath := f.Type.PkgPath
+			if seen[pkgPath] || in(pkgPath, exclude) {
+				continue
+			}
+
+			seen[pkgPath] = true
+
+			imp := goImport{
+				Path: pkgPath,
+			}
+
+			// alias the import when the package name does not match the last element of the path
+			// e.g. github.com/google/uuid/v2 or gopkg.in/yaml.v3
+			if f.Type.PkgName != "" && f.Type.PkgName != path.Base(pkgPath) {
+				imp.Alias = f.Type.PkgName
+			}
+
+			goImports = append(goImports, imp)
+		}
+	}
+
+	sort.Slice(goImports, func(i, j int) bool {
+		return goImports[i].Path < goImports[j].Path
+	})
+
+	return goImports
+}
+
 // parseTemplate parses the template and sets values in the template
 func parseTemplate(name, path string) *gen.Template {
 	t := gen.NewTemplate(name)
 	t.Funcs(template.FuncMap{
 		"extractUpdatedByKey":       extractUpdatedByKey,
 		"extractUpdatedByValueType": extractUpdatedByValueType,
+		"extractDeletedByKey":       extractDeletedByKey,
+		"extractDeletedByValueType": extractDeletedByValueType,
 		"fieldPropertiesNillable":   fieldPropertiesNillable,
 		"isSlice":                   isSlice,
 		"in":                        in,
+		"goTypeImports":             goTypeImports,
+		"convertEnum":               convertEnum,
+		"isOptionalEnum":            isOptionalEnum,
+		"isOptionalReference":       isOptionalReference,
+		"zeroValue":                 zeroValue,
+		"softDeleteField":           softDeleteField,
+		"historyType":               historyType,
+		"cascadeEdges":              cascadeEdges,
+		"edgeRefField":              edgeRefField,
+		"historyRef":                historyRef,
+		"refFields":                 refFields,
+		"idRef":                     idRef,
+		"skipOp":                    skipOp,
+		"isCompositeIDField":        isCompositeIDField,
+		"edgeHistoryType":           edgeHistoryType,
+		"isEdgeHistory":             isEdgeHistory,
+		"edgeHistoryColumns":        edgeHistoryColumns,
+		"tenantTypes":               tenantTypes,
+		"orderTypes":                orderTypes,
+		"edgeHistoryRefs":           edgeHistoryRefs,
+		"hasField":                  hasField,
+		"ignoredUpdateFields":       ignoredUpdateFields,
+		"sampleInterval":            sampleInterval,
+		"valueTypeName":             valueTypeName,
+		"valueTypeZero":             valueTypeZero,
+		"fieldBuilder":              fieldBuilder,
+		"historyAnnotations":        historyAnnotations,
+		"sampleValue":               sampleValue,
+		"sampleCreate":              sampleCreate,
+		"sampleUpdateField":         sampleUpdateField,
+		"sensitiveFields":           sensitiveFields,
+		"maskedField":               maskedField,
+		"auditedTable":              auditedTable,
 	})
 
 	return gen.MustParse(t.ParseFS(_templates, path))
@@ -81,17 +776,62 @@ func parseTemplate(name, path string) *gen.Template {
 
 // parseSchemaTemplate parses the template and sets values in the template
 func parseSchemaTemplate(info templateInfo, path string) error {
-	name := "schema"
+	overrides := map[string]string{}
+
+	// the policy block can be replaced using WithPolicyTemplate
+	if info.PolicyTemplate != "" {
+		overrides["policy"] = info.PolicyTemplate
+	}
+
+	return executeSchemaTemplate("schema", info, path, overrides)
+}
+
+// parseHistoryMetaSchemaTemplate parses the history meta template and sets values in the template
+func parseHistoryMetaSchemaTemplate(info historyMetaTemplateInfo, path string) error {
+	return executeSchemaTemplate("historyMetaSchema", info, path, nil)
+}
+
+// parseHistoryAttemptSchemaTemplate parses the history attempt template and sets values in the template
+func parseHistoryAttemptSchemaTemplate(info historyMetaTemplateInfo, path string) error {
+	return executeSchemaTemplate("historyAttemptSchema", info, path, nil)
+}
+
+// parseLatestViewSchemaTemplate parses the latest history view template and sets values in the template
+func parseLatestViewSchemaTemplate(info latestViewTemplateInfo, path string) error {
+	return executeSchemaTemplate("latestViewSchema", info, path, nil)
+}
+
+// parseAuditSummarySchemaTemplate parses the audit summary template and sets values in the template
+func parseAuditSummarySchemaTemplate(info auditSummaryTemplateInfo, path string) error {
+	return executeSchemaTemplate("auditSummarySchema", info, path, nil)
+}
+
+// parseEdgeSchemaTemplate parses the edge history template and sets values in the template
+func parseEdgeSchemaTemplate(info edgeTemplateInfo, path string) error {
+	return executeSchemaTemplate("edgeSchema", info, path, nil)
+}
+
+// executeSchemaTemplate executes the schema template with the given name and writes the formatted output to the path,
+// the overrides replace the templates defined by the schema template with the given name
+func executeSchemaTemplate(name string, info any, path string, overrides map[string]string) error {
 	templateName := fmt.Sprintf("%s.tmpl", name)
 
-	t := template.New("schema")
+	t := template.New(name)
 	t.Funcs(template.FuncMap{
 		"ToUpperCamel": strcase.UpperCamelCase,
 		"ToLower":      strings.ToLower,
+		"quoteJoin":    quoteJoin,
+		"fieldBuilder": fieldBuilder,
 	})
 
 	template.Must(t.ParseFS(_templates, fmt.Sprintf("%s/%s", templateDir, templateName)))
 
+	for overrideName, override := range overrides {
+		if _, err := t.New(overrideName).Parse(override); err != nil {
+			return fmt.Errorf("%w: failed to parse %s template: %v", ErrFailedToGenerateTemplate, overrideName, err)
+		}
+	}
+
 	var buf bytes.Buffer
 	if err := t.ExecuteTemplate(&buf, templateName, info); err != nil {
 		return fmt.Errorf("%w: failed to execute template: %v", ErrFailedToGenerateTemplate, err)
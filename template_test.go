@@ -1,9 +1,16 @@
 package enthistory
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/entc/load"
+	"entgo.io/ent/schema/field"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtractUpdatedByKey(t *testing.T) {
@@ -61,6 +68,22 @@ func TestExxtractUpdatedByValueType(t *testing.T) {
 			},
 			want: "int",
 		},
+		{
+			name: "happy path, int64",
+			val: &UpdatedBy{
+				key:       "userID",
+				valueType: ValueTypeInt64,
+			},
+			want: "int64",
+		},
+		{
+			name: "happy path, uuid",
+			val: &UpdatedBy{
+				key:       "userID",
+				valueType: ValueTypeUUID,
+			},
+			want: "uuid.UUID",
+		},
 		{
 			name: "invalid type",
 			val: &UpdatedBy{
@@ -85,6 +108,91 @@ func TestExxtractUpdatedByValueType(t *testing.T) {
 	}
 }
 
+func TestValueTypeField(t *testing.T) {
+	assert.Equal(t, "Int", valueTypeField(ValueTypeInt))
+	assert.Equal(t, "String", valueTypeField(ValueTypeString))
+	assert.Equal(t, "Int64", valueTypeField(ValueTypeInt64))
+	assert.Equal(t, "UUID", valueTypeField(ValueTypeUUID))
+	assert.Equal(t, "", valueTypeField(42))
+}
+
+func TestValueTypeZero(t *testing.T) {
+	assert.Equal(t, "0", valueTypeZero("int"))
+	assert.Equal(t, "0", valueTypeZero("int64"))
+	assert.Equal(t, `""`, valueTypeZero("string"))
+	assert.Equal(t, "uuid.Nil", valueTypeZero("uuid.UUID"))
+	assert.Equal(t, "*new(ids.UserID)", valueTypeZero("ids.UserID"))
+}
+
+func TestExtractDeletedByKey(t *testing.T) {
+	tests := []struct {
+		name string
+		val  any
+		want string
+	}{
+		{
+			name: "happy path",
+			val: &DeletedBy{
+				key:       "userID",
+				valueType: ValueTypeString,
+			},
+			want: "userID",
+		},
+		{
+			name: "nil deleted by",
+			val:  (*DeletedBy)(nil),
+			want: "",
+		},
+		{
+			name: "bad type",
+			val:  "something else",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractDeletedByKey(tt.val)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExtractDeletedByValueType(t *testing.T) {
+	tests := []struct {
+		name string
+		val  any
+		want string
+	}{
+		{
+			name: "happy path, string",
+			val: &DeletedBy{
+				key:       "userID",
+				valueType: ValueTypeString,
+			},
+			want: "string",
+		},
+		{
+			name: "happy path, int",
+			val: &DeletedBy{
+				key:       "userID",
+				valueType: ValueTypeInt,
+			},
+			want: "int",
+		},
+		{
+			name: "nil deleted by",
+			val:  (*DeletedBy)(nil),
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractDeletedByValueType(tt.val)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestFieldPropertiesNillable(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -195,3 +303,1444 @@ func TestIn(t *testing.T) {
 		})
 	}
 }
+
+func TestQuoteJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		list []string
+		want string
+	}{
+		{
+			name: "multiple",
+			list: []string{"EntGQL", "EntSQL"},
+			want: `"EntGQL", "EntSQL"`,
+		},
+		{
+			name: "single",
+			list: []string{"EntGQL"},
+			want: `"EntGQL"`,
+		},
+		{
+			name: "empty",
+			list: []string{},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quoteJoin(tt.list)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSchemaTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		info        templateInfo
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "no indexes",
+			info: templateInfo{},
+			notContains: []string{
+				"Indexes()",
+			},
+		},
+		{
+			name: "history time index",
+			info: templateInfo{
+				WithHistoryTimeIndex: true,
+			},
+			contains: []string{
+				`index.Fields("history_time")`,
+			},
+			notContains: []string{
+				`index.Fields("ref", "history_time")`,
+			},
+		},
+		{
+			name: "history time index type and name",
+			info: templateInfo{
+				WithHistoryTimeIndex: true,
+				HistoryTimeIndexType: "BRIN",
+				HistoryTimeIndexName: "todo_history_time_brin",
+			},
+			contains: []string{
+				`index.Fields("history_time").`,
+				`StorageKey("todo_history_time_brin").`,
+				`Annotations(entsql.IndexTypes(map[string]string{dialect.Postgres: "BRIN"})),`,
+			},
+		},
+		{
+			name: "ref history time index",
+			info: templateInfo{
+				WithRefHistoryTimeIndex: true,
+			},
+			contains: []string{
+				`index.Fields("ref", "history_time")`,
+			},
+			notContains: []string{
+				`index.Fields("history_time")`,
+			},
+		},
+		{
+			name: "updated by index",
+			info: templateInfo{
+				WithUpdatedByIndex: true,
+			},
+			contains: []string{
+				`index.Fields("updated_by")`,
+			},
+		},
+		{
+			name: "deleted by",
+			info: templateInfo{
+				WithDeletedBy:      true,
+				DeletedByValueType: "String",
+			},
+			contains: []string{
+				`field.String("deleted_by")`,
+			},
+		},
+		{
+			name: "uuid updated by",
+			info: templateInfo{
+				WithUpdatedBy:      true,
+				UpdatedByValueType: "UUID",
+			},
+			contains: []string{
+				`field.UUID("updated_by", uuid.UUID{})`,
+				`"github.com/google/uuid"`,
+			},
+		},
+		{
+			name: "go type updated by",
+			info: templateInfo{
+				WithUpdatedBy:      true,
+				UpdatedByValueType: "String",
+				UpdatedByGoType:    "ids.UserID",
+				ValueTypeImports:   []goImport{{Path: "example.com/ids"}},
+			},
+			contains: []string{
+				`field.String("updated_by").GoType(*new(ids.UserID))`,
+				`"example.com/ids"`,
+			},
+		},
+		{
+			name: "int64 deleted by",
+			info: templateInfo{
+				WithDeletedBy:      true,
+				DeletedByValueType: "Int64",
+			},
+			contains: []string{
+				`field.Int64("deleted_by")`,
+			},
+		},
+		{
+			name: "restored from",
+			info: templateInfo{
+				WithRestoredFrom: true,
+			},
+			contains: []string{
+				`field.String("restored_from")`,
+			},
+		},
+		{
+			name: "custom operations",
+			info: templateInfo{
+				Operations: []string{"IMPORT", "MERGE"},
+			},
+			contains: []string{
+				`Values("IMPORT", "MERGE")`,
+			},
+		},
+		{
+			name: "correlation id",
+			info: templateInfo{
+				WithCorrelationID: true,
+			},
+			contains: []string{
+				`field.String("correlation_id")`,
+				`index.Fields("correlation_id")`,
+			},
+		},
+		{
+			name: "authz decision",
+			info: templateInfo{
+				WithAuthzDecision: true,
+			},
+			contains: []string{
+				`field.String("authz_subject")`,
+				`field.String("authz_relation")`,
+			},
+		},
+		{
+			name: "composite id",
+			info: templateInfo{
+				CompositeID: []string{"user_id", "group_id"},
+			},
+			contains: []string{
+				`field.Int("id")`,
+				`field.String("ref")`,
+				"(user_id, group_id)",
+			},
+		},
+		{
+			name: "tenant field",
+			info: templateInfo{
+				WithTenantField: true,
+			},
+			contains: []string{
+				`field.String("tenant_id")`,
+				`index.Fields("tenant_id")`,
+			},
+			notContains: []string{
+				"Interceptors() []ent.Interceptor",
+			},
+		},
+		{
+			name: "history time schema type",
+			info: templateInfo{
+				HistoryTimeSchemaType: map[string]string{"Postgres": "timestamptz(6)", "MySQL": "timestamp(6)"},
+			},
+			contains: []string{
+				`dialect.MySQL:    "timestamp(6)"`,
+				`dialect.Postgres: "timestamptz(6)"`,
+			},
+		},
+		{
+			name: "synthetic",
+			info: templateInfo{
+				WithSynthetic: true,
+			},
+			contains: []string{
+				`field.Bool("synthetic")`,
+				"Default(false)",
+			},
+		},
+		{
+			name: "schema version",
+			info: templateInfo{
+				SchemaVersion:     2,
+				SchemaHash:        "0123456789abcdef",
+				SchemaGeneratedAt: 1704164645,
+			},
+			contains: []string{
+				"SchemaVersion:     2",
+				`SchemaHash:        "0123456789abcdef"`,
+				"SchemaGeneratedAt: 1704164645",
+				`field.Int("schema_version")`,
+				"Default(2)",
+			},
+		},
+		{
+			name: "legacy fields",
+			info: templateInfo{
+				SchemaVersion:     3,
+				SchemaHash:        "0123456789abcdef",
+				SchemaGeneratedAt: 1704164645,
+				SchemaFields:      map[string]string{"name": "TypeString"},
+				LegacyFields: []legacyFieldInfo{
+					{Name: "due", Type: "TypeTime", Builder: "Time"},
+					{Name: "metadata", Type: "TypeJSON", Builder: "JSON"},
+				},
+			},
+			contains: []string{
+				`"name": "TypeString"`,
+				`"due":      "TypeTime"`,
+				`field.Time("due")`,
+				`field.JSON("metadata", json.RawMessage{})`,
+			},
+		},
+		{
+			name: "additional fields",
+			info: templateInfo{
+				AdditionalFields: []additionalFieldInfo{{Name: "region", ValueType: "string"}},
+			},
+			contains: []string{
+				`field.String("region")`,
+			},
+		},
+		{
+			name: "policy template",
+			info: templateInfo{
+				WithHistoryPolicy: true,
+				PolicyTemplate: `
+// Policy of the {{ .Schema.Name }}
+func ({{ .Schema.Name }}) Policy() ent.Policy {
+	return enthistory.InheritedPolicy({{ .OriginalTableName }}{}.Policy(), nil)
+}`,
+			},
+			contains: []string{
+				"func (TodoHistory) Policy() ent.Policy",
+				"return enthistory.InheritedPolicy(Todo{}.Policy(), nil)",
+			},
+			notContains: []string{
+				"return enthistory.HistoryPolicy()",
+			},
+		},
+		{
+			name: "authz policy",
+			info: templateInfo{
+				AddPolicy: true,
+				AuthzPolicy: authzPolicyInfo{
+					Enabled:    true,
+					ObjectType: "todo",
+					IDField:    "Ref",
+				},
+			},
+			contains: []string{
+				"Policy() ent.Policy",
+				"enthistory.SystemContextRule()",
+				"privacy.AlwaysDenyRule()",
+			},
+		},
+		{
+			name: "inherited policy",
+			info: templateInfo{
+				OriginalTableName:   "Todo",
+				WithInheritedPolicy: true,
+			},
+			contains: []string{
+				"Policy() ent.Policy",
+				"return enthistory.InheritedPolicy(Todo{}.Policy(), nil)",
+			},
+		},
+		{
+			name: "inherited policy with history policy",
+			info: templateInfo{
+				OriginalTableName:   "Todo",
+				WithInheritedPolicy: true,
+				WithHistoryPolicy:   true,
+			},
+			contains: []string{
+				"return enthistory.InheritedPolicy(Todo{}.Policy(), enthistory.HistoryPolicy())",
+			},
+		},
+		{
+			name: "owner read rules",
+			info: templateInfo{
+				OwnerField:      "owner_id",
+				OwnerObjectType: "organization",
+			},
+			contains: []string{
+				"Policy() ent.Policy",
+				`return enthistory.OwnedPolicy(nil, "owner_id", "organization")`,
+			},
+		},
+		{
+			name: "owner read rules with inherited policy",
+			info: templateInfo{
+				OriginalTableName:   "Todo",
+				WithInheritedPolicy: true,
+				WithHistoryPolicy:   true,
+				OwnerField:          "owner_id",
+				OwnerObjectType:     "organization",
+			},
+			contains: []string{
+				`return enthistory.OwnedPolicy(enthistory.InheritedPolicy(Todo{}.Policy(), enthistory.HistoryPolicy()), "owner_id", "organization")`,
+			},
+		},
+		{
+			name: "owner read rules with authz policy",
+			info: templateInfo{
+				AddPolicy: true,
+				AuthzPolicy: authzPolicyInfo{
+					Enabled:    true,
+					ObjectType: "todo",
+					IDField:    "Ref",
+				},
+				OwnerField:      "account_id",
+				OwnerObjectType: "user",
+			},
+			contains: []string{
+				"enthistory.SystemContextRule(),\n\t\t\tenthistory.OwnerQueryRule(\"account_id\", \"user\"),",
+				"privacy.AlwaysDenyRule()",
+			},
+			notContains: []string{
+				"OwnedPolicy",
+			},
+		},
+		{
+			name: "history policy",
+			info: templateInfo{
+				WithHistoryPolicy: true,
+			},
+			contains: []string{
+				"Policy() ent.Policy",
+				"return enthistory.HistoryPolicy()",
+			},
+		},
+		{
+			name: "mirrored indexes",
+			info: templateInfo{
+				Indexes: [][]string{{"tenant_id"}, {"age", "name"}},
+			},
+			contains: []string{
+				`index.Fields("tenant_id")`,
+				`index.Fields("age", "name")`,
+			},
+		},
+		{
+			name: "compressed fields",
+			info: templateInfo{
+				CompressedFields: map[string]string{"body": "gzip"},
+			},
+			contains: []string{
+				"Compressed: map[string]enthistory.Compression{",
+				`"body": "gzip",`,
+			},
+		},
+		{
+			name: "field limits",
+			info: templateInfo{
+				OriginalTableName: "Todo",
+				FieldLimits: []fieldLimitInfo{
+					{Field: "body", MaxSize: 4096, Strategy: "LimitHash"},
+				},
+			},
+			contains: []string{
+				"Hooks() []ent.Hook",
+				`enthistory.FieldLimitHook("Todo",`,
+				`enthistory.FieldLimit{Field: "body", MaxSize: 4096, Strategy: enthistory.LimitHash},`,
+			},
+		},
+		{
+			name: "comments",
+			info: templateInfo{
+				Comment:           "History of todos table, generated by enthistory",
+				WithCorrelationID: true,
+			},
+			contains: []string{
+				`schema.Comment("History of todos table, generated by enthistory"),`,
+				"entsql.WithComments(true),",
+				`Comment("time the history row was created").`,
+				`Comment("id of the changed Todo").`,
+				`Comment("id of the changes committed together").`,
+			},
+		},
+		{
+			name: "no comments",
+			info: templateInfo{},
+			notContains: []string{
+				"Comment(",
+				"WithComments",
+			},
+		},
+		{
+			name: "redacted fields",
+			info: templateInfo{
+				RedactedFields: []string{"ssn", "token"},
+			},
+			contains: []string{
+				"Hooks() []ent.Hook",
+				`enthistory.RedactHook("ssn", "token"),`,
+			},
+			notContains: []string{
+				"FieldLimitHook",
+			},
+		},
+		{
+			name: "restricted fields",
+			info: templateInfo{
+				RestrictedFields: []string{"salary", "ssn"},
+			},
+			contains: []string{
+				"Interceptors() []ent.Interceptor",
+				`enthistory.RestrictInterceptor("Todo", "salary", "ssn"),`,
+			},
+			notContains: []string{
+				"FieldAccessInterceptor",
+			},
+		},
+		{
+			name: "restricted fields with gql query and authz policy",
+			info: templateInfo{
+				Query:            true,
+				RestrictedFields: []string{"salary"},
+				AuthzPolicy: authzPolicyInfo{
+					Enabled:    true,
+					ObjectType: "organization",
+					IDField:    "OwnerID",
+				},
+			},
+			contains: []string{
+				`enthistory.FieldAccessInterceptor("organization", "OwnerID", "salary"),`,
+			},
+			notContains: []string{
+				"RestrictInterceptor",
+			},
+		},
+		{
+			name: "gql federation",
+			info: templateInfo{
+				Query:      true,
+				Federation: true,
+			},
+			contains: []string{
+				`"github.com/vektah/gqlparser/v2/ast"`,
+				`entgql.NewDirective("key", &ast.Argument{Name: "fields", Value: &ast.Value{Raw: "id", Kind: ast.StringValue}}),`,
+				`entgql.NewDirective("shareable"),`,
+			},
+		},
+		{
+			name: "gql query without federation",
+			info: templateInfo{
+				Query: true,
+			},
+			contains: []string{
+				"entgql.QueryField(),",
+			},
+			notContains: []string{
+				"entgql.Directives(",
+				"gqlparser",
+			},
+		},
+		{
+			name: "sink",
+			info: templateInfo{
+				WithSink: true,
+			},
+			contains: []string{
+				"Hooks() []ent.Hook",
+				"enthistory.SinkHook(),",
+			},
+			notContains: []string{
+				"FieldLimitHook",
+			},
+		},
+		{
+			name: "callbacks",
+			info: templateInfo{
+				WithCallbacks: true,
+			},
+			contains: []string{
+				"Hooks() []ent.Hook",
+				"enthistory.CallbackHook(),",
+			},
+			notContains: []string{
+				"enthistory.SinkHook(),",
+			},
+		},
+		{
+			name: "no field annotation config",
+			info: templateInfo{},
+			contains: []string{
+				"fieldConfig := enthistory.FieldConfig{}",
+				"enthistory.HistoryFields(original.Fields(), fieldConfig)",
+			},
+		},
+		{
+			name: "stripped field annotations",
+			info: templateInfo{
+				StrippedFieldAnnotations: []string{"EntGQL", "Authz"},
+			},
+			contains: []string{
+				`StrippedAnnotations: []string{"EntGQL", "Authz"},`,
+			},
+			notContains: []string{
+				"AllowedAnnotations",
+			},
+		},
+		{
+			name: "allowed field annotations",
+			info: templateInfo{
+				AllowedFieldAnnotations: []string{"EntSQL"},
+			},
+			contains: []string{
+				`AllowedAnnotations: []string{"EntSQL"},`,
+			},
+			notContains: []string{
+				"StrippedAnnotations",
+			},
+		},
+		{
+			name: "keep field defaults and validators",
+			info: templateInfo{
+				KeepFieldDefaults:   true,
+				KeepFieldValidators: true,
+			},
+			contains: []string{
+				"KeepDefaults:   true,",
+				"KeepValidators: true,",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.info.SchemaPkg = "schema"
+			tt.info.IDType = "string"
+			tt.info.OriginalTableName = "Todo"
+			tt.info.TableName = "todo_history"
+			tt.info.Schema = &load.Schema{Name: "TodoHistory"}
+
+			path := filepath.Join(t.TempDir(), "todo_history.go")
+
+			err := parseSchemaTemplate(tt.info, path)
+			require.NoError(t, err)
+
+			out, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			for _, s := range tt.contains {
+				assert.Contains(t, string(out), s)
+			}
+
+			for _, s := range tt.notContains {
+				assert.NotContains(t, string(out), s)
+			}
+		})
+	}
+}
+
+func TestGoTypeImports(t *testing.T) {
+	nodes := []*gen.Type{
+		{
+			Name: "Todo",
+			ID: &gen.Field{
+				Name: "id",
+				Type: &field.TypeInfo{Type: field.TypeUUID, Ident: "uuid.UUID", PkgPath: "github.com/google/uuid", PkgName: "uuid"},
+			},
+			Fields: []*gen.Field{
+				{
+					Name: "item",
+					Type: &field.TypeInfo{Type: field.TypeString},
+				},
+				{
+					Name: "settings",
+					Type: &field.TypeInfo{Type: field.TypeJSON, Ident: "yaml.Node", PkgPath: "gopkg.in/yaml.v3", PkgName: "yaml"},
+				},
+				{
+					Name: "status",
+					Type: &field.TypeInfo{Type: field.TypeEnum, Ident: "enums.Status", PkgPath: "github.com/foo/bar/enums", PkgName: "enums"},
+				},
+				{
+					Name: "due",
+					Type: &field.TypeInfo{Type: field.TypeOther, Ident: "time.Duration", PkgPath: "time", PkgName: "time"},
+				},
+			},
+		},
+		{
+			Name: "TodoHistory",
+			ID: &gen.Field{
+				Name: "id",
+				Type: &field.TypeInfo{Type: field.TypeUUID, Ident: "uuid.UUID", PkgPath: "github.com/google/uuid", PkgName: "uuid"},
+			},
+		},
+	}
+
+	got := goTypeImports(nodes, "time")
+
+	assert.Equal(t, []goImport{
+		{Path: "github.com/foo/bar/enums"},
+		{Path: "github.com/google/uuid"},
+		{Alias: "yaml", Path: "gopkg.in/yaml.v3"},
+	}, got)
+
+	assert.Empty(t, goTypeImports([]*gen.Type{}))
+}
+
+func TestConvertEnum(t *testing.T) {
+	target := &gen.Type{
+		Name:   "TodoHistory",
+		Config: &gen.Config{},
+	}
+
+	tests := []struct {
+		name    string
+		field   *gen.Field
+		expr    string
+		pointer bool
+		want    string
+	}{
+		{
+			name:  "not an enum",
+			field: &gen.Field{Name: "item", Type: &field.TypeInfo{Type: field.TypeString}},
+			expr:  "item",
+			want:  "item",
+		},
+		{
+			name:  "enum with go type",
+			field: &gen.Field{Name: "status", Type: &field.TypeInfo{Type: field.TypeEnum, Ident: "enums.Status", PkgPath: "github.com/foo/bar/enums", RType: &field.RType{Name: "Status"}}},
+			expr:  "status",
+			want:  "status",
+		},
+		{
+			name:  "enum values",
+			field: &gen.Field{Name: "status", Type: &field.TypeInfo{Type: field.TypeEnum, Ident: "todo.Status"}},
+			expr:  "todo.Status",
+			want:  "todohistory.Status(todo.Status)",
+		},
+		{
+			name:    "enum values pointer",
+			field:   &gen.Field{Name: "due_status", Type: &field.TypeInfo{Type: field.TypeEnum, Ident: "todo.DueStatus"}},
+			expr:    "&dueStatus",
+			pointer: true,
+			want:    "(*todohistory.DueStatus)(&dueStatus)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertEnum(tt.field, target, tt.expr, tt.pointer)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsOptionalEnum(t *testing.T) {
+	tests := []struct {
+		name  string
+		field *gen.Field
+		want  bool
+	}{
+		{
+			name:  "optional enum",
+			field: &gen.Field{Name: "status", Optional: true, Type: &field.TypeInfo{Type: field.TypeEnum}},
+			want:  true,
+		},
+		{
+			name:  "optional nillable enum",
+			field: &gen.Field{Name: "status", Optional: true, Nillable: true, Type: &field.TypeInfo{Type: field.TypeEnum}},
+			want:  false,
+		},
+		{
+			name:  "required enum",
+			field: &gen.Field{Name: "status", Type: &field.TypeInfo{Type: field.TypeEnum}},
+			want:  false,
+		},
+		{
+			name:  "optional string",
+			field: &gen.Field{Name: "item", Optional: true, Type: &field.TypeInfo{Type: field.TypeString}},
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := isOptionalEnum(tt.field)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSoftDeleteField(t *testing.T) {
+	node := &gen.Type{
+		Name: "Todo",
+		Fields: []*gen.Field{
+			{Name: "item", Type: &field.TypeInfo{Type: field.TypeString}},
+			{Name: "deleted_at", Type: &field.TypeInfo{Type: field.TypeTime}},
+			{Name: "is_deleted", Type: &field.TypeInfo{Type: field.TypeBool}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		fieldName string
+		want      string
+	}{
+		{
+			name:      "time field",
+			fieldName: "deleted_at",
+			want:      "deleted_at",
+		},
+		{
+			name:      "bool field",
+			fieldName: "is_deleted",
+			want:      "is_deleted",
+		},
+		{
+			name:      "unsupported type",
+			fieldName: "item",
+		},
+		{
+			name:      "field not found",
+			fieldName: "removed_at",
+		},
+		{
+			name:      "not set",
+			fieldName: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := softDeleteField(node, tt.fieldName)
+			if tt.want == "" {
+				assert.Nil(t, got)
+
+				return
+			}
+
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, got.Name)
+		})
+	}
+}
+
+func TestHistoryType(t *testing.T) {
+	todo := &gen.Type{Name: "Todo"}
+	todoHistory := &gen.Type{Name: "TodoHistory"}
+	user := &gen.Type{Name: "User"}
+
+	nodes := []*gen.Type{todo, todoHistory, user}
+
+	assert.Equal(t, todoHistory, historyType(nodes, todo))
+	assert.Nil(t, historyType(nodes, user))
+}
+
+func TestHistoryRef(t *testing.T) {
+	user := &gen.Type{Name: "User"}
+	group := &gen.Type{Name: "Group"}
+
+	membership := &gen.Type{Name: "Membership"}
+	membership.EdgeSchema.To = &gen.Edge{Name: "user", Type: user}
+	membership.EdgeSchema.From = &gen.Edge{Name: "group", Type: group}
+	membership.EdgeSchema.ID = []*gen.Field{{Name: "user_id"}, {Name: "group_id"}}
+
+	account := &gen.Type{
+		Name:   "Account",
+		ID:     &gen.Field{Name: "id"},
+		Fields: []*gen.Field{{Name: "tenant_id"}, {Name: "email"}},
+		Annotations: gen.Annotations{
+			annotationName: map[string]any{"refFields": []any{"tenant_id", "email"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		node     *gen.Type
+		receiver string
+		want     string
+	}{
+		{
+			name:     "id",
+			node:     &gen.Type{Name: "Todo"},
+			receiver: "todo",
+			want:     "todo.ID",
+		},
+		{
+			name:     "composite id",
+			node:     membership,
+			receiver: "node",
+			want:     "enthistory.CompositeRef(node.UserID, node.GroupID)",
+		},
+		{
+			name:     "ref fields",
+			node:     account,
+			receiver: "a",
+			want:     "enthistory.NaturalRef(a.TenantID, a.Email)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := historyRef(tt.node, tt.receiver)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	assert.True(t, isCompositeIDField(membership, &gen.Field{Name: "group_id"}))
+	assert.False(t, isCompositeIDField(membership, &gen.Field{Name: "role"}))
+	assert.False(t, isCompositeIDField(&gen.Type{Name: "Todo"}, &gen.Field{Name: "group_id"}))
+}
+
+func TestRefFields(t *testing.T) {
+	email := &gen.Field{Name: "email"}
+
+	account := &gen.Type{
+		Name:   "Account",
+		ID:     &gen.Field{Name: "id"},
+		Fields: []*gen.Field{{Name: "name"}, email},
+		Annotations: gen.Annotations{
+			annotationName: map[string]any{"refFields": []any{"email"}},
+		},
+	}
+
+	fields, err := refFields(account)
+	require.NoError(t, err)
+	assert.Equal(t, []*gen.Field{email}, fields)
+	assert.False(t, idRef(account))
+
+	todo := &gen.Type{Name: "Todo", ID: &gen.Field{Name: "id"}}
+
+	fields, err = refFields(todo)
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+	assert.True(t, idRef(todo))
+
+	missing := &gen.Type{
+		Name: "Account",
+		Annotations: gen.Annotations{
+			annotationName: map[string]any{"refFields": []any{"login"}},
+		},
+	}
+
+	_, err = refFields(missing)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+}
+
+func TestHasField(t *testing.T) {
+	todo := &gen.Type{Name: "Todo", Fields: []*gen.Field{{Name: "tenant_id"}}}
+
+	assert.True(t, hasField(todo, "tenant_id"))
+	assert.False(t, hasField(todo, "name"))
+}
+
+func TestIgnoredUpdateFields(t *testing.T) {
+	lastSeen := &gen.Field{Name: "last_seen_at"}
+
+	todo := &gen.Type{
+		Name:   "Todo",
+		Fields: []*gen.Field{{Name: "name"}, lastSeen},
+		Annotations: gen.Annotations{
+			annotationName: map[string]any{"ignoredUpdateFields": []string{"last_seen_at"}},
+		},
+	}
+
+	got, err := ignoredUpdateFields(todo)
+	require.NoError(t, err)
+	assert.Equal(t, []*gen.Field{lastSeen}, got)
+
+	got, err = ignoredUpdateFields(&gen.Type{Name: "Todo"})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	todo.Annotations[annotationName] = map[string]any{"ignoredUpdateFields": []string{"missing"}}
+
+	_, err = ignoredUpdateFields(todo)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+}
+
+func TestSampleInterval(t *testing.T) {
+	beat := &gen.Type{
+		Name: "Beat",
+		Annotations: gen.Annotations{
+			annotationName: map[string]any{"sampleInterval": time.Minute},
+		},
+	}
+
+	got, err := sampleInterval(beat)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, got)
+
+	got, err = sampleInterval(&gen.Type{Name: "Todo"})
+	require.NoError(t, err)
+	assert.Zero(t, got)
+}
+
+func TestSkipOp(t *testing.T) {
+	session := &gen.Type{
+		Name: "Session",
+		Annotations: gen.Annotations{
+			annotationName: map[string]any{"skipOps": []any{"CREATE", "UPDATE"}},
+		},
+	}
+
+	for op, want := range map[string]bool{"CREATE": true, "UPDATE": true, "DELETE": false} {
+		got, err := skipOp(session, op)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, op)
+	}
+
+	got, err := skipOp(&gen.Type{Name: "Todo"}, "CREATE")
+	require.NoError(t, err)
+	assert.False(t, got)
+}
+
+func TestEdgeHistoryType(t *testing.T) {
+	user := &gen.Type{Name: "User"}
+	group := &gen.Type{Name: "Group"}
+	userGroupsHistory := &gen.Type{Name: "UserGroupsHistory"}
+
+	groups := &gen.Edge{Name: "groups", Type: group, Owner: user, Rel: gen.Relation{Type: gen.M2M, Table: "user_groups", Columns: []string{"user_id", "group_id"}}}
+	users := &gen.Edge{Name: "users", Type: user, Owner: group, Inverse: "groups", Rel: groups.Rel}
+	owner := &gen.Edge{Name: "owner", Type: user, Owner: group, Rel: gen.Relation{Type: gen.M2O, Table: "groups", Columns: []string{"owner_id"}}}
+	memberships := &gen.Edge{Name: "memberships", Type: group, Owner: user, Through: &gen.Type{Name: "Membership"}, Rel: gen.Relation{Type: gen.M2M, Table: "memberships", Columns: []string{"user_id", "group_id"}}}
+
+	user.Edges = []*gen.Edge{groups, memberships}
+	group.Edges = []*gen.Edge{users, owner}
+
+	nodes := []*gen.Type{user, group, userGroupsHistory}
+
+	assert.Equal(t, "UserGroupsHistory", edgeHistoryName("user_groups"))
+
+	assert.Equal(t, userGroupsHistory, edgeHistoryType(nodes, groups))
+	assert.Equal(t, userGroupsHistory, edgeHistoryType(nodes, users))
+	assert.Nil(t, edgeHistoryType(nodes, owner))
+	assert.Nil(t, edgeHistoryType(nodes, memberships))
+	assert.Nil(t, edgeHistoryType([]*gen.Type{user, group}, groups))
+
+	assert.True(t, isEdgeHistory(nodes, userGroupsHistory))
+	assert.False(t, isEdgeHistory(nodes, user))
+
+	assert.Equal(t, []*gen.Edge{groups}, edgeHistoryEdges(user))
+	assert.Empty(t, edgeHistoryEdges(group))
+	assert.Empty(t, edgeHistoryEdges(nil))
+}
+
+func TestEdgeHistoryColumns(t *testing.T) {
+	history := &gen.Type{
+		Name: "UserGroupsHistory",
+		Fields: []*gen.Field{
+			{Name: "history_time"},
+			{Name: "operation"},
+			{Name: "user_id"},
+			{Name: "group_id"},
+			{Name: "updated_by"},
+			{Name: "tenant_id"},
+		},
+	}
+
+	got := []string{}
+	for _, f := range edgeHistoryColumns(history) {
+		got = append(got, f.Name)
+	}
+
+	assert.Equal(t, []string{"user_id", "group_id"}, got)
+}
+
+func TestEdgeHistoryRefs(t *testing.T) {
+	user := &gen.Type{Name: "User"}
+	group := &gen.Type{Name: "Group"}
+	userHistory := &gen.Type{Name: "UserHistory"}
+	groupHistory := &gen.Type{Name: "GroupHistory"}
+	userGroupsHistory := &gen.Type{Name: "UserGroupsHistory"}
+	userFriendsHistory := &gen.Type{Name: "UserFriendsHistory"}
+
+	groups := &gen.Edge{Name: "groups", Type: group, Owner: user, Rel: gen.Relation{Type: gen.M2M, Table: "user_groups", Columns: []string{"user_id", "group_id"}}}
+	users := &gen.Edge{Name: "users", Type: user, Owner: group, Inverse: "groups", Rel: groups.Rel}
+	friends := &gen.Edge{Name: "friends", Type: user, Owner: user, Rel: gen.Relation{Type: gen.M2M, Table: "user_friends", Columns: []string{"user_id", "friend_id"}}}
+
+	user.Edges = []*gen.Edge{groups, friends}
+	group.Edges = []*gen.Edge{users}
+
+	nodes := []*gen.Type{user, group, userHistory, groupHistory, userGroupsHistory, userFriendsHistory}
+
+	assert.Equal(t, []edgeHistoryRef{
+		{Type: userGroupsHistory, Columns: []string{"user_id"}},
+		{Type: userFriendsHistory, Columns: []string{"user_id", "friend_id"}},
+	}, edgeHistoryRefs(nodes, userHistory))
+	assert.Equal(t, []edgeHistoryRef{
+		{Type: userGroupsHistory, Columns: []string{"group_id"}},
+	}, edgeHistoryRefs(nodes, groupHistory))
+	assert.Empty(t, edgeHistoryRefs(nodes, userGroupsHistory))
+}
+
+func TestTenantTypes(t *testing.T) {
+	tenant := []*gen.Field{{Name: "tenant_id"}}
+
+	user := &gen.Type{Name: "User", Fields: tenant}
+	group := &gen.Type{Name: "Group"}
+	userHistory := &gen.Type{Name: "UserHistory", Fields: tenant}
+	userHistoryLatest := &gen.Type{Name: "UserHistoryLatest", Fields: tenant}
+	groupHistory := &gen.Type{Name: "GroupHistory"}
+	userGroupsHistory := &gen.Type{Name: "UserGroupsHistory", Fields: tenant}
+	auditSummary := &gen.Type{Name: "AuditSummary", Fields: tenant}
+
+	user.Edges = []*gen.Edge{
+		{Name: "groups", Type: group, Owner: user, Rel: gen.Relation{Type: gen.M2M, Table: "user_groups", Columns: []string{"user_id", "group_id"}}},
+	}
+
+	nodes := []*gen.Type{user, group, userHistory, userHistoryLatest, groupHistory, userGroupsHistory, auditSummary}
+
+	// the original schema holding its own tenant_id field is not constrained, nor the history without a tenant_id field
+	assert.Equal(t, []*gen.Type{userHistory, userHistoryLatest, userGroupsHistory, auditSummary}, tenantTypes(nodes))
+	assert.Empty(t, tenantTypes([]*gen.Type{user, group}))
+}
+
+func TestOrderTypes(t *testing.T) {
+	historyTime := []*gen.Field{{Name: "history_time"}}
+
+	user := &gen.Type{Name: "User"}
+	group := &gen.Type{Name: "Group"}
+	userHistory := &gen.Type{Name: "UserHistory", Fields: historyTime}
+	userHistoryLatest := &gen.Type{Name: "UserHistoryLatest", Fields: historyTime}
+	userGroupsHistory := &gen.Type{Name: "UserGroupsHistory", Fields: historyTime}
+	auditSummary := &gen.Type{Name: "AuditSummary"}
+
+	user.Edges = []*gen.Edge{
+		{Name: "groups", Type: group, Owner: user, Rel: gen.Relation{Type: gen.M2M, Table: "user_groups", Columns: []string{"user_id", "group_id"}}},
+	}
+
+	nodes := []*gen.Type{user, group, userHistory, userHistoryLatest, userGroupsHistory, auditSummary}
+
+	// the original schemas and the audit summary are not ordered
+	assert.Equal(t, []*gen.Type{userHistory, userHistoryLatest, userGroupsHistory}, orderTypes(nodes))
+	assert.Empty(t, orderTypes([]*gen.Type{user, group}))
+}
+
+func TestParseEdgeSchemaTemplate(t *testing.T) {
+	info := edgeTemplateInfo{
+		Name:      "UserGroupsHistory",
+		Owner:     "User",
+		Edge:      "groups",
+		SchemaPkg: "schema",
+		TableName: "user_groups_history",
+		Columns: []edgeColumn{
+			{Name: "user_id", IDType: "string"},
+			{Name: "group_id", IDType: "int"},
+		},
+		WithUpdatedBy:      true,
+		UpdatedByValueType: "string",
+		WithHistoryPolicy:  true,
+		AdditionalFields: []additionalFieldInfo{
+			{Name: "region", ValueType: "string"},
+			{Name: "source_id", ValueType: "int64", GoType: "ids.SourceID"},
+		},
+		ValueTypeImports: []goImport{{Path: "example.com/ids"}},
+		Comment:          "History of user_groups table, generated by enthistory",
+	}
+
+	path := filepath.Join(t.TempDir(), "user_groups_history.go")
+
+	err := parseEdgeSchemaTemplate(info, path)
+	require.NoError(t, err)
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		"type UserGroupsHistory struct",
+		`Table: "user_groups_history"`,
+		`field.String("user_id")`,
+		`field.Int("group_id")`,
+		`field.String("updated_by")`,
+		`field.Int64("source_id").GoType(*new(ids.SourceID))`,
+		`"example.com/ids"`,
+		`index.Fields("user_id", "group_id", "history_time")`,
+		"return enthistory.HistoryPolicy()",
+		`field.String("region")`,
+		`schema.Comment("History of user_groups table, generated by enthistory"),`,
+		`Comment("user_id of the groups edge").`,
+	} {
+		assert.Contains(t, string(out), s)
+	}
+}
+
+func TestParseHistoryMetaSchemaTemplate(t *testing.T) {
+	info := historyMetaTemplateInfo{
+		SchemaPkg: "schema",
+		TableName: "history_meta",
+	}
+
+	path := filepath.Join(t.TempDir(), "history_meta.go")
+
+	err := parseHistoryMetaSchemaTemplate(info, path)
+	require.NoError(t, err)
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		"type HistoryMeta struct",
+		`Table: "history_meta"`,
+		"Exclude: true",
+		`field.String("entity")`,
+		`field.Int("schema_version")`,
+		`field.Time("generated_at")`,
+	} {
+		assert.Contains(t, string(out), s)
+	}
+}
+
+func TestParseHistoryAttemptSchemaTemplate(t *testing.T) {
+	info := historyMetaTemplateInfo{
+		SchemaPkg: "schema",
+		TableName: "history_attempt",
+	}
+
+	path := filepath.Join(t.TempDir(), "history_attempt.go")
+
+	err := parseHistoryAttemptSchemaTemplate(info, path)
+	require.NoError(t, err)
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		"type HistoryAttempt struct",
+		`Table: "history_attempt"`,
+		"Exclude: true",
+		`field.String("entity")`,
+		`field.JSON("values", map[string]any{})`,
+		"GoType(enthistory.AttemptReason(\"\"))",
+		`field.Text("error")`,
+	} {
+		assert.Contains(t, string(out), s)
+	}
+}
+
+func TestParseLatestViewSchemaTemplate(t *testing.T) {
+	info := latestViewTemplateInfo{
+		SchemaPkg:   "schema",
+		Name:        "TodoHistoryLatest",
+		HistoryName: "TodoHistory",
+		ViewName:    "todo_history_latest",
+		Query:       latestHistoryQuery("todo_history"),
+		IDType:      "int",
+	}
+
+	path := filepath.Join(t.TempDir(), "todo_history_latest.go")
+
+	err := parseLatestViewSchemaTemplate(info, path)
+	require.NoError(t, err)
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		"type TodoHistoryLatest struct",
+		"ent.View",
+		`Table: "todo_history_latest"`,
+		`entsql.View("SELECT h.* FROM todo_history AS h WHERE NOT EXISTS`,
+		"Exclude: true",
+		`return enthistory.ViewFields(TodoHistory{}.Fields(), field.Int("id"))`,
+	} {
+		assert.Contains(t, string(out), s)
+	}
+}
+
+func TestParseAuditSummarySchemaTemplate(t *testing.T) {
+	info := auditSummaryTemplateInfo{
+		SchemaPkg:  "schema",
+		ViewName:   "audit_summary",
+		SchemaName: "audit",
+	}
+
+	path := filepath.Join(t.TempDir(), "audit_summary.go")
+
+	err := parseAuditSummarySchemaTemplate(info, path)
+	require.NoError(t, err)
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		"type AuditSummary struct",
+		"ent.View",
+		`Table:  "audit_summary"`,
+		`Schema: "audit"`,
+		"Exclude: true",
+		`field.String("actor")`,
+		`field.Int("changes")`,
+	} {
+		assert.Contains(t, string(out), s)
+	}
+}
+
+func TestHistoryAnnotations(t *testing.T) {
+	todoHistory := &gen.Type{
+		Name: "TodoHistory",
+		Annotations: gen.Annotations{
+			annotationName: map[string]any{"isHistory": true, "schemaVersion": 2},
+		},
+	}
+
+	got, err := historyAnnotations(todoHistory)
+	require.NoError(t, err)
+	assert.Equal(t, Annotations{IsHistory: true, SchemaVersion: 2}, got)
+}
+
+func TestCascadeEdges(t *testing.T) {
+	todo := &gen.Type{Name: "Todo"}
+	user := &gen.Type{
+		Name: "User",
+		Edges: []*gen.Edge{
+			{Name: "todos", Type: todo, Rel: gen.Relation{Type: gen.O2M}},
+			{Name: "profile", Type: todo, Rel: gen.Relation{Type: gen.O2O}},
+			{Name: "groups", Type: todo, Rel: gen.Relation{Type: gen.M2M}},
+			{Name: "owner", Type: todo, Inverse: "users", Rel: gen.Relation{Type: gen.M2O}},
+			{Name: "pinned", Type: todo, Rel: gen.Relation{Type: gen.M2O}},
+			{Name: "memberships", Type: todo, Through: &gen.Type{Name: "Membership"}, Rel: gen.Relation{Type: gen.M2M}},
+		},
+	}
+
+	got := []string{}
+	for _, e := range cascadeEdges(user) {
+		got = append(got, e.Name)
+	}
+
+	assert.Equal(t, []string{"todos", "profile"}, got)
+}
+
+func TestZeroValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		field *gen.Field
+		want  string
+	}{
+		{
+			name:  "string",
+			field: &gen.Field{Name: "owner_id", Type: &field.TypeInfo{Type: field.TypeString}},
+			want:  `""`,
+		},
+		{
+			name:  "enum",
+			field: &gen.Field{Name: "status", Type: &field.TypeInfo{Type: field.TypeEnum}},
+			want:  `""`,
+		},
+		{
+			name:  "int",
+			field: &gen.Field{Name: "owner_id", Type: &field.TypeInfo{Type: field.TypeInt}},
+			want:  "0",
+		},
+		{
+			name:  "bool",
+			field: &gen.Field{Name: "done", Type: &field.TypeInfo{Type: field.TypeBool}},
+			want:  "false",
+		},
+		{
+			name:  "uuid",
+			field: &gen.Field{Name: "owner_id", Type: &field.TypeInfo{Type: field.TypeUUID, Ident: "uuid.UUID", PkgPath: "github.com/google/uuid"}},
+			want:  "uuid.UUID{}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := zeroValue(tt.field)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSampleValue(t *testing.T) {
+	todo := &gen.Type{
+		Name:   "Todo",
+		Config: &gen.Config{},
+	}
+
+	tests := []struct {
+		name    string
+		field   *gen.Field
+		want    string
+		updated string
+	}{
+		{
+			name:    "string",
+			field:   &gen.Field{Name: "name", Type: &field.TypeInfo{Type: field.TypeString}},
+			want:    `"sample"`,
+			updated: `"sample updated"`,
+		},
+		{
+			name:    "enum",
+			field:   &gen.Field{Name: "status", Type: &field.TypeInfo{Type: field.TypeEnum}, Enums: []gen.Enum{{Name: "StatusOpen", Value: "open"}, {Name: "StatusDone", Value: "done"}}},
+			want:    "todo.StatusOpen",
+			updated: "todo.StatusDone",
+		},
+		{
+			name:    "int",
+			field:   &gen.Field{Name: "priority", Type: &field.TypeInfo{Type: field.TypeInt}},
+			want:    "1",
+			updated: "2",
+		},
+		{
+			name:    "bool",
+			field:   &gen.Field{Name: "done", Type: &field.TypeInfo{Type: field.TypeBool}},
+			want:    "true",
+			updated: "false",
+		},
+		{
+			name:    "time",
+			field:   &gen.Field{Name: "due_at", Type: &field.TypeInfo{Type: field.TypeTime}},
+			want:    "time.Now()",
+			updated: "time.Now().Add(time.Hour)",
+		},
+		{
+			name:    "bytes",
+			field:   &gen.Field{Name: "attachment", Type: &field.TypeInfo{Type: field.TypeBytes}},
+			want:    `[]byte("sample")`,
+			updated: `[]byte("sample updated")`,
+		},
+		{
+			name:  "json",
+			field: &gen.Field{Name: "tags", Type: &field.TypeInfo{Type: field.TypeJSON, Ident: "[]string"}},
+			want:  "*new([]string)",
+		},
+		{
+			name:  "uuid",
+			field: &gen.Field{Name: "owner_id", Type: &field.TypeInfo{Type: field.TypeUUID, Ident: "uuid.UUID", PkgPath: "github.com/google/uuid", RType: &field.RType{Name: "UUID"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sampleValue(tt.field, todo, false))
+			assert.Equal(t, tt.updated, sampleValue(tt.field, todo, true))
+		})
+	}
+}
+
+func TestSampleCreate(t *testing.T) {
+	name := &gen.Field{Name: "name", Type: &field.TypeInfo{Type: field.TypeString}}
+	owner := &gen.Field{Name: "owner_id", Type: &field.TypeInfo{Type: field.TypeUUID, Ident: "uuid.UUID", PkgPath: "github.com/google/uuid", RType: &field.RType{Name: "UUID"}}}
+
+	assert.True(t, sampleCreate(&gen.Type{Name: "Todo", Fields: []*gen.Field{name}}))
+	assert.False(t, sampleCreate(&gen.Type{Name: "Todo", Fields: []*gen.Field{name, owner}}))
+	assert.False(t, sampleCreate(&gen.Type{Name: "Todo", Fields: []*gen.Field{name}, Edges: []*gen.Edge{{Name: "owner"}}}))
+
+	owner.Optional = true
+
+	assert.True(t, sampleCreate(&gen.Type{Name: "Todo", Fields: []*gen.Field{name, owner}, Edges: []*gen.Edge{{Name: "owner", Optional: true}}}))
+}
+
+func TestSensitiveFields(t *testing.T) {
+	n, err := gen.NewType(&gen.Config{}, &load.Schema{
+		Name: "User",
+		Fields: []*load.Field{
+			{Name: "name", Info: &field.TypeInfo{Type: field.TypeString}},
+			{Name: "password", Info: &field.TypeInfo{Type: field.TypeString}, Sensitive: true},
+			{
+				Name:        "ssn",
+				Info:        &field.TypeInfo{Type: field.TypeString},
+				Annotations: map[string]any{fieldAnnotationName: map[string]any{"redact": true}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`"password"`, `"ssn"`}, sensitiveFields(n))
+	assert.Empty(t, sensitiveFields(&gen.Type{Name: "Todo"}))
+}
+
+func TestMaskedField(t *testing.T) {
+	n, err := gen.NewType(&gen.Config{}, &load.Schema{
+		Name: "User",
+		Fields: []*load.Field{
+			{Name: "name", Info: &field.TypeInfo{Type: field.TypeString}},
+			{Name: "password", Info: &field.TypeInfo{Type: field.TypeString}, Sensitive: true},
+			{
+				Name:        "ssn",
+				Info:        &field.TypeInfo{Type: field.TypeString},
+				Annotations: map[string]any{fieldAnnotationName: map[string]any{"redact": true}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.False(t, maskedField(n, "name"))
+	assert.True(t, maskedField(n, "password"))
+	assert.True(t, maskedField(n, "ssn"))
+	assert.False(t, maskedField(n, "missing"))
+}
+
+func TestSampleUpdateField(t *testing.T) {
+	name := &gen.Field{Name: "name", Type: &field.TypeInfo{Type: field.TypeString}, Immutable: true}
+	lastSeen := &gen.Field{Name: "last_seen_at", Type: &field.TypeInfo{Type: field.TypeTime}}
+	deleted := &gen.Field{Name: "deleted_at", Type: &field.TypeInfo{Type: field.TypeTime}}
+	priority := &gen.Field{Name: "priority", Type: &field.TypeInfo{Type: field.TypeInt}}
+
+	todo := &gen.Type{
+		Name:   "Todo",
+		Fields: []*gen.Field{name, lastSeen, deleted, priority},
+		Annotations: gen.Annotations{
+			annotationName: map[string]any{"ignoredUpdateFields": []string{"last_seen_at"}},
+		},
+	}
+
+	got, err := sampleUpdateField(todo, "deleted_at")
+	require.NoError(t, err)
+	assert.Equal(t, priority, got)
+
+	got, err = sampleUpdateField(&gen.Type{Name: "Todo", Fields: []*gen.Field{name}}, "deleted_at")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
@@ -0,0 +1,21 @@
+package enthistory
+
+import (
+	"context"
+)
+
+// TenantFromContext returns the tenant stored on the context using the key, which the generated tenant interceptors
+// and hooks use to constrain the queries and deletes of the history rows; the query or mutation using the system token
+// is not constrained so false is returned, and ErrTenantRequired is returned when there is no tenant on the context
+func TenantFromContext(ctx context.Context, v any, key string) (string, bool, error) {
+	if HasToken(ctx, v, TokenSystem) {
+		return "", false, nil
+	}
+
+	tenantID, ok := ctx.Value(key).(string)
+	if !ok || tenantID == "" {
+		return "", false, ErrTenantRequired
+	}
+
+	return tenantID, true, nil
+}
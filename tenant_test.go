@@ -0,0 +1,64 @@
+package enthistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantFromContext(t *testing.T) {
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		wantTenant      string
+		wantConstrained bool
+		wantErr         error
+	}{
+		{
+			name:            "tenant on context",
+			ctx:             context.WithValue(context.Background(), "organizationID", "org1"), //nolint:staticcheck
+			wantTenant:      "org1",
+			wantConstrained: true,
+		},
+		{
+			name:    "no tenant on context",
+			ctx:     context.Background(),
+			wantErr: ErrTenantRequired,
+		},
+		{
+			name:    "empty tenant on context",
+			ctx:     context.WithValue(context.Background(), "organizationID", ""), //nolint:staticcheck
+			wantErr: ErrTenantRequired,
+		},
+		{
+			name:    "tenant of another type on context",
+			ctx:     context.WithValue(context.Background(), "organizationID", 1), //nolint:staticcheck
+			wantErr: ErrTenantRequired,
+		},
+		{
+			name: "system token",
+			ctx:  withTestTokens(context.Background(), TokenSystem),
+		},
+		{
+			name: "system token with tenant",
+			ctx:  withTestTokens(context.WithValue(context.Background(), "organizationID", "org1"), TokenSystem), //nolint:staticcheck
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenantID, constrained, err := TenantFromContext(tt.ctx, &testQuery{}, "organizationID")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.False(t, constrained)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantConstrained, constrained)
+			assert.Equal(t, tt.wantTenant, tenantID)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+)
+
+type Todo struct {
+	ent.Schema
+}
+
+func (Todo) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("item"),
+		field.Enum("status").
+			Values("open", "done").
+			Default("open"),
+		field.Int("priority").
+			Optional(),
+		field.Time("due_date").
+			Optional().
+			Nillable(),
+	}
+}
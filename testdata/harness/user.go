@@ -0,0 +1,25 @@
+package schema
+
+import (
+	"strconv"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+)
+
+type User struct {
+	ent.Schema
+}
+
+func (User) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("id").
+			DefaultFunc(func() string {
+				return strconv.FormatInt(time.Now().UnixNano(), 36)
+			}),
+		field.String("name"),
+		field.String("nickname").
+			Unique(),
+	}
+}
@@ -0,0 +1,232 @@
+package enthistory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/entc/load"
+)
+
+const (
+	// historyTriggersName is the name of the files the DDL of the history triggers is written to
+	historyTriggersName = "history_triggers"
+	// historyTriggerSuffix is the suffix of the triggers, and trigger functions, named after the history tables
+	historyTriggerSuffix = "_capture"
+)
+
+// historyTriggerDialects are the dialects the DDL of the history triggers is written for, SQLite does not have
+// database users to tell the writes of the application apart from the out-of-band writes
+var historyTriggerDialects = []string{dialect.Postgres, dialect.MySQL}
+
+// HistoryTrigger is a table of a tracked schema whose out-of-band writes are captured by a trigger
+type HistoryTrigger struct {
+	// Table is the name of the table of the tracked schema
+	Table string
+	// HistoryTable is the name of the history table
+	HistoryTable string
+	// HistorySchema is the database schema of the history table, if any
+	HistorySchema string
+	// ID is the id column of the table, recorded as the ref
+	ID string
+	// Columns are the columns of the table copied to the history table
+	Columns []string
+	// Redacted are the columns stored as RedactedValue on the history table
+	Redacted []string
+	// Ops are the operations captured by the trigger (CREATE, UPDATE, or DELETE)
+	Ops []string
+}
+
+// values returns the values inserted into the history table from the row (NEW or OLD) at the time (now), the
+// columns of the row are quoted for the dialect
+func (t HistoryTrigger) values(d, row, now string) string {
+	values := []string{now, row + "." + quoteIdent(d, t.ID), fmt.Sprintf("'%s'", OpTypeExternal)}
+
+	for _, c := range t.Columns {
+		if slices.Contains(t.Redacted, c) {
+			values = append(values, fmt.Sprintf("'%s'", RedactedValue))
+			continue
+		}
+
+		values = append(values, row+"."+quoteIdent(d, c))
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// insert returns the INSERT INTO clause of the history table, listing the history columns quoted for the dialect;
+// the history table is prefixed with its schema, if any
+func (t HistoryTrigger) insert(d string) string {
+	historyTable := quoteIdent(d, t.HistoryTable)
+	if t.HistorySchema != "" {
+		historyTable = quoteIdent(d, t.HistorySchema) + "." + historyTable
+	}
+
+	columns := append([]string{"history_time", "ref", "operation"}, t.Columns...)
+	for i, c := range columns {
+		columns[i] = quoteIdent(d, c)
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s)", historyTable, strings.Join(columns, ", "))
+}
+
+// quoteIdent returns the identifier quoted for the dialect, using backticks on MySQL and double quotes on Postgres
+func quoteIdent(d, ident string) string {
+	if d == dialect.MySQL {
+		return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+	}
+
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+// historyTriggerRow returns the row of the trigger of the operation, the deleted rows are recorded using their values
+// before the delete
+func historyTriggerRow(op string) string {
+	if op == "DELETE" {
+		return "OLD"
+	}
+
+	return "NEW"
+}
+
+// historyTriggerEvent returns the trigger event of the operation
+func historyTriggerEvent(op string) string {
+	if op == "CREATE" {
+		return "INSERT"
+	}
+
+	return op
+}
+
+// historyTriggersDDL returns the DDL creating the triggers capturing the writes to the tables of the tracked schemas
+// made by database users other than the application user, into the history tables with the EXTERNAL operation; all
+// writes are captured when the application user is empty
+func historyTriggersDDL(d, appUser string, triggers []HistoryTrigger) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- Code generated by enthistory, the triggers capturing the out-of-band writes (%s)\n", d)
+
+	for _, t := range triggers {
+		name := quoteIdent(d, t.HistoryTable+historyTriggerSuffix)
+
+		if d == dialect.Postgres {
+			fmt.Fprintf(&b, "CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$\nBEGIN\n", name)
+
+			if appUser != "" {
+				fmt.Fprintf(&b, "  IF session_user = '%s' THEN\n    RETURN NULL;\n  END IF;\n", appUser)
+			}
+
+			b.WriteString("  IF TG_OP = 'DELETE' THEN\n")
+			fmt.Fprintf(&b, "    %s VALUES (%s);\n", t.insert(d), t.values(d, "OLD", "now()"))
+			b.WriteString("  ELSE\n")
+			fmt.Fprintf(&b, "    %s VALUES (%s);\n", t.insert(d), t.values(d, "NEW", "now()"))
+			b.WriteString("  END IF;\n  RETURN NULL;\nEND;\n$$ LANGUAGE plpgsql;\n")
+
+			events := make([]string, len(t.Ops))
+			for i, op := range t.Ops {
+				events[i] = historyTriggerEvent(op)
+			}
+
+			fmt.Fprintf(&b, "CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW EXECUTE FUNCTION %s();\n",
+				name, strings.Join(events, " OR "), quoteIdent(d, t.Table), name)
+
+			continue
+		}
+
+		// MySQL triggers fire for a single event, the single statement body does not need a custom delimiter
+		for _, op := range t.Ops {
+			where := ""
+			if appUser != "" {
+				where = fmt.Sprintf(" WHERE SUBSTRING_INDEX(USER(), '@', 1) <> '%s'", appUser)
+			}
+
+			fmt.Fprintf(&b, "CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW %s SELECT %s FROM DUAL%s;\n",
+				quoteIdent(d, t.HistoryTable+historyTriggerSuffix+"_"+strings.ToLower(historyTriggerEvent(op))),
+				historyTriggerEvent(op), quoteIdent(d, t.Table), t.insert(d), t.values(d, historyTriggerRow(op), "NOW(6)"),
+				where)
+		}
+	}
+
+	return b.String()
+}
+
+// writeHistoryTriggers writes the DDL of the history triggers for each of the dialects supporting them to the
+// directory, the files are named after the dialect (e.g. history_triggers.postgres.sql)
+func writeHistoryTriggers(dir, appUser string, triggers []HistoryTrigger, dialects []string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, d := range dialects {
+		if !slices.Contains(historyTriggerDialects, d) {
+			continue
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s.sql", historyTriggersName, d))
+
+		if err := os.WriteFile(path, []byte(historyTriggersDDL(d, appUser, triggers)), 0o600); err != nil { //nolint:mnd
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getHistoryTriggers returns the tables of the tracked schemas captured by the history triggers; schemas without an
+// integer id, using natural key refs, or with compressed fields are not captured, since the triggers can neither
+// number the history rows nor compute the ref or the compressed values of those history tables
+func getHistoryTriggers(config *Config, schemas []*load.Schema, nodes map[string]*gen.Type) ([]HistoryTrigger, error) {
+	var triggers []HistoryTrigger
+
+	for _, schema := range schemas {
+		n, ok := nodes[schema.Name]
+		if !shouldGenerate(schema, config.OptIn) || !ok || !n.HasOneFieldID() || !n.ID.Type.Type.Integer() {
+			continue
+		}
+
+		annotations, err := jsonUnmarshalAnnotations(schema.Annotations[annotationName])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(annotations.RefFields) > 0 || len(annotations.CompressedFields) > 0 {
+			continue
+		}
+
+		redacted, err := getRedactedFields(schema)
+		if err != nil {
+			return nil, err
+		}
+
+		t := HistoryTrigger{
+			Table:         n.Table(),
+			HistoryTable:  getHistoryTableName(schema),
+			HistorySchema: getHistorySchemaName(schema.Annotations, config),
+			ID:            n.ID.StorageKey(),
+		}
+
+		for _, f := range n.Fields {
+			t.Columns = append(t.Columns, f.StorageKey())
+
+			if slices.Contains(redacted, f.Name) {
+				t.Redacted = append(t.Redacted, f.StorageKey())
+			}
+		}
+
+		for _, op := range skipOps {
+			if !slices.Contains(annotations.SkipOps, op) {
+				t.Ops = append(t.Ops, op)
+			}
+		}
+
+		if len(t.Ops) > 0 {
+			triggers = append(triggers, t)
+		}
+	}
+
+	return triggers, nil
+}
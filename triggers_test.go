@@ -0,0 +1,117 @@
+package enthistory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/entc"
+	"entgo.io/ent/entc/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryTriggersDDL(t *testing.T) {
+	triggers := []HistoryTrigger{
+		{
+			Table:         "users",
+			HistoryTable:  "user_history",
+			HistorySchema: "audit",
+			ID:            "id",
+			Columns:       []string{"name", "ssn"},
+			Redacted:      []string{"ssn"},
+			Ops:           []string{"CREATE", "UPDATE", "DELETE"},
+		},
+		{
+			Table:        "lists",
+			HistoryTable: "list_history",
+			ID:           "id",
+			Columns:      []string{"item"},
+			Ops:          []string{"UPDATE"},
+		},
+	}
+
+	ddl := historyTriggersDDL(dialect.Postgres, "app", triggers)
+	assert.Contains(t, ddl, `CREATE OR REPLACE FUNCTION "user_history_capture"() RETURNS trigger AS $$`)
+	assert.Contains(t, ddl, "IF session_user = 'app' THEN\n    RETURN NULL;")
+	assert.Contains(t, ddl, `INSERT INTO "audit"."user_history" ("history_time", "ref", "operation", "name", "ssn") `+
+		`VALUES (now(), OLD."id", 'EXTERNAL', OLD."name", '[REDACTED]');`)
+	assert.Contains(t, ddl, `VALUES (now(), NEW."id", 'EXTERNAL', NEW."name", '[REDACTED]');`)
+	assert.Contains(t, ddl, `CREATE TRIGGER "user_history_capture" AFTER INSERT OR UPDATE OR DELETE ON "users" `+
+		`FOR EACH ROW EXECUTE FUNCTION "user_history_capture"();`)
+	assert.Contains(t, ddl, `CREATE TRIGGER "list_history_capture" AFTER UPDATE ON "lists"`)
+
+	ddl = historyTriggersDDL(dialect.MySQL, "app", triggers)
+	assert.Contains(t, ddl, "CREATE TRIGGER `user_history_capture_insert` AFTER INSERT ON `users` FOR EACH ROW "+
+		"INSERT INTO `audit`.`user_history` (`history_time`, `ref`, `operation`, `name`, `ssn`) "+
+		"SELECT NOW(6), NEW.`id`, 'EXTERNAL', NEW.`name`, '[REDACTED]' FROM DUAL "+
+		"WHERE SUBSTRING_INDEX(USER(), '@', 1) <> 'app';")
+	assert.Contains(t, ddl, "CREATE TRIGGER `user_history_capture_delete` AFTER DELETE ON `users` FOR EACH ROW "+
+		"INSERT INTO `audit`.`user_history` (`history_time`, `ref`, `operation`, `name`, `ssn`) "+
+		"SELECT NOW(6), OLD.`id`, 'EXTERNAL', OLD.`name`, '[REDACTED]' FROM DUAL")
+	assert.Contains(t, ddl, "CREATE TRIGGER `list_history_capture_update` AFTER UPDATE ON `lists`")
+	assert.NotContains(t, ddl, "list_history_capture_insert")
+
+	// all writes are captured without an application user
+	ddl = historyTriggersDDL(dialect.MySQL, "", triggers)
+	assert.NotContains(t, ddl, "WHERE")
+
+	ddl = historyTriggersDDL(dialect.Postgres, "", triggers)
+	assert.NotContains(t, ddl, "session_user")
+
+	// reserved words are quoted
+	ddl = historyTriggersDDL(dialect.Postgres, "", []HistoryTrigger{
+		{Table: "order", HistoryTable: "order_history", ID: "id", Columns: []string{"user"}, Ops: []string{"CREATE"}},
+	})
+	assert.Contains(t, ddl, `INSERT INTO "order_history" ("history_time", "ref", "operation", "user") `+
+		`VALUES (now(), NEW."id", 'EXTERNAL', NEW."user");`)
+	assert.Contains(t, ddl, `AFTER INSERT ON "order" FOR EACH ROW`)
+}
+
+func TestGetHistoryTriggers(t *testing.T) {
+	graph, err := entc.LoadGraph("./testdata/schema", &gen.Config{})
+	require.NoError(t, err)
+
+	nodes := make(map[string]*gen.Type, len(graph.Nodes))
+	for _, n := range graph.Nodes {
+		nodes[n.Name] = n
+	}
+
+	triggers, err := getHistoryTriggers(&Config{SchemaName: "audit"}, graph.Schemas, nodes)
+	require.NoError(t, err)
+
+	assert.Contains(t, triggers, HistoryTrigger{
+		Table:         "users",
+		HistoryTable:  "user_history",
+		HistorySchema: "audit",
+		ID:            "id",
+		Columns:       []string{"age", "name", "nickname"},
+		Ops:           []string{"CREATE", "UPDATE", "DELETE"},
+	})
+
+	for _, trigger := range triggers {
+		// excluded schemas and history schemas are not captured
+		assert.NotEqual(t, "todos", trigger.Table)
+		assert.NotEqual(t, "user_histories", trigger.Table)
+	}
+}
+
+func TestGenerateSchemasTriggers(t *testing.T) {
+	dir := copyTestSchemas(t)
+	triggersDir := filepath.Join(filepath.Dir(dir), "triggers")
+
+	require.NoError(t, New(WithSchemaPath(dir), WithTriggers(triggersDir, "app")).GenerateSchemas())
+
+	ddl, err := os.ReadFile(filepath.Join(triggersDir, "history_triggers.postgres.sql"))
+	require.NoError(t, err)
+	assert.Contains(t, string(ddl), `CREATE TRIGGER "list_history_capture"`)
+
+	assert.FileExists(t, filepath.Join(triggersDir, "history_triggers.mysql.sql"))
+	assert.NoFileExists(t, filepath.Join(triggersDir, "history_triggers.sqlite3.sql"))
+
+	// the EXTERNAL operation is accepted by the history schemas
+	schema, err := os.ReadFile(filepath.Join(dir, "list_history.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(schema), `"EXTERNAL"`)
+}
@@ -1,7 +1,9 @@
 package enthistory
 
 import (
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 
 	"entgo.io/ent/schema/field"
@@ -90,6 +92,127 @@ func getHistoryAnnotations(schema *load.Schema) Annotations {
 	return annotations
 }
 
+// getMirroredIndexes returns the fields of the indexes on the original schema that should be added to
+// the history schema based on the history annotation; indexes that include edges are not supported because
+// the history schema does not include edges
+func getMirroredIndexes(schema *load.Schema) ([][]string, error) {
+	annotations, err := jsonUnmarshalAnnotations(schema.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes [][]string
+
+	for _, fields := range annotations.Indexes {
+		found := false
+
+		for _, idx := range schema.Indexes {
+			if len(idx.Edges) == 0 && slices.Equal(idx.Fields, fields) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: %s index on %s", ErrIndexNotFound, strings.Join(fields, ", "), schema.Name)
+		}
+
+		indexes = append(indexes, fields)
+	}
+
+	return indexes, nil
+}
+
+// limitStrategies are the names of the LimitStrategy constants by strategy
+var limitStrategies = map[LimitStrategy]string{
+	LimitTruncate: "LimitTruncate",
+	LimitHash:     "LimitHash",
+	LimitExternal: "LimitExternal",
+}
+
+// getFieldLimits returns the size limits of the fields copied to the history schema based on the history
+// annotation; only string and bytes fields can be limited, and truncated values must fit the TruncatedMarker
+func getFieldLimits(schema *load.Schema) ([]fieldLimitInfo, error) {
+	annotations, err := jsonUnmarshalAnnotations(schema.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	var limits []fieldLimitInfo
+
+	for _, limit := range annotations.FieldLimits {
+		idx := slices.IndexFunc(schema.Fields, func(f *load.Field) bool {
+			return f.Name == limit.Field
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %s on %s", ErrFieldNotFound, limit.Field, schema.Name)
+		}
+
+		if t := schema.Fields[idx].Info.Type; t != field.TypeString && t != field.TypeBytes {
+			return nil, fmt.Errorf("%w: %s field %s on %s cannot be limited", ErrUnsupportedType, t, limit.Field, schema.Name)
+		}
+
+		if limit.Strategy == "" {
+			limit.Strategy = LimitTruncate
+		}
+
+		strategy, ok := limitStrategies[limit.Strategy]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown strategy %s for %s on %s", ErrInvalidFieldLimit, limit.Strategy, limit.Field, schema.Name)
+		}
+
+		if limit.MaxSize <= 0 || (limit.Strategy == LimitTruncate && limit.MaxSize <= len(TruncatedMarker)) {
+			return nil, fmt.Errorf("%w: max size %d for %s on %s", ErrInvalidFieldLimit, limit.MaxSize, limit.Field, schema.Name)
+		}
+
+		limits = append(limits, fieldLimitInfo{
+			Field:    limit.Field,
+			MaxSize:  limit.MaxSize,
+			Strategy: strategy,
+		})
+	}
+
+	return limits, nil
+}
+
+// getCompressedFields returns the fields stored compressed on the history schema by the name of the compression
+// based on the history annotation; only string and bytes fields without a custom Go type or ValueScanner can be
+// compressed, and nillable fields are not supported by the ent ValueScanner
+func getCompressedFields(schema *load.Schema) (map[string]string, error) {
+	annotations, err := jsonUnmarshalAnnotations(schema.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(annotations.CompressedFields) == 0 {
+		return nil, nil
+	}
+
+	compressed := make(map[string]string, len(annotations.CompressedFields))
+
+	for name, compression := range annotations.CompressedFields {
+		idx := slices.IndexFunc(schema.Fields, func(f *load.Field) bool {
+			return f.Name == name
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %s on %s", ErrFieldNotFound, name, schema.Name)
+		}
+
+		f := schema.Fields[idx]
+		if t := f.Info.Type; (t != field.TypeString && t != field.TypeBytes) || f.Info.RType != nil || f.ValueScanner || f.Nillable {
+			return nil, fmt.Errorf("%w: %s field %s on %s cannot be compressed", ErrUnsupportedType, t, name, schema.Name)
+		}
+
+		if compression == "" {
+			compression = CompressionGzip
+		}
+
+		compressed[name] = string(compression)
+	}
+
+	return compressed, nil
+}
+
 // getSchemaTableName from the entSQL annotation
 func getSchemaTableName(schema *load.Schema) string {
 	if entSQLMap, ok := schema.Annotations["EntSQL"].(map[string]any); ok {
@@ -101,6 +224,160 @@ func getSchemaTableName(schema *load.Schema) string {
 	return toSnakeCase(schema.Name)
 }
 
+// getRedactedFields returns the names of the fields of the schema annotated using Redact, which must be string or bytes
+// fields
+func getRedactedFields(schema *load.Schema) ([]string, error) {
+	var fields []string
+
+	for _, f := range schema.Fields {
+		ant, ok := f.Annotations[fieldAnnotationName].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if redact, _ := ant["redact"].(bool); !redact {
+			continue
+		}
+
+		if t := f.Info.Type; t != field.TypeString && t != field.TypeBytes {
+			return nil, fmt.Errorf("%w: %s field %s on %s cannot be redacted", ErrUnsupportedType, t, f.Name, schema.Name)
+		}
+
+		fields = append(fields, f.Name)
+	}
+
+	return fields, nil
+}
+
+// getRestrictedFields returns the names of the fields of the schema annotated using Restrict
+func getRestrictedFields(schema *load.Schema) []string {
+	var fields []string
+
+	for _, f := range schema.Fields {
+		ant, ok := f.Annotations[fieldAnnotationName].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if restrict, _ := ant["restrict"].(bool); restrict {
+			fields = append(fields, f.Name)
+		}
+	}
+
+	return fields
+}
+
+// getRefFields returns the fields recorded as the ref of the history rows instead of the id, set using the RefFields
+// of the history annotation; the fields cannot be json, nillable, sensitive, or redacted, and cannot be used by edge schemas
+// with a composite id or by sampled schemas, which correlate the history rows using the ids
+func getRefFields(schema *load.Schema) ([]string, error) {
+	annotations, err := jsonUnmarshalAnnotations(schema.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(annotations.RefFields) == 0 {
+		return nil, nil
+	}
+
+	if len(getCompositeID(schema)) > 0 {
+		return nil, fmt.Errorf("%w: %s has a composite id", ErrInvalidRefFields, schema.Name)
+	}
+
+	if annotations.SampleInterval != 0 {
+		return nil, fmt.Errorf("%w: %s is sampled", ErrInvalidRefFields, schema.Name)
+	}
+
+	for _, name := range annotations.RefFields {
+		idx := slices.IndexFunc(schema.Fields, func(f *load.Field) bool {
+			return f.Name == name
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %s on %s", ErrFieldNotFound, name, schema.Name)
+		}
+
+		f := schema.Fields[idx]
+		ant, _ := f.Annotations[fieldAnnotationName].(map[string]any)
+		redact, _ := ant["redact"].(bool)
+
+		switch {
+		case f.Info.Type == field.TypeJSON:
+			return nil, fmt.Errorf("%w: %s field %s on %s cannot be a ref field", ErrUnsupportedType, f.Info.Type, name, schema.Name)
+		case f.Nillable:
+			return nil, fmt.Errorf("%w: %s on %s is nillable", ErrInvalidRefFields, name, schema.Name)
+		case f.Sensitive || redact:
+			return nil, fmt.Errorf("%w: %s on %s is sensitive", ErrInvalidRefFields, name, schema.Name)
+		}
+	}
+
+	return annotations.RefFields, nil
+}
+
+// getSkipOps returns the operations of the schema that do not create history, set using the SkipOps of the history
+// annotation
+func getSkipOps(schema *load.Schema) ([]string, error) {
+	annotations, err := jsonUnmarshalAnnotations(schema.Annotations[annotationName])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range annotations.SkipOps {
+		if !slices.Contains(skipOps, op) {
+			return nil, fmt.Errorf("%w: %s on %s", ErrInvalidSkipOp, op, schema.Name)
+		}
+	}
+
+	return annotations.SkipOps, nil
+}
+
+// getHistoryTableName returns the name of the history table of the schema, set using the TableName of the history
+// annotation, or the table of the schema with the history suffix
+func getHistoryTableName(schema *load.Schema) string {
+	if historyAnnotations, ok := schema.Annotations[annotationName].(map[string]any); ok {
+		if table, ok := historyAnnotations["tableName"].(string); ok && table != "" {
+			return table
+		}
+	}
+
+	return getSchemaTableName(schema) + historyTableSuffix
+}
+
+// getHistorySchemaName returns the database schema of the history table of the schema with the annotations, set using
+// the SchemaName of the history annotation, or the schema name set by WithSchemaName
+func getHistorySchemaName(annotations map[string]any, config *Config) string {
+	if historyAnnotations, ok := annotations[annotationName].(map[string]any); ok {
+		if name, ok := historyAnnotations["schemaName"].(string); ok && name != "" {
+			return name
+		}
+	}
+
+	return config.SchemaName
+}
+
+// getCompositeID returns the fields of the composite id set using the field.ID annotation, this is
+// used by edge schemas (e.g. the Through schema of an edge), which do not have an id field
+func getCompositeID(schema *load.Schema) []string {
+	fieldsAnnotation, ok := schema.Annotations["Fields"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	ids, ok := fieldsAnnotation["ID"].([]any)
+	if !ok {
+		return nil
+	}
+
+	compositeID := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		if name, ok := id.(string); ok {
+			compositeID = append(compositeID, name)
+		}
+	}
+
+	return compositeID
+}
+
 // getPkgFromSchemaPath returns the package from the schema path
 func getPkgFromSchemaPath(schemaPath string) (string, error) {
 	parts := strings.Split(schemaPath, "/")
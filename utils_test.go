@@ -2,6 +2,7 @@ package enthistory
 
 import (
 	"testing"
+	"time"
 
 	"entgo.io/ent/entc/load"
 	"entgo.io/ent/schema/field"
@@ -244,6 +245,58 @@ func TestGetSchemaTableName(t *testing.T) {
 	}
 }
 
+func TestGetHistoryTableName(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *load.Schema
+		want   string
+	}{
+		{
+			name: "table name annotation",
+			schema: &load.Schema{
+				Name: "User",
+				Annotations: map[string]any{
+					"EntSQL":  map[string]any{"table": "accounts"},
+					"History": map[string]any{"tableName": "audit_users"},
+				},
+			},
+			want: "audit_users",
+		},
+		{
+			name: "entsql table",
+			schema: &load.Schema{
+				Name: "User",
+				Annotations: map[string]any{
+					"EntSQL":  map[string]any{"table": "accounts"},
+					"History": map[string]any{"tableName": ""},
+				},
+			},
+			want: "accounts_history",
+		},
+		{
+			name: "not set, should use schema name",
+			schema: &load.Schema{
+				Name: "User",
+			},
+			want: "user_history",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getHistoryTableName(tt.schema))
+		})
+	}
+}
+
+func TestGetHistorySchemaName(t *testing.T) {
+	config := &Config{SchemaName: "history"}
+
+	assert.Equal(t, "audit", getHistorySchemaName(map[string]any{"History": map[string]any{"schemaName": "audit"}}, config))
+	assert.Equal(t, "history", getHistorySchemaName(map[string]any{"History": map[string]any{"exclude": false}}, config))
+	assert.Equal(t, "history", getHistorySchemaName(nil, config))
+	assert.Empty(t, getHistorySchemaName(nil, &Config{}))
+}
+
 func TestGetPkgFromSchemaPath(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -337,3 +390,440 @@ func TestGetIDType(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCompositeID(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *load.Schema
+		want   []string
+	}{
+		{
+			name:   "no annotation",
+			schema: &load.Schema{Name: "User"},
+			want:   nil,
+		},
+		{
+			name: "field annotation without id",
+			schema: &load.Schema{
+				Name: "User",
+				Annotations: map[string]any{
+					"Fields": map[string]any{
+						"StructTag": map[string]any{"name": `json:"name"`},
+					},
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "composite id",
+			schema: &load.Schema{
+				Name: "Membership",
+				Annotations: map[string]any{
+					"Fields": map[string]any{
+						"ID": []any{"user_id", "group_id"},
+					},
+				},
+			},
+			want: []string{"user_id", "group_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getCompositeID(tt.schema)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetMirroredIndexes(t *testing.T) {
+	indexes := []*load.Index{
+		{
+			Fields: []string{"tenant_id"},
+		},
+		{
+			Fields: []string{"age", "name"},
+			Unique: true,
+		},
+		{
+			Fields: []string{"name"},
+			Edges:  []string{"owner"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		schema  *load.Schema
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name: "no annotation",
+			schema: &load.Schema{
+				Name:    "User",
+				Indexes: indexes,
+			},
+			want: nil,
+		},
+		{
+			name: "mirror indexes",
+			schema: &load.Schema{
+				Name:    "User",
+				Indexes: indexes,
+				Annotations: map[string]any{
+					"History": map[string]any{
+						"indexes": []any{[]any{"tenant_id"}, []any{"age", "name"}},
+					},
+				},
+			},
+			want: [][]string{{"tenant_id"}, {"age", "name"}},
+		},
+		{
+			name: "index does not exist",
+			schema: &load.Schema{
+				Name:    "User",
+				Indexes: indexes,
+				Annotations: map[string]any{
+					"History": map[string]any{
+						"indexes": []any{[]any{"nickname"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "edge index is not mirrored",
+			schema: &load.Schema{
+				Name:    "User",
+				Indexes: indexes,
+				Annotations: map[string]any{
+					"History": map[string]any{
+						"indexes": []any{[]any{"name"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getMirroredIndexes(tt.schema)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrIndexNotFound)
+				assert.Empty(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetSkipOps(t *testing.T) {
+	schema := func(ops ...any) *load.Schema {
+		return &load.Schema{
+			Name:        "Session",
+			Annotations: map[string]any{"History": map[string]any{"skipOps": ops}},
+		}
+	}
+
+	got, err := getSkipOps(schema("CREATE", "UPDATE"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CREATE", "UPDATE"}, got)
+
+	got, err = getSkipOps(&load.Schema{Name: "Session"})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = getSkipOps(schema("INSERT"))
+	assert.ErrorIs(t, err, ErrInvalidSkipOp)
+}
+
+func TestGetRedactedFields(t *testing.T) {
+	redact := map[string]any{fieldAnnotationName: map[string]any{"redact": true}}
+
+	got, err := getRedactedFields(&load.Schema{
+		Name: "User",
+		Fields: []*load.Field{
+			{Name: "name", Info: &field.TypeInfo{Type: field.TypeString}},
+			{Name: "ssn", Info: &field.TypeInfo{Type: field.TypeString}, Annotations: redact},
+			{Name: "token", Info: &field.TypeInfo{Type: field.TypeBytes}, Annotations: redact},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ssn", "token"}, got)
+
+	_, err = getRedactedFields(&load.Schema{
+		Name: "User",
+		Fields: []*load.Field{
+			{Name: "age", Info: &field.TypeInfo{Type: field.TypeInt}, Annotations: redact},
+		},
+	})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestGetRestrictedFields(t *testing.T) {
+	assert.Equal(t, []string{"salary", "ssn"}, getRestrictedFields(&load.Schema{
+		Name: "Employee",
+		Fields: []*load.Field{
+			{Name: "name", Info: &field.TypeInfo{Type: field.TypeString}},
+			{Name: "salary", Info: &field.TypeInfo{Type: field.TypeInt}, Annotations: map[string]any{
+				fieldAnnotationName: map[string]any{"restrict": true},
+			}},
+			{Name: "ssn", Info: &field.TypeInfo{Type: field.TypeString}, Annotations: map[string]any{
+				fieldAnnotationName: map[string]any{"redact": true, "restrict": true},
+			}},
+			{Name: "token", Info: &field.TypeInfo{Type: field.TypeString}, Annotations: map[string]any{
+				fieldAnnotationName: map[string]any{"redact": true},
+			}},
+		},
+	}))
+
+	assert.Empty(t, getRestrictedFields(&load.Schema{Name: "Employee"}))
+}
+
+func TestGetRefFields(t *testing.T) {
+	fields := []*load.Field{
+		{Name: "tenant_id", Info: &field.TypeInfo{Type: field.TypeString}},
+		{Name: "email", Info: &field.TypeInfo{Type: field.TypeString}},
+		{Name: "tags", Info: &field.TypeInfo{Type: field.TypeJSON}},
+		{Name: "nickname", Info: &field.TypeInfo{Type: field.TypeString}, Nillable: true},
+		{Name: "password", Info: &field.TypeInfo{Type: field.TypeString}, Sensitive: true},
+		{Name: "ssn", Info: &field.TypeInfo{Type: field.TypeString}, Annotations: map[string]any{
+			fieldAnnotationName: map[string]any{"redact": true},
+		}},
+	}
+
+	schema := func(history map[string]any) *load.Schema {
+		return &load.Schema{
+			Name:        "Account",
+			Fields:      fields,
+			Annotations: map[string]any{"History": history},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		schema  *load.Schema
+		want    []string
+		wantErr error
+	}{
+		{
+			name:   "no annotation",
+			schema: &load.Schema{Name: "Account", Fields: fields},
+			want:   nil,
+		},
+		{
+			name:   "ref fields",
+			schema: schema(map[string]any{"refFields": []any{"tenant_id", "email"}}),
+			want:   []string{"tenant_id", "email"},
+		},
+		{
+			name:    "missing field",
+			schema:  schema(map[string]any{"refFields": []any{"login"}}),
+			wantErr: ErrFieldNotFound,
+		},
+		{
+			name:    "json field",
+			schema:  schema(map[string]any{"refFields": []any{"tags"}}),
+			wantErr: ErrUnsupportedType,
+		},
+		{
+			name:    "nillable field",
+			schema:  schema(map[string]any{"refFields": []any{"nickname"}}),
+			wantErr: ErrInvalidRefFields,
+		},
+		{
+			name:    "sensitive field",
+			schema:  schema(map[string]any{"refFields": []any{"password"}}),
+			wantErr: ErrInvalidRefFields,
+		},
+		{
+			name:    "redacted field",
+			schema:  schema(map[string]any{"refFields": []any{"ssn"}}),
+			wantErr: ErrInvalidRefFields,
+		},
+		{
+			name:    "sampled",
+			schema:  schema(map[string]any{"refFields": []any{"email"}, "sampleInterval": float64(time.Minute)}),
+			wantErr: ErrInvalidRefFields,
+		},
+		{
+			name: "composite id",
+			schema: &load.Schema{
+				Name:   "Account",
+				Fields: fields,
+				Annotations: map[string]any{
+					"History": map[string]any{"refFields": []any{"email"}},
+					"Fields":  map[string]any{"ID": []any{"tenant_id", "email"}},
+				},
+			},
+			wantErr: ErrInvalidRefFields,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getRefFields(tt.schema)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetFieldLimits(t *testing.T) {
+	fields := []*load.Field{
+		{Name: "body", Info: &field.TypeInfo{Type: field.TypeString}},
+		{Name: "attachment", Info: &field.TypeInfo{Type: field.TypeBytes}},
+		{Name: "count", Info: &field.TypeInfo{Type: field.TypeInt}},
+	}
+
+	schema := func(limits ...any) *load.Schema {
+		return &load.Schema{
+			Name:   "Document",
+			Fields: fields,
+			Annotations: map[string]any{
+				"History": map[string]any{
+					"fieldLimits": limits,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		schema  *load.Schema
+		want    []fieldLimitInfo
+		wantErr error
+	}{
+		{
+			name:   "no annotation",
+			schema: &load.Schema{Name: "Document", Fields: fields},
+			want:   nil,
+		},
+		{
+			name: "field limits",
+			schema: schema(
+				map[string]any{"field": "body", "maxSize": 1024},
+				map[string]any{"field": "attachment", "maxSize": 64, "strategy": "EXTERNAL"},
+			),
+			want: []fieldLimitInfo{
+				{Field: "body", MaxSize: 1024, Strategy: "LimitTruncate"},
+				{Field: "attachment", MaxSize: 64, Strategy: "LimitExternal"},
+			},
+		},
+		{
+			name:    "field does not exist",
+			schema:  schema(map[string]any{"field": "title", "maxSize": 1024}),
+			wantErr: ErrFieldNotFound,
+		},
+		{
+			name:    "unsupported field type",
+			schema:  schema(map[string]any{"field": "count", "maxSize": 1024}),
+			wantErr: ErrUnsupportedType,
+		},
+		{
+			name:    "unknown strategy",
+			schema:  schema(map[string]any{"field": "body", "maxSize": 1024, "strategy": "DROP"}),
+			wantErr: ErrInvalidFieldLimit,
+		},
+		{
+			name:    "max size too small to truncate",
+			schema:  schema(map[string]any{"field": "body", "maxSize": 8}),
+			wantErr: ErrInvalidFieldLimit,
+		},
+		{
+			name:    "max size not set",
+			schema:  schema(map[string]any{"field": "body", "strategy": "HASH"}),
+			wantErr: ErrInvalidFieldLimit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getFieldLimits(tt.schema)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetCompressedFields(t *testing.T) {
+	fields := []*load.Field{
+		{Name: "body", Info: &field.TypeInfo{Type: field.TypeString}},
+		{Name: "attachment", Info: &field.TypeInfo{Type: field.TypeBytes}},
+		{Name: "summary", Info: &field.TypeInfo{Type: field.TypeString}, Nillable: true},
+		{Name: "settings", Info: &field.TypeInfo{Type: field.TypeJSON}},
+	}
+
+	schema := func(compressed map[string]any) *load.Schema {
+		return &load.Schema{
+			Name:   "Document",
+			Fields: fields,
+			Annotations: map[string]any{
+				"History": map[string]any{
+					"compressedFields": compressed,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		schema  *load.Schema
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			name:   "no annotation",
+			schema: &load.Schema{Name: "Document", Fields: fields},
+			want:   nil,
+		},
+		{
+			name:   "compressed fields",
+			schema: schema(map[string]any{"body": "", "attachment": "zstd"}),
+			want:   map[string]string{"body": "gzip", "attachment": "zstd"},
+		},
+		{
+			name:    "field does not exist",
+			schema:  schema(map[string]any{"title": "gzip"}),
+			wantErr: ErrFieldNotFound,
+		},
+		{
+			name:    "nillable field",
+			schema:  schema(map[string]any{"summary": "gzip"}),
+			wantErr: ErrUnsupportedType,
+		},
+		{
+			name:    "json field",
+			schema:  schema(map[string]any{"settings": "gzip"}),
+			wantErr: ErrUnsupportedType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getCompressedFields(tt.schema)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
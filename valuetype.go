@@ -0,0 +1,194 @@
+package enthistory
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// valueTypeCustom is the first value type returned by ValueTypeOf, the value types below are the builtin types
+	valueTypeCustom ValueType = 1 << 16
+)
+
+// customValueType is a go type registered using ValueTypeOf
+type customValueType struct {
+	// goType is the go type of the values (e.g. ids.UserID)
+	goType reflect.Type
+	// builder is the builtin value type of the ent field builder storing the values
+	builder ValueType
+}
+
+var (
+	// customValueTypes are the go types registered using ValueTypeOf, by value type
+	customValueTypes []customValueType
+	// customValueTypesMu guards the custom value types
+	customValueTypesMu sync.RWMutex
+)
+
+// ValueTypeOf returns the value type of an arbitrary go type, e.g. a named principal id type, which is stored using
+// the ent field builder of the builtin value type: ValueTypeOf(ids.UserID(""), ValueTypeString) generates
+// field.String("updated_by").GoType(*new(ids.UserID)), and the value on the context must be an ids.UserID. The go
+// type must be a comparable named type of a package that is a valid GoType of the field builder (e.g. its underlying
+// type is the builtin type, or it implements sql.Scanner and driver.Valuer)
+func ValueTypeOf(goType any, builder ValueType) ValueType {
+	t := reflect.TypeOf(goType)
+
+	customValueTypesMu.Lock()
+	defer customValueTypesMu.Unlock()
+
+	for i, c := range customValueTypes {
+		if c.goType == t && c.builder == builder {
+			return valueTypeCustom + ValueType(i) //nolint:gosec
+		}
+	}
+
+	customValueTypes = append(customValueTypes, customValueType{goType: t, builder: builder})
+
+	return valueTypeCustom + ValueType(len(customValueTypes)-1) //nolint:gosec
+}
+
+// lookupValueType returns the go type registered using ValueTypeOf of the value type, if any
+func lookupValueType(valueType ValueType) (customValueType, bool) {
+	if valueType < valueTypeCustom {
+		return customValueType{}, false
+	}
+
+	customValueTypesMu.RLock()
+	defer customValueTypesMu.RUnlock()
+
+	i := int(valueType - valueTypeCustom)
+	if i >= len(customValueTypes) {
+		return customValueType{}, false
+	}
+
+	return customValueTypes[i], true
+}
+
+// valueTypeGoType returns the go type name of the value types registered using ValueTypeOf (e.g. ids.UserID), and an
+// empty string for the builtin value types
+func valueTypeGoType(valueType ValueType) string {
+	if c, ok := lookupValueType(valueType); ok && c.goType != nil {
+		return c.goType.String()
+	}
+
+	return ""
+}
+
+// valueTypeImport returns the import of the package of the go type registered using ValueTypeOf, aliased when the
+// package name does not match the last element of its path
+func valueTypeImport(valueType ValueType) (goImport, bool) {
+	c, ok := lookupValueType(valueType)
+	if !ok || c.goType == nil || c.goType.PkgPath() == "" {
+		return goImport{}, false
+	}
+
+	imp := goImport{Path: c.goType.PkgPath()}
+
+	if name, _, _ := strings.Cut(c.goType.String(), "."); name != path.Base(imp.Path) {
+		imp.Alias = name
+	}
+
+	return imp, true
+}
+
+// validateValueType checks the value type is a builtin value type, or a named go type of a package registered using
+// ValueTypeOf with a builtin field builder
+func validateValueType(valueType ValueType) error {
+	if valueType < valueTypeCustom {
+		if valueTypeField(valueType) == "" {
+			return fmt.Errorf("%w: value type %d", ErrUnsupportedType, valueType)
+		}
+
+		return nil
+	}
+
+	c, ok := lookupValueType(valueType)
+
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: value type %d", ErrUnsupportedType, valueType)
+	case c.goType == nil || c.goType.Name() == "" || c.goType.PkgPath() == "":
+		return fmt.Errorf("%w: value type %v is not a named type of a package", ErrUnsupportedType, c.goType)
+	case !c.goType.Comparable():
+		return fmt.Errorf("%w: value type %v is not comparable", ErrUnsupportedType, c.goType)
+	case c.builder >= valueTypeCustom || valueTypeField(c.builder) == "":
+		return fmt.Errorf("%w: value type %v has no field builder", ErrUnsupportedType, c.goType)
+	}
+
+	return nil
+}
+
+// configValueTypes returns the value types of updated_by, deleted_by, and the additional fields of the config
+func configValueTypes(c *Config) []ValueType {
+	var valueTypes []ValueType
+
+	if c.UpdatedBy != nil {
+		valueTypes = append(valueTypes, c.UpdatedBy.valueType)
+	}
+
+	if c.DeletedBy != nil {
+		valueTypes = append(valueTypes, c.DeletedBy.valueType)
+	}
+
+	for _, af := range c.AdditionalFields {
+		valueTypes = append(valueTypes, af.ValueType)
+	}
+
+	return valueTypes
+}
+
+// validateValueTypes checks the value types of updated_by, deleted_by, and the additional fields of the config
+func validateValueTypes(c *Config) error {
+	for _, valueType := range configValueTypes(c) {
+		if err := validateValueType(valueType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configValueTypeImports returns the imports of the go types of the value types of the config
+func configValueTypeImports(c *Config) []goImport {
+	return valueTypeImports(configValueTypes(c)...)
+}
+
+// valueTypeImports returns the imports of the go types registered using ValueTypeOf of the value types, sorted by path
+func valueTypeImports(valueTypes ...ValueType) []goImport {
+	var imps []goImport
+
+	for _, valueType := range valueTypes {
+		imp, ok := valueTypeImport(valueType)
+		if ok && !slices.ContainsFunc(imps, func(i goImport) bool { return i.Path == imp.Path }) {
+			imps = append(imps, imp)
+		}
+	}
+
+	sort.Slice(imps, func(i, j int) bool {
+		return imps[i].Path < imps[j].Path
+	})
+
+	return imps
+}
+
+// fieldBuilder returns the ent field builder expression of the field stored using the builder (e.g. Int, UUID), with
+// the go type of ValueTypeOf when set (e.g. field.String("updated_by").GoType(*new(ids.UserID)))
+func fieldBuilder(name, builder, goType string) string {
+	uuidBuilder := strings.EqualFold(builder, "UUID")
+
+	switch {
+	case uuidBuilder && goType == "":
+		return fmt.Sprintf("field.UUID(%q, uuid.UUID{})", name)
+	case uuidBuilder:
+		return fmt.Sprintf("field.UUID(%q, *new(%s))", name, goType)
+	case goType == "":
+		return fmt.Sprintf("field.%s(%q)", builder, name)
+	default:
+		return fmt.Sprintf("field.%s(%q).GoType(*new(%s))", builder, name, goType)
+	}
+}
@@ -0,0 +1,145 @@
+package enthistory
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueTypeOf(t *testing.T) {
+	duration := ValueTypeOf(time.Duration(0), ValueTypeInt64)
+
+	assert.Equal(t, duration, ValueTypeOf(time.Duration(0), ValueTypeInt64))
+	assert.NotEqual(t, duration, ValueTypeOf(time.Duration(0), ValueTypeInt))
+	assert.GreaterOrEqual(t, duration, valueTypeCustom)
+
+	assert.Equal(t, "time.Duration", valueTypeName(duration))
+	assert.Equal(t, "time.Duration", valueTypeGoType(duration))
+	assert.Equal(t, "Int64", valueTypeField(duration))
+	assert.Equal(t, []goImport{{Path: "time"}}, valueTypeImports(duration, duration, ValueTypeUUID))
+
+	assert.Equal(t, "", valueTypeGoType(ValueTypeUUID))
+	assert.Equal(t, "", valueTypeGoType(valueTypeCustom+1000))
+}
+
+func TestValidateValueType(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueType ValueType
+		wantErr   bool
+	}{
+		{
+			name:      "builtin",
+			valueType: ValueTypeUUID,
+		},
+		{
+			name:      "go type",
+			valueType: ValueTypeOf(uuid.UUID{}, ValueTypeUUID),
+		},
+		{
+			name:      "unknown builtin",
+			valueType: 42,
+			wantErr:   true,
+		},
+		{
+			name:      "unknown go type",
+			valueType: valueTypeCustom + 1000,
+			wantErr:   true,
+		},
+		{
+			name:      "unnamed go type",
+			valueType: ValueTypeOf("", ValueTypeString),
+			wantErr:   true,
+		},
+		{
+			name:      "nil go type",
+			valueType: ValueTypeOf(nil, ValueTypeString),
+			wantErr:   true,
+		},
+		{
+			name:      "not comparable",
+			valueType: ValueTypeOf(http.Header{}, ValueTypeString),
+			wantErr:   true,
+		},
+		{
+			name:      "go type builder",
+			valueType: ValueTypeOf(time.Duration(0), ValueTypeOf(time.Duration(0), ValueTypeInt64)),
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateValueType(tc.valueType)
+			if tc.wantErr {
+				require.ErrorIs(t, err, ErrUnsupportedType)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateValueTypes(t *testing.T) {
+	config := &Config{
+		UpdatedBy:        &UpdatedBy{valueType: ValueTypeInt64},
+		DeletedBy:        &DeletedBy{valueType: ValueTypeOf(time.Duration(0), ValueTypeInt64)},
+		AdditionalFields: []AdditionalField{{Name: "region", ValueType: ValueTypeString}},
+	}
+
+	require.NoError(t, validateValueTypes(config))
+	assert.Equal(t, []goImport{{Path: "time"}}, configValueTypeImports(config))
+
+	config.AdditionalFields = append(config.AdditionalFields, AdditionalField{Name: "tags", ValueType: ValueTypeOf(http.Header{}, ValueTypeString)})
+
+	require.ErrorIs(t, validateValueTypes(config), ErrUnsupportedType)
+}
+
+func TestFieldBuilder(t *testing.T) {
+	assert.Equal(t, `field.Int("updated_by")`, fieldBuilder("updated_by", "Int", ""))
+	assert.Equal(t, `field.UUID("updated_by", uuid.UUID{})`, fieldBuilder("updated_by", "UUID", ""))
+	assert.Equal(t, `field.UUID("deleted_by", uuid.UUID{})`, fieldBuilder("deleted_by", "Uuid", ""))
+	assert.Equal(t, `field.UUID("updated_by", *new(ids.UserID))`, fieldBuilder("updated_by", "UUID", "ids.UserID"))
+	assert.Equal(t, `field.String("updated_by").GoType(*new(ids.UserID))`, fieldBuilder("updated_by", "String", "ids.UserID"))
+}
+
+func TestWithUpdatedByFromSchema(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueType ValueType
+		want      string
+	}{
+		{
+			name:      "int64",
+			valueType: ValueTypeInt64,
+			want:      "int64",
+		},
+		{
+			name:      "uuid",
+			valueType: ValueTypeUUID,
+			want:      "uuid.UUID",
+		},
+		{
+			name:      "go type",
+			valueType: ValueTypeOf(time.Duration(0), ValueTypeInt64),
+			want:      "time.Duration",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(WithUpdatedByFromSchema(tc.valueType, true))
+
+			assert.True(t, h.config.IncludeUpdatedBy)
+			assert.Equal(t, tc.want, extractUpdatedByValueType(h.config.UpdatedBy))
+			assert.True(t, h.config.UpdatedBy.Nillable)
+			assert.Empty(t, extractUpdatedByKey(h.config.UpdatedBy))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package enthistory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"entgo.io/ent"
+	"entgo.io/ent/entc/gen"
+)
+
+const (
+	// latestViewSuffix is the suffix of the name of the latest history views, e.g. todo_history_latest
+	latestViewSuffix = "_latest"
+)
+
+// latestHistoryQuery returns the query of the latest history view of the history table, selecting the most
+// recent history row of each ref, by history_time and then id; this only uses NOT EXISTS so it is the same
+// on all dialects
+func latestHistoryQuery(table string) string {
+	return fmt.Sprintf("SELECT h.* FROM %[1]s AS h WHERE NOT EXISTS (SELECT 1 FROM %[1]s AS n WHERE n.ref = h.ref "+
+		"AND (n.history_time > h.history_time OR (n.history_time = h.history_time AND n.id > h.id)))", table)
+}
+
+// latestHistoryViewDDL returns the DDL creating the latest history view of the history table, in the schema, if any
+func latestHistoryViewDDL(table, schemaName string) string {
+	view := table + latestViewSuffix
+
+	if schemaName != "" {
+		table = schemaName + "." + table
+		view = schemaName + "." + view
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "-- Code generated by enthistory, the latest history row of each ref of %s\n", table)
+	fmt.Fprintf(&b, "CREATE VIEW %s AS %s;\n", view, latestHistoryQuery(table))
+
+	return b.String()
+}
+
+// writeLatestHistoryView writes the DDL of the latest history view of the history table to the directory, the file
+// is named after the view (e.g. todo_history_latest.sql)
+func writeLatestHistoryView(dir, table, schemaName string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, table+latestViewSuffix+".sql")
+
+	return os.WriteFile(path, []byte(latestHistoryViewDDL(table, schemaName)), 0o600) //nolint:mnd
+}
+
+// ViewFields prepares the fields of the history schema to be used by the latest history view, the defaults are
+// removed because views are read only, and the id field is added first when the history schema uses the default
+// id field, because views do not have one
+func ViewFields(fields []ent.Field, id ent.Field) []ent.Field {
+	viewFields := make([]ent.Field, 0, len(fields)+1)
+
+	hasID := false
+
+	for _, f := range fields {
+		desc := f.Descriptor()
+		if desc.Name == idFieldName {
+			hasID = true
+		}
+
+		removeDefaults(desc)
+
+		viewFields = append(viewFields, f)
+	}
+
+	if !hasID {
+		viewFields = append([]ent.Field{id}, viewFields...)
+	}
+
+	return viewFields
+}
+
+// viewLastNodeHook moves the last schema of the graph after the views when the graph ends with views (e.g. the
+// latest history view of the last history schema), the client generated by ent does not compile when the last
+// node of the graph is a view
+func viewLastNodeHook(next gen.Generator) gen.Generator {
+	return gen.GenerateFunc(func(g *gen.Graph) error {
+		for i := len(g.Nodes) - 1; i >= 0 && g.Nodes[len(g.Nodes)-1].IsView(); i-- {
+			if !g.Nodes[i].IsView() {
+				n := g.Nodes[i]
+				g.Nodes = append(append(g.Nodes[:i:i], g.Nodes[i+1:]...), n)
+			}
+		}
+
+		return next.Generate(g)
+	})
+}
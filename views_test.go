@@ -0,0 +1,78 @@
+package enthistory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/entc/load"
+	"entgo.io/ent/schema/field"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestHistoryViewDDL(t *testing.T) {
+	query := "SELECT h.* FROM todo_history AS h WHERE NOT EXISTS (SELECT 1 FROM todo_history AS n WHERE n.ref = h.ref " +
+		"AND (n.history_time > h.history_time OR (n.history_time = h.history_time AND n.id > h.id)))"
+
+	assert.Equal(t, query, latestHistoryQuery("todo_history"))
+	assert.Contains(t, latestHistoryViewDDL("todo_history", ""), "CREATE VIEW todo_history_latest AS "+query+";")
+	assert.Contains(t, latestHistoryViewDDL("todo_history", "audit"), "CREATE VIEW audit.todo_history_latest AS SELECT h.* FROM audit.todo_history AS h")
+
+	dir := filepath.Join(t.TempDir(), "views")
+	require.NoError(t, writeLatestHistoryView(dir, "todo_history", ""))
+
+	out, err := os.ReadFile(filepath.Join(dir, "todo_history_latest.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, latestHistoryViewDDL("todo_history", ""), string(out))
+}
+
+func TestViewFields(t *testing.T) {
+	fields := ViewFields([]ent.Field{
+		field.String("name").Default("todo"),
+	}, field.Int("id"))
+
+	require.Len(t, fields, 2)
+	assert.Equal(t, "id", fields[0].Descriptor().Name)
+	assert.Nil(t, fields[1].Descriptor().Default)
+
+	fields = ViewFields([]ent.Field{
+		field.Int("id"),
+		field.String("name"),
+	}, field.String("id"))
+
+	require.Len(t, fields, 2)
+	assert.Equal(t, field.TypeInt, fields[0].Descriptor().Info.Type)
+}
+
+func TestViewLastNodeHook(t *testing.T) {
+	node := func(name string, view bool) *gen.Type {
+		n, err := gen.NewType(&gen.Config{}, &load.Schema{Name: name, View: view})
+		require.NoError(t, err)
+
+		return n
+	}
+
+	g := &gen.Graph{
+		Nodes: []*gen.Type{
+			node("Todo", false),
+			node("TodoHistory", false),
+			node("TodoHistoryLatest", true),
+		},
+	}
+
+	var names []string
+
+	err := viewLastNodeHook(gen.GenerateFunc(func(g *gen.Graph) error {
+		for _, n := range g.Nodes {
+			names = append(names, n.Name)
+		}
+
+		return nil
+	})).Generate(g)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Todo", "TodoHistoryLatest", "TodoHistory"}, names)
+}